@@ -0,0 +1,147 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jomadu/rooda/internal/ai"
+	"github.com/jomadu/rooda/internal/config"
+	"github.com/jomadu/rooda/internal/loop"
+	"github.com/jomadu/rooda/internal/prompt"
+	"github.com/spf13/cobra"
+)
+
+// defaultProbeTimeout bounds how long rooda alias test waits for the AI CLI.
+const defaultProbeTimeout = 120
+
+func newAliasCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "alias",
+		Short: "Work with AI command aliases",
+		Long:  `Inspect and check the AI command aliases defined in configuration.`,
+	}
+
+	cmd.AddCommand(newAliasTestCommand())
+
+	return cmd
+}
+
+func newAliasTestCommand() *cobra.Command {
+	var timeout int
+
+	cmd := &cobra.Command{
+		Use:   "test <alias>",
+		Short: "Check that an alias follows the signal protocol",
+		Long: `Send a minimal probe prompt, built from the real procedure preamble, to an
+AI command alias. Checks that the command starts, reads the prompt from stdin,
+finishes within the timeout and emits a valid <promise> signal, then reports
+latency, output size and any protocol violations.`,
+		Args: cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if timeout < 1 {
+				return fmt.Errorf("--timeout must be >= 1")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runAliasTest(cmd, args[0], timeout)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+
+			cfg, err := config.LoadConfig(configFlags())
+			if err != nil {
+				return nil, cobra.ShellCompDirectiveError
+			}
+
+			names := make([]string, 0, len(cfg.AICmdAliases))
+			for name := range cfg.AICmdAliases {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return names, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+
+	cmd.Flags().IntVar(&timeout, "timeout", defaultProbeTimeout, "seconds to wait for the AI CLI to finish")
+
+	return cmd
+}
+
+func runAliasTest(cmd *cobra.Command, aliasName string, timeout int) error {
+	flags := configFlags()
+
+	cfg, err := loadConfig(cmd, flags)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	aiCmd, err := config.ResolveAlias(*cfg, aliasName)
+	if err != nil {
+		return err
+	}
+
+	token, err := probeToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate probe token: %w", err)
+	}
+	oneIteration := 1
+	probeProcedure := config.Procedure{
+		Display: "Alias probe",
+		Act:     []config.FragmentAction{{Content: ai.ProbeInstructions(token)}},
+	}
+	probePrompt, err := prompt.AssemblePrompt(probeProcedure, "", "", &prompt.IterationContext{
+		CurrentIteration: 0,
+		MaxIterations:    &oneIteration,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to assemble probe prompt: %w", err)
+	}
+
+	target := aiCmd.Command
+	if aiCmd.Options.Backend == config.BackendHTTP && aiCmd.Options.HTTP != nil {
+		target = aiCmd.Options.HTTP.BaseURL
+	}
+	cmd.Printf("Probing alias %s (%s)...\n\n", aliasName, target)
+
+	result := ai.Probe(ai.NewExecutor(aiCmd), probePrompt, token, ai.ExecOptions{
+		Verbose:          verbose,
+		IterationTimeout: &timeout,
+		MaxOutputBuffer:  cfg.Loop.MaxOutputBuffer,
+	}, loop.SetupSignalHandler())
+
+	signal := result.Signal
+	if signal == "" {
+		signal = "(none)"
+	}
+	cmd.Printf("Latency:   %s\n", result.Latency.Round(time.Millisecond))
+	cmd.Printf("Output:    %d bytes\n", result.OutputBytes)
+	cmd.Printf("Exit code: %d\n", result.ExitCode)
+	cmd.Printf("Signal:    %s\n", signal)
+	cmd.Println()
+
+	if result.OK() {
+		cmd.Printf("✓ Alias %s follows the signal protocol\n", aliasName)
+		return nil
+	}
+
+	cmd.Println("Protocol violations:")
+	for _, v := range result.Violations {
+		cmd.Printf("  - %s\n", v)
+	}
+	return fmt.Errorf("alias %s failed the probe with %d violation(s)", aliasName, len(result.Violations))
+}
+
+// probeToken returns a random token the agent must echo, proving it read the prompt.
+func probeToken() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "rooda-probe-" + hex.EncodeToString(b), nil
+}
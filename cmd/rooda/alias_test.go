@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAliasTestIntegration(t *testing.T) {
+	tmpDir := t.TempDir()
+	agentPath := filepath.Join(tmpDir, "agent.sh")
+	agentScript := `#!/bin/sh
+token=$(grep -o 'rooda-probe-[0-9a-f]*' | head -n 1)
+echo "<promise>SUCCESS</promise> $token"
+`
+	if err := os.WriteFile(agentPath, []byte(agentScript), 0755); err != nil {
+		t.Fatalf("Failed to create agent script: %v", err)
+	}
+	configPath := filepath.Join(tmpDir, "rooda-config.yml")
+	configContent := `
+ai_cmd_aliases:
+  compliant: "` + agentPath + `"
+  echoes-prompt: "cat"
+  slow: "sleep 5"
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		args         []string
+		wantExitCode int
+		wantOutput   []string
+	}{
+		{
+			name:         "compliant alias",
+			args:         []string{"alias", "test", "compliant"},
+			wantExitCode: ExitSuccess,
+			wantOutput:   []string{"Signal:    SUCCESS", "✓ Alias compliant follows the signal protocol"},
+		},
+		{
+			name:         "alias echoing the prompt",
+			args:         []string{"alias", "test", "echoes-prompt"},
+			wantExitCode: ExitUserError,
+			wantOutput:   []string{"emitted both SUCCESS and FAILURE signals", "alias echoes-prompt failed the probe"},
+		},
+		{
+			name:         "slow alias",
+			args:         []string{"alias", "test", "slow", "--timeout", "1"},
+			wantExitCode: ExitUserError,
+			wantOutput:   []string{"did not finish within the timeout"},
+		},
+		{
+			name:         "unknown alias",
+			args:         []string{"alias", "test", "missing"},
+			wantExitCode: ExitUserError,
+			wantOutput:   []string{"unknown AI command alias: missing"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, exitCode := runRooda(t, append(tt.args, "--config", configPath)...)
+			output := stdout + stderr
+			if exitCode != tt.wantExitCode {
+				t.Errorf("Expected exit code %d, got %d. output: %s", tt.wantExitCode, exitCode, output)
+			}
+			for _, want := range tt.wantOutput {
+				if !strings.Contains(output, want) {
+					t.Errorf("Expected output to contain %q, got: %s", want, output)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jomadu/rooda/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func newConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect, check and edit configuration",
+		Long:  `Inspect and check the merged configuration from built-in defaults, config files and environment variables, and edit config file settings.`,
+	}
+
+	cmd.AddCommand(newConfigValidateCommand())
+	cmd.AddCommand(newConfigShowCommand())
+	cmd.AddCommand(newConfigSchemaCommand())
+	cmd.AddCommand(newConfigEnvCommand())
+	cmd.AddCommand(newConfigGetCommand())
+	cmd.AddCommand(newConfigSetCommand())
+	cmd.AddCommand(newConfigUnsetCommand())
+	cmd.AddCommand(newConfigMigrateCommand())
+
+	return cmd
+}
+
+// loadConfig loads the merged configuration and prints its warnings, such as
+// unknown keys, unless --quiet is set.
+func loadConfig(cmd *cobra.Command, flags config.CLIFlags) (*config.Config, error) {
+	cfg, err := config.LoadConfig(flags)
+	if err != nil {
+		return nil, err
+	}
+	if !quiet {
+		for _, w := range cfg.Warnings {
+			cmd.PrintErrf("Warning: %s\n", w.Error())
+		}
+	}
+	return cfg, nil
+}
+
+func newConfigValidateCommand() *cobra.Command {
+	var checkCommands bool
+	var strict bool
+
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate configuration and report every error",
+		Long: `Load and validate the merged configuration. Every problem is reported with
+the file, line and column that set it. Exits nonzero if any are found, for use
+in pre-commit hooks and CI. Unknown keys are warnings unless --strict is set.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigValidate(cmd, checkCommands, strict)
+		},
+	}
+
+	cmd.Flags().BoolVar(&checkCommands, "check-commands", false, "also check that configured ai_cmd binaries exist and are executable")
+	cmd.Flags().BoolVar(&strict, "strict", false, "treat unknown keys as errors")
+
+	return cmd
+}
+
+func runConfigValidate(cmd *cobra.Command, checkCommands bool, strict bool) error {
+	flags := configFlags()
+
+	cfg, err := config.LoadConfig(flags)
+	if err == nil && checkCommands {
+		err = config.ValidateConfig(cfg)
+	}
+
+	var errs config.ValidationErrors
+	if err != nil && !errors.As(err, &errs) {
+		return err
+	}
+	var warnings config.ValidationErrors
+	if cfg != nil {
+		warnings = cfg.Warnings
+	}
+	if strict {
+		errs = append(warnings, errs...)
+		warnings = nil
+	}
+
+	for _, w := range warnings {
+		cmd.PrintErrf("Warning: %s\n", w.Error())
+	}
+	if len(errs) > 0 {
+		for _, e := range errs {
+			cmd.PrintErrln(e.Error())
+		}
+		return fmt.Errorf("configuration has %d error(s)", len(errs))
+	}
+
+	if !quiet {
+		for _, file := range cfg.Files() {
+			cmd.Printf("Loaded %s\n", file)
+		}
+		cmd.Println("✓ Configuration valid")
+	}
+	return nil
+}
+
+func newConfigShowCommand() *cobra.Command {
+	var format string
+	var showProvenance bool
+
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Print the fully merged configuration",
+		Long: `Print the configuration after merging built-in defaults, the global and
+workspace config files, environment variables and CLI flags. With --provenance,
+every value is marked with the tier (and file or env var) it came from.`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			switch format {
+			case config.FormatYAML, config.FormatJSON:
+				return nil
+			}
+			return fmt.Errorf("invalid --format %q, must be one of: yaml, json", format)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigShow(cmd, format, showProvenance)
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", config.FormatYAML, "output format (yaml, json)")
+	cmd.Flags().BoolVar(&showProvenance, "provenance", false, "mark each value with where it came from")
+
+	return cmd
+}
+
+func runConfigShow(cmd *cobra.Command, format string, showProvenance bool) error {
+	flags := configFlags()
+
+	cfg, err := loadConfig(cmd, flags)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	out, err := config.MarshalConfig(cfg, format, showProvenance)
+	if err != nil {
+		return err
+	}
+	_, err = cmd.OutOrStdout().Write(out)
+	return err
+}
+
+func newConfigSchemaCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print a JSON Schema for rooda-config.yml",
+		Long: `Print a JSON Schema (draft-07) describing rooda-config.yml, for editor
+autocompletion and validation, e.g. with the YAML language server:
+
+  rooda config schema > .rooda/config.schema.json
+  # yaml-language-server: $schema=.rooda/config.schema.json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			schema, err := config.JSONSchema()
+			if err != nil {
+				return err
+			}
+			_, err = cmd.OutOrStdout().Write(schema)
+			return err
+		},
+	}
+
+	return cmd
+}
+
+func newConfigEnvCommand() *cobra.Command {
+	var onlySet bool
+
+	cmd := &cobra.Command{
+		Use:   "env",
+		Short: "List the environment variables that set config values",
+		Long: `List every environment variable that sets a config value, with the setting
+it maps to and the values it accepts. Variables are named after the setting
+path, e.g. loop.log_level is ROODA_LOOP_LOG_LEVEL and procedures.build.ai_cmd_alias
+is ROODA_PROCEDURES_BUILD_AI_CMD_ALIAS. Variables set in the environment are
+marked [set].`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigEnv(cmd, onlySet)
+		},
+	}
+
+	cmd.Flags().BoolVar(&onlySet, "set", false, "list only variables set in the environment")
+
+	return cmd
+}
+
+func runConfigEnv(cmd *cobra.Command, onlySet bool) error {
+	flags := configFlags()
+
+	cfg, err := loadConfig(cmd, flags)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	vars := config.EnvVars(cfg)
+	vars = append(vars,
+		config.EnvVar{Name: "ROODA_CONFIG_HOME", Type: "global config directory"},
+		config.EnvVar{Name: config.ProfileEnvVar, Type: "profile to apply when --profile is not given"},
+	)
+
+	// Group by heading, keeping the order EnvVars lists them in
+	var groups []string
+	byGroup := make(map[string][]config.EnvVar)
+	for _, v := range vars {
+		if _, set := os.LookupEnv(v.Name); onlySet && !set {
+			continue
+		}
+		g := envGroup(v)
+		if _, ok := byGroup[g]; !ok {
+			groups = append(groups, g)
+		}
+		byGroup[g] = append(byGroup[g], v)
+	}
+
+	out := cmd.OutOrStdout()
+	for i, g := range groups {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintln(out, g)
+		nameWidth, pathWidth := 0, 0
+		for _, v := range byGroup[g] {
+			nameWidth = max(nameWidth, len(v.Name))
+			pathWidth = max(pathWidth, len(v.Path))
+		}
+		for _, v := range byGroup[g] {
+			line := fmt.Sprintf("  %-*s  %-*s  %s", nameWidth, v.Name, pathWidth, v.Path, v.Type)
+			if _, set := os.LookupEnv(v.Name); set {
+				line += "  [set]"
+			}
+			fmt.Fprintln(out, line)
+		}
+	}
+	if !onlySet {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "ROODA_AI_CMD_ALIASES_<NAME> defines a new alias, named in lowercase with '_' as '-'.")
+	}
+	return nil
+}
+
+// envGroup is the heading config env lists a variable under.
+func envGroup(v config.EnvVar) string {
+	switch {
+	case v.Path == "":
+		return "Config selection:"
+	case strings.HasPrefix(v.Path, "loop."):
+		return "Loop settings:"
+	case strings.HasPrefix(v.Path, "ai_cmd_aliases."):
+		return "AI command aliases:"
+	}
+	name := strings.Split(v.Path, ".")[1]
+	return fmt.Sprintf("Procedure %s:", name)
+}
+
+func newConfigGetCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "get <path>",
+		Short: "Print the merged value of one setting",
+		Long: `Print the value of the setting at path after merging every tier, e.g.
+
+  rooda config get procedures.build.iteration_timeout
+
+Mappings and lists are printed as YAML. Use config show --provenance to see
+where values came from.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(cmd, configFlags())
+			if err != nil {
+				return fmt.Errorf("failed to load configuration: %w", err)
+			}
+			value, err := config.GetValue(cfg, args[0])
+			if err != nil {
+				return err
+			}
+			if !strings.HasSuffix(value, "\n") {
+				value += "\n"
+			}
+			_, err = fmt.Fprint(cmd.OutOrStdout(), value)
+			return err
+		},
+	}
+
+	return cmd
+}
+
+func newConfigSetCommand() *cobra.Command {
+	var global, workspace bool
+
+	cmd := &cobra.Command{
+		Use:   "set <path> <value>",
+		Short: "Set a setting in a config file",
+		Long: `Set the setting at path in the workspace config file (./rooda-config.yml, or
+--config), or with --global in the global config file, e.g.
+
+  rooda config set procedures.build.iteration_timeout 900
+  rooda config set ai_cmd_aliases.fast "fast-ai --yes" --global
+
+The value is read as YAML, so 900 is a number, true a boolean and [a, b] a
+list. Comments and key order in the file are kept. The file is left unchanged
+if the resulting configuration does not validate.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file := editedFile(global)
+			if err := config.SetValue(file, args[0], args[1], configFlags()); err != nil {
+				return err
+			}
+			if !quiet {
+				cmd.Printf("✓ Set %s in %s\n", args[0], file)
+			}
+			return nil
+		},
+	}
+
+	addConfigFileFlags(cmd, &global, &workspace)
+	return cmd
+}
+
+func newConfigUnsetCommand() *cobra.Command {
+	var global, workspace bool
+
+	cmd := &cobra.Command{
+		Use:   "unset <path>",
+		Short: "Remove a setting from a config file",
+		Long: `Remove the setting at path from the workspace config file (./rooda-config.yml,
+or --config), or with --global from the global config file, so the value
+from a lower tier applies. Keys left empty are removed too.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file := editedFile(global)
+			if err := config.UnsetValue(file, args[0], configFlags()); err != nil {
+				return err
+			}
+			if !quiet {
+				cmd.Printf("✓ Unset %s in %s\n", args[0], file)
+			}
+			return nil
+		},
+	}
+
+	addConfigFileFlags(cmd, &global, &workspace)
+	return cmd
+}
+
+func newConfigMigrateCommand() *cobra.Command {
+	var global, write bool
+
+	cmd := &cobra.Command{
+		Use:   "migrate [file...]",
+		Short: "Rewrite config files to the current schema version",
+		Long: fmt.Sprintf(`Rewrite config files in deprecated forms to schema version %d: phases given
+as a single fragment path become fragment lists, and the top-level version key
+is set. Comments and key order are kept.
+
+The changes are printed as a diff; pass --write to apply them. Migrates the
+workspace config file (./rooda-config.yml, or --config) unless files are
+given, or the global config file with --global.`, config.ConfigVersion),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files := args
+			if len(files) == 0 {
+				files = []string{editedFile(global)}
+			}
+			return runConfigMigrate(cmd, files, write)
+		},
+	}
+
+	cmd.Flags().BoolVar(&global, "global", false, "migrate the global config file")
+	cmd.Flags().BoolVar(&write, "write", false, "write the migrated files instead of only showing the diff")
+
+	return cmd
+}
+
+func runConfigMigrate(cmd *cobra.Command, files []string, write bool) error {
+	pending := false
+	for _, file := range files {
+		m, err := config.MigrateFile(file)
+		if err != nil {
+			return err
+		}
+		if !m.Changed() {
+			if !quiet {
+				cmd.Printf("✓ %s is already at version %d\n", file, config.ConfigVersion)
+			}
+			continue
+		}
+
+		fmt.Fprint(cmd.OutOrStdout(), m.Diff())
+		if !write {
+			pending = true
+			continue
+		}
+		if err := m.Write(configFlags()); err != nil {
+			return err
+		}
+		if !quiet {
+			cmd.Printf("✓ Migrated %s to version %d\n", file, config.ConfigVersion)
+		}
+	}
+	if pending && !quiet {
+		cmd.Println("Run with --write to apply these changes.")
+	}
+	return nil
+}
+
+// addConfigFileFlags adds the flags choosing which file config set and unset
+// edit. The workspace file is the default.
+func addConfigFileFlags(cmd *cobra.Command, global *bool, workspace *bool) {
+	cmd.Flags().BoolVar(global, "global", false, "edit the global config file")
+	cmd.Flags().BoolVar(workspace, "workspace", false, "edit the workspace config file (default)")
+	cmd.MarkFlagsMutuallyExclusive("global", "workspace")
+}
+
+// editedFile returns the config file config set and unset edit.
+func editedFile(global bool) string {
+	switch {
+	case global:
+		return filepath.Join(config.GlobalConfigDir(), config.ConfigFileName)
+	case cfgFile != "":
+		return cfgFile
+	}
+	return "./" + config.ConfigFileName
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jomadu/rooda/internal/config"
+)
+
+func TestConfigValidateIntegration(t *testing.T) {
+	tmpDir := t.TempDir()
+	validPath := filepath.Join(tmpDir, "valid.yml")
+	if err := os.WriteFile(validPath, []byte("loop:\n  ai_cmd: not-installed-ai\n  failure_threshold: 2\n"), 0644); err != nil {
+		t.Fatalf("Failed to create config: %v", err)
+	}
+	invalidPath := filepath.Join(tmpDir, "invalid.yml")
+	if err := os.WriteFile(invalidPath, []byte("loop:\n  log_level: verbose\n  failure_threshold: 0\n"), 0644); err != nil {
+		t.Fatalf("Failed to create config: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		args         []string
+		wantExitCode int
+		wantOutput   []string
+	}{
+		{
+			name:         "valid config",
+			args:         []string{"config", "validate", "--config", validPath},
+			wantExitCode: ExitSuccess,
+			wantOutput:   []string{"Loaded " + validPath, "✓ Configuration valid"},
+		},
+		{
+			name:         "invalid config reports every error",
+			args:         []string{"config", "validate", "--config", invalidPath},
+			wantExitCode: ExitUserError,
+			wantOutput: []string{
+				invalidPath + `:2:14: invalid log_level "verbose"`,
+				invalidPath + ":3:22: loop.failure_threshold must be >= 1, got 0",
+				"configuration has 2 error(s)",
+			},
+		},
+		{
+			name:         "missing binary with check-commands",
+			args:         []string{"config", "validate", "--config", validPath, "--check-commands"},
+			wantExitCode: ExitUserError,
+			wantOutput:   []string{validPath + `:2:11: loop.ai_cmd: command "not-installed-ai" not found in PATH`},
+		},
+		{
+			name:         "other commands reject invalid config",
+			args:         []string{"list", "--config", invalidPath},
+			wantExitCode: ExitUserError,
+			wantOutput:   []string{"failed to load configuration: 2 configuration errors:"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, exitCode := runRooda(t, tt.args...)
+			output := stdout + stderr
+			if exitCode != tt.wantExitCode {
+				t.Errorf("Expected exit code %d, got %d. output: %s", tt.wantExitCode, exitCode, output)
+			}
+			for _, want := range tt.wantOutput {
+				if !strings.Contains(output, want) {
+					t.Errorf("Expected output to contain %q, got: %s", want, output)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigShowIntegration(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "rooda-config.yml")
+	if err := os.WriteFile(configPath, []byte("loop:\n  iteration_timeout: 30\n"), 0644); err != nil {
+		t.Fatalf("Failed to create config: %v", err)
+	}
+
+	stdout, stderr, exitCode := runRooda(t, "config", "show", "--config", configPath, "--provenance")
+	if exitCode != ExitSuccess {
+		t.Fatalf("Expected exit code %d, got %d. stderr: %s", ExitSuccess, exitCode, stderr)
+	}
+	if !strings.Contains(stdout, "iteration_timeout: 30 # workspace "+configPath) {
+		t.Errorf("Expected workspace provenance for iteration_timeout, got: %s", stdout)
+	}
+
+	stdout, _, exitCode = runRooda(t, "config", "show", "--config", configPath, "--format", "json")
+	if exitCode != ExitSuccess || !strings.Contains(stdout, `"iteration_timeout": 30`) {
+		t.Errorf("Expected JSON output, got exit %d: %s", exitCode, stdout)
+	}
+
+	_, stderr, exitCode = runRooda(t, "config", "show", "--format", "toml")
+	if exitCode != ExitUserError || !strings.Contains(stderr, `invalid --format "toml"`) {
+		t.Errorf("Expected invalid format error, got exit %d: %s", exitCode, stderr)
+	}
+}
+
+func TestConfigProfileIntegration(t *testing.T) {
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+	configPath := filepath.Join(t.TempDir(), "rooda-config.yml")
+	os.WriteFile(configPath, []byte(`profiles:
+  ci:
+    loop:
+      iteration_mode: unlimited
+`), 0644)
+
+	stdout, stderr, exitCode := runRooda(t, "config", "show", "--provenance", "--profile", "ci", "--config", configPath)
+	if exitCode != ExitSuccess || !strings.Contains(stdout, "iteration_mode: unlimited # profile "+configPath) {
+		t.Errorf("Expected profile provenance, got exit %d: %s%s", exitCode, stdout, stderr)
+	}
+
+	stdout, stderr, exitCode = runRooda(t, "config", "show", "--profile", "missing", "--config", configPath)
+	if exitCode != ExitUserError || !strings.Contains(stdout+stderr, `unknown profile "missing", available: ci`) {
+		t.Errorf("Expected unknown profile error, got exit %d: %s%s", exitCode, stdout, stderr)
+	}
+}
+
+func TestConfigEnvIntegration(t *testing.T) {
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+	t.Setenv("ROODA_LOOP_MAX_RETRIES", "2")
+
+	stdout, stderr, exitCode := runRooda(t, "config", "env")
+	if exitCode != ExitSuccess {
+		t.Fatalf("Expected success, got exit %d: %s", exitCode, stderr)
+	}
+	for _, want := range []string{
+		"Loop settings:",
+		"ROODA_LOOP_MAX_OUTPUT_BUFFER  ",
+		"ROODA_LOOP_MAX_RETRIES  ",
+		"Procedure build:",
+		"ROODA_PROCEDURES_BUILD_AI_CMD_ALIAS  ",
+		"ROODA_AI_CMD_ALIASES_CLAUDE  ",
+		"ROODA_PROFILE",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, stdout)
+		}
+	}
+
+	stdout, _, _ = runRooda(t, "config", "env", "--set")
+	if !strings.Contains(stdout, "loop.max_retries") || !strings.HasSuffix(strings.TrimSpace(stdout), "[set]") || strings.Contains(stdout, "ROODA_LOOP_LOG_LEVEL") {
+		t.Errorf("Expected only set variables, got:\n%s", stdout)
+	}
+
+	t.Setenv("ROODA_LOOP_FAILURE_THRESHOLD", "abc")
+	stdout, stderr, exitCode = runRooda(t, "config", "validate")
+	if exitCode != ExitUserError || !strings.Contains(stdout+stderr, `ROODA_LOOP_FAILURE_THRESHOLD: invalid value "abc"`) {
+		t.Errorf("Expected invalid env value error, got exit %d: %s%s", exitCode, stdout, stderr)
+	}
+}
+
+func TestConfigSetIntegration(t *testing.T) {
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+	configPath := filepath.Join(t.TempDir(), "rooda-config.yml")
+	os.WriteFile(configPath, []byte("# Team settings\nloop:\n  log_level: info # default\n"), 0644)
+
+	_, stderr, exitCode := runRooda(t, "config", "set", "procedures.build.iteration_timeout", "900", "--config", configPath)
+	if exitCode != ExitSuccess || !strings.Contains(stderr, "✓ Set procedures.build.iteration_timeout in "+configPath) {
+		t.Fatalf("Expected set to succeed, got exit %d: %s", exitCode, stderr)
+	}
+	stdout, stderr, exitCode := runRooda(t, "config", "get", "procedures.build.iteration_timeout", "--config", configPath)
+	if exitCode != ExitSuccess || stdout != "900\n" {
+		t.Errorf("Expected get to print 900, got exit %d: %q %s", exitCode, stdout, stderr)
+	}
+
+	_, stderr, exitCode = runRooda(t, "config", "set", "loop.log_level", "loud", "--config", configPath)
+	if exitCode != ExitUserError || !strings.Contains(stderr, `invalid log_level "loud"`) {
+		t.Errorf("Expected invalid value error, got exit %d: %s", exitCode, stderr)
+	}
+	_, _, exitCode = runRooda(t, "config", "unset", "procedures.build.iteration_timeout", "--config", configPath)
+	if exitCode != ExitSuccess {
+		t.Errorf("Expected unset to succeed, got exit %d", exitCode)
+	}
+	if data, _ := os.ReadFile(configPath); string(data) != "# Team settings\nloop:\n  log_level: info # default\n" {
+		t.Errorf("Expected file back to its original content, got:\n%s", data)
+	}
+}
+
+func TestConfigMigrateIntegration(t *testing.T) {
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+	configPath := filepath.Join(t.TempDir(), "rooda-config.yml")
+	legacy := "procedures:\n  bootstrap:\n    act: prompts/act.md\n"
+	os.WriteFile(configPath, []byte(legacy), 0644)
+
+	stdout, stderr, exitCode := runRooda(t, "config", "migrate", "--config", configPath)
+	if exitCode != ExitSuccess || !strings.Contains(stdout, "-    act: prompts/act.md\n+    act:\n+      - path: prompts/act.md\n") {
+		t.Errorf("Expected migration diff, got exit %d: %s%s", exitCode, stdout, stderr)
+	}
+	if data, _ := os.ReadFile(configPath); string(data) != legacy {
+		t.Error("Expected file unchanged without --write")
+	}
+
+	_, stderr, exitCode = runRooda(t, "config", "migrate", "--write", "--config", configPath)
+	if exitCode != ExitSuccess || !strings.Contains(stderr, "✓ Migrated "+configPath+" to version 2") {
+		t.Errorf("Expected file migrated, got exit %d: %s", exitCode, stderr)
+	}
+	_, stderr, _ = runRooda(t, "config", "validate", "--strict", "--config", configPath)
+	if !strings.Contains(stderr, "✓ Configuration valid") {
+		t.Errorf("Expected migrated file to validate without warnings, got: %s", stderr)
+	}
+}
+
+func TestConfigUnknownKeysIntegration(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "rooda-config.yml")
+	if err := os.WriteFile(configPath, []byte("loop:\n  iteraton_timeout: 30\n"), 0644); err != nil {
+		t.Fatalf("Failed to create config: %v", err)
+	}
+	warning := configPath + `:2:3: unknown key "iteraton_timeout" in loop (did you mean "iteration_timeout"?)`
+
+	stdout, stderr, exitCode := runRooda(t, "list", "--config", configPath)
+	if exitCode != ExitSuccess || !strings.Contains(stdout+stderr, "Warning: "+warning) {
+		t.Errorf("Expected unknown key warning, got exit %d: %s%s", exitCode, stdout, stderr)
+	}
+
+	stdout, stderr, exitCode = runRooda(t, "config", "validate", "--config", configPath)
+	if exitCode != ExitSuccess || !strings.Contains(stdout+stderr, warning) {
+		t.Errorf("Expected warning without --strict, got exit %d: %s%s", exitCode, stdout, stderr)
+	}
+
+	stdout, stderr, exitCode = runRooda(t, "config", "validate", "--config", configPath, "--strict")
+	if exitCode != ExitUserError || !strings.Contains(stdout+stderr, "configuration has 1 error(s)") {
+		t.Errorf("Expected --strict to fail, got exit %d: %s%s", exitCode, stdout, stderr)
+	}
+}
+
+func TestConfigSchemaIntegration(t *testing.T) {
+	stdout, stderr, exitCode := runRooda(t, "config", "schema")
+	if exitCode != ExitSuccess {
+		t.Fatalf("Expected exit code %d, got %d. stderr: %s", ExitSuccess, exitCode, stderr)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(stdout), &schema); err != nil {
+		t.Fatalf("Expected JSON schema on stdout: %v\n%s", err, stdout)
+	}
+	if schema["$id"] != config.SchemaID {
+		t.Errorf("Expected $id %q, got %v", config.SchemaID, schema["$id"])
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jomadu/rooda/internal/doctor"
+	"github.com/spf13/cobra"
+)
+
+func newDoctorCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the environment for problems that make runs fail",
+		Long: `Check everything a run depends on and print a pass/warn/fail table:
+
+  config     the merged configuration loads and validates
+  aliases    every AI command alias and ai_cmd binary is installed and executable
+  fragments  every fragment path in every procedure, builtin: or file, exists
+  AGENTS.md  exists and has the required sections
+  git        the workspace is a git repository, and whether it is clean
+
+An alias that is not installed fails only when the loop or a procedure uses it.
+Exits nonzero if any check fails. With --quiet, only warnings and failures are
+listed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			checks := doctor.Run(configFlags())
+			if quiet {
+				var problems []doctor.Check
+				for _, c := range checks {
+					if c.Status != doctor.Pass {
+						problems = append(problems, c)
+					}
+				}
+				checks = problems
+			}
+			if len(checks) > 0 {
+				printChecks(cmd.OutOrStdout(), checks)
+			}
+			if n := doctor.Failed(checks); n > 0 {
+				return fmt.Errorf("%d check(s) failed", n)
+			}
+			return nil
+		},
+	}
+}
+
+// printChecks writes checks as a table, indenting multi-line details under
+// the detail column.
+func printChecks(w io.Writer, checks []doctor.Check) {
+	width := len("CHECK")
+	for _, c := range checks {
+		width = max(width, len(c.Name))
+	}
+	indent := "\n" + strings.Repeat(" ", len("STATUS")+2+width+2)
+	fmt.Fprintf(w, "%-6s  %-*s  %s\n", "STATUS", width, "CHECK", "DETAIL")
+	for _, c := range checks {
+		detail := strings.ReplaceAll(c.Detail, "\n", indent)
+		fmt.Fprintf(w, "%-6s  %-*s  %s\n", c.Status, width, c.Name, detail)
+	}
+}
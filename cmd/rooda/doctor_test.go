@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jomadu/rooda/internal/doctor"
+)
+
+func TestDoctorIntegration(t *testing.T) {
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+	configPath := filepath.Join(t.TempDir(), "rooda-config.yml")
+	os.WriteFile(configPath, []byte(`procedures:
+  broken:
+    observe:
+      - path: builtin:fragments/observe/no_such_fragment.md
+`), 0644)
+
+	// There is no AGENTS.md in this package's directory, so that check fails too
+	stdout, stderr, exitCode := runRooda(t, "doctor", "--config", configPath, "--quiet")
+	if exitCode != ExitUserError || !strings.Contains(stderr, "Error: 2 check(s) failed") {
+		t.Fatalf("Expected doctor to fail, got exit %d: %s%s", exitCode, stdout, stderr)
+	}
+	if !strings.HasPrefix(stdout, "STATUS  CHECK") || !strings.Contains(stdout, "fail    fragments") ||
+		!strings.Contains(stdout, "procedures.broken.observe[0]: embedded fragment not found: builtin:fragments/observe/no_such_fragment.md") {
+		t.Errorf("Expected the missing fragment in the table, got:\n%s", stdout)
+	}
+	if strings.Contains(stdout, "\npass ") {
+		t.Errorf("Expected only warnings and failures with --quiet, got:\n%s", stdout)
+	}
+}
+
+func TestPrintChecks(t *testing.T) {
+	var out bytes.Buffer
+	printChecks(&out, []doctor.Check{
+		{Name: "config", Status: doctor.Pass, Detail: "built-in defaults only"},
+		{Name: "alias claude", Status: doctor.Fail, Detail: "not found\n  - Install the tool"},
+	})
+	want := `STATUS  CHECK         DETAIL
+pass    config        built-in defaults only
+fail    alias claude  not found
+                        - Install the tool
+`
+	if out.String() != want {
+		t.Errorf("Unexpected table:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
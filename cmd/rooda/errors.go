@@ -6,4 +6,5 @@ var (
 	ErrInvalidMaxIterations = errors.New("--max-iterations must be >= 1")
 	ErrEmptyContext         = errors.New("empty inline content not allowed for --context flag")
 	ErrEmptyFragment        = errors.New("empty inline content not allowed for OODA phase flag")
+	ErrInvalidPhaseMode     = errors.New("--phase-mode must be replace or append")
 )
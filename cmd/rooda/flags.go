@@ -1,6 +1,7 @@
 package main
 
 import (
+	"github.com/jomadu/rooda/internal/config"
 	"github.com/spf13/cobra"
 )
 
@@ -23,6 +24,11 @@ type ExecutionFlags struct {
 	OrientFragments  []string
 	DecideFragments  []string
 	ActFragments     []string
+	PhaseMode        string
+
+	// Record/replay
+	Record bool
+	Replay string
 }
 
 // AddExecutionFlags adds all execution flags to a command
@@ -44,9 +50,15 @@ func AddExecutionFlags(cmd *cobra.Command, flags *ExecutionFlags) {
 	cmd.Flags().StringArrayVar(&flags.OrientFragments, "orient", nil, "orient phase fragment (file path or inline, repeatable)")
 	cmd.Flags().StringArrayVar(&flags.DecideFragments, "decide", nil, "decide phase fragment (file path or inline, repeatable)")
 	cmd.Flags().StringArrayVar(&flags.ActFragments, "act", nil, "act phase fragment (file path or inline, repeatable)")
+	cmd.Flags().StringVar(&flags.PhaseMode, "phase-mode", string(config.PhaseModeReplace), "how phase fragment flags combine with the procedure (replace, append)")
+
+	// Record/replay flags
+	cmd.Flags().BoolVar(&flags.Record, "record", false, "save each iteration's prompt hash, output, exit code and duration under .rooda/runs")
+	cmd.Flags().StringVar(&flags.Replay, "replay", "", "feed a recorded run's outputs through the loop instead of calling the AI")
 
 	// Mark mutually exclusive flags
 	cmd.MarkFlagsMutuallyExclusive("max-iterations", "unlimited")
+	cmd.MarkFlagsMutuallyExclusive("record", "replay")
 }
 
 // ValidateExecutionFlags validates execution flags
@@ -85,5 +97,12 @@ func ValidateExecutionFlags(flags *ExecutionFlags) error {
 		}
 	}
 
+	// Validate phase mode
+	switch config.PhaseMode(flags.PhaseMode) {
+	case "", config.PhaseModeReplace, config.PhaseModeAppend:
+	default:
+		return ErrInvalidPhaseMode
+	}
+
 	return nil
 }
@@ -23,12 +23,7 @@ func newInfoCommand() *cobra.Command {
 			}
 
 			// Load config to get procedure names
-			flags := config.CLIFlags{
-				ConfigPath: cfgFile,
-				Verbose:    verbose,
-				Quiet:      quiet,
-				LogLevel:   logLevel,
-			}
+			flags := configFlags()
 			cfg, err := config.LoadConfig(flags)
 			if err != nil {
 				return nil, cobra.ShellCompDirectiveError
@@ -47,16 +42,10 @@ func newInfoCommand() *cobra.Command {
 }
 
 func runInfo(cmd *cobra.Command, procedureName string) error {
-	// Build CLIFlags from persistent flags
-	flags := config.CLIFlags{
-		ConfigPath: cfgFile,
-		Verbose:    verbose,
-		Quiet:      quiet,
-		LogLevel:   logLevel,
-	}
+	flags := configFlags()
 
 	// Load merged config
-	cfg, err := config.LoadConfig(flags)
+	cfg, err := loadConfig(cmd, flags)
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
@@ -75,6 +64,9 @@ func runInfo(cmd *cobra.Command, procedureName string) error {
 
 	cmd.Printf("Procedure: %s\n", procedureName)
 	cmd.Printf("Display Name: %s\n", display)
+	if proc.Extends != "" {
+		cmd.Printf("Extends: %s\n", proc.Extends)
+	}
 	cmd.Println()
 
 	// Summary
@@ -114,6 +106,18 @@ func runInfo(cmd *cobra.Command, procedureName string) error {
 		cmd.Printf("  Timeout: %ds\n", *proc.IterationTimeout)
 		hasOverrides = true
 	}
+	if proc.IterationTimeoutAuto {
+		cmd.Println("  Timeout: auto")
+		hasOverrides = true
+	}
+	if proc.IdleTimeout != nil {
+		cmd.Printf("  Idle timeout: %ds\n", *proc.IdleTimeout)
+		hasOverrides = true
+	}
+	if proc.Context != "" {
+		cmd.Printf("  Context: %s\n", proc.Context)
+		hasOverrides = true
+	}
 	if proc.AICmd != "" {
 		cmd.Printf("  AI command: %s\n", proc.AICmd)
 		hasOverrides = true
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jomadu/rooda/internal/config"
+	"github.com/jomadu/rooda/internal/scaffold"
+	"github.com/spf13/cobra"
+)
+
+func newInitCommand() *cobra.Command {
+	var alias string
+	var yes, force, noAgentsMD bool
+
+	cmd := &cobra.Command{
+		Use:   "init [dir]",
+		Short: "Set up a workspace for rooda",
+		Long: `Set up the workspace in dir (default: the current directory):
+
+  rooda-config.yml           commented config with the default AI command alias
+  .rooda/fragments/<phase>/  a directory per OODA phase for your own fragments
+  AGENTS.md                  sections for agents-sync to fill in, if missing
+  .gitignore                 entries for recorded runs and iteration stats
+
+Installed AI CLIs are detected from the built-in aliases. When stdin is a
+terminal, rooda asks which one to use; otherwise, or with --yes, the first one
+found is used. Existing files are kept, except rooda-config.yml with --force.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := "."
+			if len(args) == 1 {
+				dir = args[0]
+			}
+			interactive := !yes && alias == "" && isTerminal(cmd.InOrStdin())
+			return runInit(cmd, dir, alias, interactive, force, !noAgentsMD)
+		},
+	}
+
+	cmd.Flags().StringVar(&alias, "alias", "", "default AI command alias to configure (default: the first installed built-in alias)")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "use the defaults without asking")
+	cmd.Flags().BoolVar(&force, "force", false, "overwrite an existing rooda-config.yml")
+	cmd.Flags().BoolVar(&noAgentsMD, "no-agents-md", false, "do not create AGENTS.md")
+
+	return cmd
+}
+
+func runInit(cmd *cobra.Command, dir string, alias string, interactive bool, force bool, agentsMD bool) error {
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+
+	builtIn := config.BuiltInAliases()
+	detected := scaffold.DetectAliases(builtIn)
+	switch {
+	case alias != "":
+		if _, ok := builtIn[alias]; !ok && !quiet {
+			cmd.PrintErrf("Warning: %q is not a built-in alias, define it under ai_cmd_aliases\n", alias)
+		}
+	case interactive:
+		chosen, err := promptAlias(cmd, builtIn, detected)
+		if err != nil {
+			return err
+		}
+		alias = chosen
+	case len(detected) > 0:
+		alias = detected[0]
+	}
+
+	result, err := scaffold.Init(dir, scaffold.Options{
+		Alias:    alias,
+		Detected: detected,
+		AgentsMD: agentsMD,
+		Force:    force,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to initialize workspace: %w", err)
+	}
+	if quiet {
+		return nil
+	}
+
+	for _, path := range result.Created {
+		cmd.Printf("Created %s\n", filepath.Join(dir, path))
+	}
+	for _, path := range result.Updated {
+		cmd.Printf("Updated %s\n", filepath.Join(dir, path))
+	}
+	for _, path := range result.Skipped {
+		hint := ""
+		if path == config.ConfigFileName {
+			hint = ", use --force to overwrite"
+		}
+		cmd.Printf("Kept existing %s%s\n", filepath.Join(dir, path), hint)
+	}
+	switch {
+	case alias != "":
+		cmd.Printf("✓ Workspace ready, using AI command alias %s\n", alias)
+	default:
+		cmd.Println("✓ Workspace ready. No built-in AI CLI was found on PATH: set loop.ai_cmd_alias or loop.ai_cmd in rooda-config.yml")
+	}
+	return nil
+}
+
+// promptAlias asks which alias to configure, offering the installed ones.
+// An empty answer takes the first installed alias.
+func promptAlias(cmd *cobra.Command, builtIn map[string]string, detected []string) (string, error) {
+	names := make([]string, 0, len(builtIn))
+	for name := range builtIn {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	def := ""
+	if len(detected) > 0 {
+		def = detected[0]
+		cmd.Printf("Installed AI CLIs: %s\n", strings.Join(detected, ", "))
+	} else {
+		cmd.Println("No built-in AI CLI was found on PATH.")
+	}
+
+	in := bufio.NewReader(cmd.InOrStdin())
+	for {
+		cmd.Printf("Default AI command alias (%s) [%s]: ", strings.Join(names, ", "), def)
+		line, err := in.ReadString('\n')
+		answer := strings.TrimSpace(line)
+		if answer == "" && err == nil {
+			return def, nil
+		}
+		if _, ok := builtIn[answer]; ok {
+			return answer, nil
+		}
+		if err == io.EOF {
+			return def, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		cmd.Printf("Unknown alias %q\n", answer)
+	}
+}
+
+// isTerminal reports whether r is an interactive terminal. /dev/null is a
+// character device too, so it is ruled out explicitly.
+func isTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(info, null)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jomadu/rooda/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func TestInitIntegration(t *testing.T) {
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+
+	stdout, stderr, exitCode := runRooda(t, "init", dir, "--alias", "kiro-cli", "--no-agents-md")
+	if exitCode != ExitSuccess || !strings.Contains(stderr, "✓ Workspace ready, using AI command alias kiro-cli") {
+		t.Fatalf("Expected init to succeed, got exit %d: %s%s", exitCode, stdout, stderr)
+	}
+	if !strings.Contains(stderr, "Created "+filepath.Join(dir, ".rooda", "fragments", "observe")) {
+		t.Errorf("Expected fragment directories reported, got: %s", stderr)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "AGENTS.md")); !os.IsNotExist(err) {
+		t.Error("Expected no AGENTS.md with --no-agents-md")
+	}
+
+	stdout, stderr, exitCode = runRooda(t, "config", "get", "loop.ai_cmd_alias", "--config", filepath.Join(dir, "rooda-config.yml"))
+	if exitCode != ExitSuccess || stdout != "kiro-cli\n" {
+		t.Errorf("Expected generated config to set kiro-cli, got exit %d: %q %s", exitCode, stdout, stderr)
+	}
+
+	_, stderr, _ = runRooda(t, "init", dir, "--yes")
+	if !strings.Contains(stderr, "Kept existing "+filepath.Join(dir, "rooda-config.yml")+", use --force to overwrite") {
+		t.Errorf("Expected existing config kept, got: %s", stderr)
+	}
+}
+
+func TestPromptAlias(t *testing.T) {
+	cmd := &cobra.Command{}
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	builtIn := config.BuiltInAliases()
+
+	cmd.SetIn(strings.NewReader("nope\ncopilot\n"))
+	if got, err := promptAlias(cmd, builtIn, []string{"claude"}); err != nil || got != "copilot" {
+		t.Errorf("Expected copilot after an unknown answer, got %q %v", got, err)
+	}
+	if !strings.Contains(out.String(), `Unknown alias "nope"`) {
+		t.Errorf("Expected unknown alias message, got: %s", out.String())
+	}
+
+	cmd.SetIn(strings.NewReader("\n"))
+	if got, _ := promptAlias(cmd, builtIn, []string{"claude"}); got != "claude" {
+		t.Errorf("Expected the first installed alias by default, got %q", got)
+	}
+	cmd.SetIn(strings.NewReader(""))
+	if got, _ := promptAlias(cmd, builtIn, nil); got != "" {
+		t.Errorf("Expected no alias at end of input with none installed, got %q", got)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"sort"
 
-	"github.com/jomadu/rooda/internal/config"
 	"github.com/spf13/cobra"
 )
 
@@ -22,16 +21,10 @@ func newListCommand() *cobra.Command {
 }
 
 func runList(cmd *cobra.Command) error {
-	// Build CLIFlags from persistent flags
-	flags := config.CLIFlags{
-		ConfigPath: cfgFile,
-		Verbose:    verbose,
-		Quiet:      quiet,
-		LogLevel:   logLevel,
-	}
+	flags := configFlags()
 
 	// Load merged config (built-in + global + workspace)
-	cfg, err := config.LoadConfig(flags)
+	cfg, err := loadConfig(cmd, flags)
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
@@ -242,6 +242,41 @@ procedures:
 	if !strings.Contains(output, "OBSERVE") || !strings.Contains(output, "ORIENT") {
 		t.Errorf("Expected dry-run to show OODA phases, got stdout: %s, stderr: %s", stdout, stderr)
 	}
+
+	// Phase flags replace the phase by default, or append with --phase-mode append
+	stdout, stderr, exitCode = runRooda(t, "run", "test-proc", "--dry-run", "--config", configPath, "--observe", "Flag observe")
+	output = stdout + stderr
+	if exitCode != ExitSuccess || !strings.Contains(output, "Flag observe") || strings.Contains(output, "Test observe") {
+		t.Errorf("Expected --observe to replace the observe phase, got exit %d: %s", exitCode, output)
+	}
+	if !strings.Contains(output, "observe  1 fragment(s)  cli --observe") {
+		t.Errorf("Expected dry-run to show the observe phase source, got: %s", output)
+	}
+
+	stdout, stderr, exitCode = runRooda(t, "run", "test-proc", "--dry-run", "--config", configPath, "--act", "Flag act", "--phase-mode", "append")
+	output = stdout + stderr
+	if exitCode != ExitSuccess || !strings.Contains(output, "Test act") || !strings.Contains(output, "Flag act") {
+		t.Errorf("Expected --act to append to the act phase, got exit %d: %s", exitCode, output)
+	}
+	if !strings.Contains(output, "act      2 fragment(s)  cli --act (appended)") {
+		t.Errorf("Expected dry-run to show the appended act phase, got: %s", output)
+	}
+
+	// Interpolated values are listed with their templates
+	t.Setenv("ROODA_TEST_OBSERVE", "from env")
+	interpolatedPath := tmpDir + "/interpolated.yml"
+	if err := os.WriteFile(interpolatedPath, []byte(strings.Replace(configContent, `"Test observe"`, `"Observe ${ROODA_TEST_OBSERVE}"`, 1)), 0644); err != nil {
+		t.Fatalf("Failed to create test config: %v", err)
+	}
+	stdout, stderr, exitCode = runRooda(t, "run", "test-proc", "--dry-run", "--config", interpolatedPath)
+	output = stdout + stderr
+	if exitCode != ExitSuccess || !strings.Contains(output, "Observe from env") {
+		t.Errorf("Expected interpolated observe content, got exit %d: %s", exitCode, output)
+	}
+	if !strings.Contains(output, `procedures.test-proc.observe[0].content = "Observe from env"`) ||
+		!strings.Contains(output, `from "Observe ${ROODA_TEST_OBSERVE}" (env ROODA_TEST_OBSERVE) in `+interpolatedPath) {
+		t.Errorf("Expected dry-run to list the interpolated value, got: %s", output)
+	}
 }
 
 // Test short flags
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jomadu/rooda/internal/config"
+	"github.com/jomadu/rooda/internal/pack"
+	"github.com/spf13/cobra"
+)
+
+func newPackCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pack",
+		Short: "Install and check procedure packs",
+		Long: `Install versioned bundles of procedures and fragments, and check that
+installed packs still match what was installed.
+
+A pack is a directory with a rooda-pack.yml manifest (name, version,
+description) and config files under procedures/. Packs install into the
+global config directory, or into .rooda/ with --workspace.`,
+	}
+
+	cmd.AddCommand(newPackInstallCommand())
+	cmd.AddCommand(newPackVerifyCommand())
+	cmd.AddCommand(newPackListCommand())
+
+	return cmd
+}
+
+// packRoot returns where packs and the lockfile live.
+func packRoot(workspace bool) string {
+	if workspace {
+		return config.WorkspaceDir
+	}
+	return config.GlobalConfigDir()
+}
+
+func newPackInstallCommand() *cobra.Command {
+	var workspace bool
+
+	cmd := &cobra.Command{
+		Use:   "install <path>",
+		Short: "Install a pack from a local directory or git checkout",
+		Long: `Copy the pack at <path> into the packs directory, replacing any installed
+version, and record its version and file hashes in rooda-pack.lock.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := packRoot(workspace)
+			manifest, locked, err := pack.Install(args[0], root)
+			if err != nil {
+				return fmt.Errorf("failed to install pack: %w", err)
+			}
+			procedures, err := pack.Procedures(filepath.Join(pack.Dir(root), manifest.Name))
+			if err != nil {
+				return err
+			}
+			cmd.Printf("Installed %s %s into %s (%d procedure(s), %d file(s))\n",
+				manifest.Name, manifest.Version, filepath.Join(pack.Dir(root), manifest.Name), len(procedures), len(locked.Files))
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&workspace, "workspace", false, "install into "+config.WorkspaceDir+" instead of the global config directory")
+
+	return cmd
+}
+
+func newPackVerifyCommand() *cobra.Command {
+	var workspace bool
+
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Check installed packs against rooda-pack.lock",
+		Long: `Compare every installed pack with rooda-pack.lock. Reports modified, missing
+and unexpected files and version drift, and exits 1 if any are found.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := packRoot(workspace)
+			lock, err := pack.ReadLock(root)
+			if err != nil {
+				return err
+			}
+			problems, err := pack.Verify(root)
+			if err != nil {
+				return err
+			}
+			for _, p := range problems {
+				cmd.PrintErrln(p.String())
+			}
+			if len(problems) > 0 {
+				return fmt.Errorf("pack verification found %d problem(s)", len(problems))
+			}
+			cmd.Printf("✓ %d pack(s) match %s\n", len(lock.Packs), filepath.Join(root, pack.LockFile))
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&workspace, "workspace", false, "check packs in "+config.WorkspaceDir+" instead of the global config directory")
+
+	return cmd
+}
+
+func newPackListCommand() *cobra.Command {
+	var workspace bool
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List installed packs and the procedures they provide",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := packRoot(workspace)
+			lock, err := pack.ReadLock(root)
+			if err != nil {
+				return err
+			}
+			if len(lock.Packs) == 0 {
+				cmd.Println("No packs installed")
+				return nil
+			}
+
+			for i, name := range lock.Names() {
+				if i > 0 {
+					cmd.Println()
+				}
+				locked := lock.Packs[name]
+				dir := filepath.Join(pack.Dir(root), name)
+				source := locked.Source
+				if locked.Commit != "" {
+					source += " @ " + locked.Commit
+				}
+				cmd.Printf("%s %s\n", name, locked.Version)
+				cmd.Printf("  Source: %s\n", source)
+				if manifest, err := pack.LoadManifest(dir); err == nil && manifest.Description != "" {
+					cmd.Printf("  %s\n", manifest.Description)
+				}
+
+				procedures, err := pack.Procedures(dir)
+				if err != nil {
+					cmd.Printf("  Procedures: unreadable (%v)\n", err)
+					continue
+				}
+				names := make([]string, 0, len(procedures))
+				for proc := range procedures {
+					names = append(names, proc)
+				}
+				sort.Strings(names)
+				cmd.Printf("  Procedures (%d):\n", len(names))
+				for _, proc := range names {
+					if summary := procedures[proc]; summary != "" {
+						cmd.Printf("    %s - %s\n", proc, summary)
+					} else {
+						cmd.Printf("    %s\n", proc)
+					}
+				}
+				cmd.Printf("  Files: %d (%s)\n", len(locked.Files), strings.Join(fileKinds(locked.Files), ", "))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&workspace, "workspace", false, "list packs in "+config.WorkspaceDir+" instead of the global config directory")
+
+	return cmd
+}
+
+// fileKinds counts a pack's files by top-level directory, e.g. "3 fragments".
+func fileKinds(files map[string]string) []string {
+	counts := make(map[string]int)
+	for file := range files {
+		dir, _, found := strings.Cut(file, "/")
+		if !found {
+			dir = "top level"
+		}
+		counts[dir]++
+	}
+	kinds := make([]string, 0, len(counts))
+	for dir, n := range counts {
+		kinds = append(kinds, fmt.Sprintf("%d %s", n, dir))
+	}
+	sort.Strings(kinds)
+	return kinds
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPackIntegration(t *testing.T) {
+	tmpDir := t.TempDir()
+	src := filepath.Join(tmpDir, "team-pack")
+	files := map[string]string{
+		"rooda-pack.yml":        "name: team\nversion: 1.0.0\ndescription: Vetted team prompts\n",
+		"procedures/review.yml": "procedures:\n  team-review:\n    summary: Review the diff\n    act:\n      - path: ../fragments/review.md\n",
+		"fragments/review.md":   "Review the diff.\n",
+	}
+	for rel, content := range files {
+		path := filepath.Join(src, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	configHome := filepath.Join(tmpDir, "config")
+	t.Setenv("ROODA_CONFIG_HOME", configHome)
+
+	run := func(wantExitCode int, wantOutput string, args ...string) {
+		t.Helper()
+		stdout, stderr, exitCode := runRooda(t, args...)
+		output := stdout + stderr
+		if exitCode != wantExitCode {
+			t.Errorf("rooda %s: expected exit code %d, got %d. output: %s", strings.Join(args, " "), wantExitCode, exitCode, output)
+		}
+		if !strings.Contains(output, wantOutput) {
+			t.Errorf("rooda %s: expected output to contain %q, got: %s", strings.Join(args, " "), wantOutput, output)
+		}
+	}
+
+	run(ExitSuccess, "No packs installed", "pack", "list")
+	run(ExitSuccess, "Installed team 1.0.0 into "+filepath.Join(configHome, "packs", "team")+" (1 procedure(s), 3 file(s))", "pack", "install", src)
+	run(ExitSuccess, "team-review - Review the diff", "pack", "list")
+	run(ExitSuccess, "✓ 1 pack(s) match", "pack", "verify")
+	run(ExitSuccess, "Review the diff.", "run", "team-review", "--dry-run")
+
+	installed := filepath.Join(configHome, "packs", "team", "fragments", "review.md")
+	if err := os.WriteFile(installed, []byte("Approve everything.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run(ExitUserError, "team: fragments/review.md was modified", "pack", "verify")
+	run(ExitUserError, "no such file or directory", "pack", "install", filepath.Join(tmpDir, "missing"))
+}
@@ -3,15 +3,18 @@ package main
 import (
 	"fmt"
 
+	"github.com/jomadu/rooda/internal/config"
 	"github.com/spf13/cobra"
 )
 
 var (
 	// Persistent flags (available to all commands)
-	cfgFile  string
-	verbose  bool
-	quiet    bool
-	logLevel string
+	cfgFile    string
+	noDiscover bool
+	profile    string
+	verbose    bool
+	quiet      bool
+	logLevel   string
 )
 
 func newRootCommand() *cobra.Command {
@@ -31,6 +34,8 @@ iteration loops to autonomously build, plan, and maintain software from specific
 
 	// Persistent flags available to all commands
 	cmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./rooda-config.yml)")
+	cmd.PersistentFlags().BoolVar(&noDiscover, "no-discover", false, "only load ./rooda-config.yml, not rooda-config.yml files in parent directories up to the git root")
+	cmd.PersistentFlags().StringVar(&profile, "profile", "", "config profile to apply (default is $ROODA_PROFILE)")
 	cmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output (sets show_ai_output=true and log_level=debug)")
 	cmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "suppress all non-error output")
 	cmd.PersistentFlags().StringVar(&logLevel, "log-level", "", "log level (debug, info, warn, error)")
@@ -43,10 +48,28 @@ iteration loops to autonomously build, plan, and maintain software from specific
 	cmd.AddCommand(newInfoCommand())
 	cmd.AddCommand(newVersionCommand())
 	cmd.AddCommand(newRunCommand())
+	cmd.AddCommand(newTestCommand())
+	cmd.AddCommand(newAliasCommand())
+	cmd.AddCommand(newConfigCommand())
+	cmd.AddCommand(newPackCommand())
+	cmd.AddCommand(newInitCommand())
+	cmd.AddCommand(newDoctorCommand())
 
 	return cmd
 }
 
+// configFlags builds CLIFlags from the persistent flags.
+func configFlags() config.CLIFlags {
+	return config.CLIFlags{
+		ConfigPath: cfgFile,
+		NoDiscover: noDiscover,
+		Profile:    profile,
+		Verbose:    verbose,
+		Quiet:      quiet,
+		LogLevel:   logLevel,
+	}
+}
+
 func Execute() error {
 	return newRootCommand().Execute()
 }
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/jomadu/rooda/internal/ai"
 	"github.com/jomadu/rooda/internal/config"
 	"github.com/jomadu/rooda/internal/loop"
 	"github.com/jomadu/rooda/internal/observability"
@@ -66,7 +67,7 @@ func runProcedure(cmd *cobra.Command, procedureName string, execFlags *Execution
 	}
 
 	// Load configuration
-	cfg, err := config.LoadConfig(flags)
+	cfg, err := loadConfig(cmd, flags)
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
@@ -77,10 +78,13 @@ func runProcedure(cmd *cobra.Command, procedureName string, execFlags *Execution
 		return fmt.Errorf("unknown procedure '%s'\n\nRun 'rooda list' to see available procedures", procedureName)
 	}
 
-	// Resolve AI command
+	// Resolve AI command (a replayed run does not need one)
 	aiCmd, err := config.ResolveAICommand(*cfg, procedureName, flags)
 	if err != nil {
-		return fmt.Errorf("failed to resolve AI command: %w", err)
+		if execFlags.Replay == "" {
+			return fmt.Errorf("failed to resolve AI command: %w", err)
+		}
+		aiCmd = config.AICommand{Source: "--replay"}
 	}
 
 	// Determine max iterations
@@ -100,10 +104,27 @@ func runProcedure(cmd *cobra.Command, procedureName string, execFlags *Execution
 
 	// Determine iteration timeout
 	var iterationTimeout *int
-	if proc.IterationTimeout != nil {
+	adaptiveTimeout := false
+	if proc.IterationTimeout != nil || proc.IterationTimeoutAuto {
 		iterationTimeout = proc.IterationTimeout
+		adaptiveTimeout = proc.IterationTimeoutAuto
 	} else {
 		iterationTimeout = cfg.Loop.IterationTimeout
+		adaptiveTimeout = cfg.Loop.IterationTimeoutAuto
+	}
+
+	// Determine idle timeout
+	var idleTimeout *int
+	if proc.IdleTimeout != nil {
+		idleTimeout = proc.IdleTimeout
+	} else {
+		idleTimeout = cfg.Loop.IdleTimeout
+	}
+
+	// Determine resource limits
+	var resourceLimits *config.ResourceLimits
+	if limits := config.ResolveResourceLimits(*cfg, procedureName); !limits.IsZero() {
+		resourceLimits = &limits
 	}
 
 	// Determine max output buffer
@@ -136,6 +157,10 @@ func runProcedure(cmd *cobra.Command, procedureName string, execFlags *Execution
 		Iteration:           0,
 		MaxIterations:       maxIterations,
 		IterationTimeout:    iterationTimeout,
+		IdleTimeout:         idleTimeout,
+		IdleTimeoutFailure:  cfg.Loop.IdleTimeoutFailure,
+		ResourceLimits:      resourceLimits,
+		ContextMode:         proc.Context,
 		MaxOutputBuffer:     maxOutputBuffer,
 		ConsecutiveFailures: 0,
 		FailureThreshold:    cfg.Loop.FailureThreshold,
@@ -143,6 +168,75 @@ func runProcedure(cmd *cobra.Command, procedureName string, execFlags *Execution
 		Status:              loop.StatusRunning,
 		ProcedureName:       procedureName,
 		Stats:               loop.IterationStats{},
+		RetryPolicy: loop.RetryPolicy{
+			Backoff:    time.Duration(cfg.Loop.RetryBackoff) * time.Second,
+			MaxBackoff: time.Duration(cfg.Loop.RetryBackoffMax) * time.Second,
+			MaxRetries: cfg.Loop.MaxRetries,
+		},
+	}
+
+	if resourceLimits != nil && !ai.ResourceLimitsSupported {
+		logger.Warn("resource_limits are only enforced on Linux; running without them", map[string]interface{}{
+			"limits": resourceLimits.String(),
+		})
+	}
+
+	// Record or replay AI invocations
+	if execFlags.Record {
+		runDir := filepath.Join(ai.DefaultRunsDir, fmt.Sprintf("%s-%s", state.StartedAt.Format("20060102-150405"), procedureName))
+		recorder, err := ai.NewRecorder(runDir, ai.RunInfo{
+			Procedure: procedureName,
+			AICmd:     aiCmd.Source,
+			StartedAt: state.StartedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to start recording: %w", err)
+		}
+		defer recorder.Close()
+		state.Recorder = recorder
+		logger.Info("Recording run", map[string]interface{}{
+			"dir": runDir,
+		})
+	}
+	if execFlags.Replay != "" {
+		replay, err := ai.LoadReplay(execFlags.Replay)
+		if err != nil {
+			return fmt.Errorf("failed to load replay %s: %w", execFlags.Replay, err)
+		}
+		if replay.Info.Procedure != procedureName {
+			logger.Warn("Replaying a run recorded for a different procedure", map[string]interface{}{
+				"recorded": replay.Info.Procedure,
+			})
+		}
+		replay.OnPromptMismatch = func(invocation int) {
+			logger.Warn(fmt.Sprintf("Replay invocation %d: prompt no longer matches the recording", invocation), nil)
+		}
+		state.Executor = replay
+		logger.Info("Replaying run", map[string]interface{}{
+			"dir":         execFlags.Replay,
+			"invocations": replay.Remaining(),
+		})
+	}
+
+	// Seed adaptive timeouts from durations recorded in previous runs, kept at
+	// the workspace root so runs from subdirectories share them
+	var statsStore *loop.StatsStore
+	statsPath := filepath.Join(config.WorkspaceRoot(!noDiscover), loop.DefaultStatsPath)
+	if adaptiveTimeout {
+		statsStore, err = loop.LoadStatsStore(statsPath)
+		if err != nil {
+			logger.Warn("Ignoring unreadable iteration stats", map[string]interface{}{
+				"error": err.Error(),
+			})
+			statsStore = loop.NewStatsStore(statsPath)
+		}
+		state.AdaptiveTimeout = &loop.AdaptiveTimeout{
+			K:          cfg.Loop.AdaptiveTimeout.K,
+			Floor:      cfg.Loop.AdaptiveTimeout.Floor,
+			Ceiling:    cfg.Loop.AdaptiveTimeout.Ceiling,
+			MinSamples: cfg.Loop.AdaptiveTimeout.MinSamples,
+			History:    statsStore.Get(procedureName),
+		}
 	}
 
 	// Join user contexts
@@ -151,6 +245,17 @@ func runProcedure(cmd *cobra.Command, procedureName string, execFlags *Execution
 	// Run loop
 	status := loop.RunLoop(state, *cfg, aiCmd, userContext, showAIOutput, logger)
 
+	// Persist durations for the next run's adaptive timeouts (replayed durations are not real)
+	if statsStore != nil && state.Executor == nil {
+		statsStore.Set(procedureName, *state.AdaptiveTimeout.History)
+		if err := statsStore.Save(); err != nil {
+			logger.Warn("Failed to save iteration stats", map[string]interface{}{
+				"path":  statsPath,
+				"error": err.Error(),
+			})
+		}
+	}
+
 	// Map status to exit code (return nil for success, error for failure)
 	switch status {
 	case loop.StatusSuccess, loop.StatusMaxIters, loop.StatusInterrupted:
@@ -168,11 +273,14 @@ func runDryRunMode(cmd *cobra.Command, flags config.CLIFlags) error {
 
 	// Load and validate configuration
 	cmd.Println("Loading configuration...")
-	cfg, err := config.LoadConfig(flags)
+	cfg, err := loadConfig(cmd, flags)
 	if err != nil {
 		return fmt.Errorf("configuration validation failed: %w", err)
 	}
 	cmd.Println("✓ Configuration valid")
+	if cfg.Profile != "" {
+		cmd.Printf("✓ Profile '%s' applied\n", cfg.Profile)
+	}
 	cmd.Println()
 
 	// Validate procedure exists
@@ -182,22 +290,49 @@ func runDryRunMode(cmd *cobra.Command, flags config.CLIFlags) error {
 	}
 	cmd.Printf("✓ Procedure '%s' found\n\n", flags.ProcedureName)
 
-	// Assemble prompt
-	cmd.Println("Assembling prompt...")
-	userContext := strings.Join(flags.Contexts, "\n\n")
+	// Show where each phase's fragments come from
+	cmd.Println("Phases:")
+	for _, phase := range []struct {
+		name      string
+		fragments []config.FragmentAction
+	}{
+		{"observe", proc.Observe},
+		{"orient", proc.Orient},
+		{"decide", proc.Decide},
+		{"act", proc.Act},
+	} {
+		cmd.Printf("  %-8s %d fragment(s)  %s\n", phase.name, len(phase.fragments), phaseSource(cfg, flags, phase.name))
+	}
+	cmd.Println()
 
-	// Determine config directory for fragment resolution
-	configPath := "./rooda-config.yml"
-	if flags.ConfigPath != "" {
-		configPath = flags.ConfigPath
+	// Show values expanded from ${...} that affect this run
+	var interpolated []config.Interpolation
+	for _, in := range cfg.Interpolations() {
+		if strings.HasPrefix(in.Path, "loop.") || strings.HasPrefix(in.Path, "ai_cmd_aliases.") ||
+			strings.HasPrefix(in.Path, "procedures."+flags.ProcedureName+".") {
+			interpolated = append(interpolated, in)
+		}
 	}
-	configDir := "."
-	if dir := filepath.Dir(configPath); dir != "" {
-		configDir = dir
+	if len(interpolated) > 0 {
+		cmd.Println("Interpolated values:")
+		for _, in := range interpolated {
+			cmd.Printf("  %s = %q\n", in.Path, in.Value)
+			from := fmt.Sprintf("from %q", in.Template)
+			if len(in.References) > 0 {
+				from += " (" + strings.Join(in.References, ", ") + ")"
+			}
+			cmd.Printf("    %s in %s\n", from, in.File)
+		}
+		cmd.Println()
 	}
 
-	// For dry-run, we don't have iteration state, so pass nil
-	assembledPrompt, err := prompt.AssemblePrompt(proc, userContext, configDir, nil)
+	// Assemble prompt
+	cmd.Println("Assembling prompt...")
+	userContext := strings.Join(flags.Contexts, "\n\n")
+
+	// Fragment paths are resolved when config loads, so no config directory is
+	// needed. For dry-run, we don't have iteration state, so pass nil
+	assembledPrompt, err := prompt.AssemblePrompt(proc, userContext, "", nil)
 	if err != nil {
 		return fmt.Errorf("prompt assembly failed: %w", err)
 	}
@@ -215,19 +350,30 @@ func runDryRunMode(cmd *cobra.Command, flags config.CLIFlags) error {
 	return nil
 }
 
-func buildCLIFlags(execFlags *ExecutionFlags, procedureName string) config.CLIFlags {
-	flags := config.CLIFlags{
-		ProcedureName: procedureName,
-		ConfigPath:    cfgFile,
-		Verbose:       verbose,
-		Quiet:         quiet,
-		LogLevel:      logLevel,
-		DryRun:        execFlags.DryRun,
-		Unlimited:     execFlags.Unlimited,
-		AICmd:         execFlags.AICmd,
-		AICmdAlias:    execFlags.AICmdAlias,
-		Contexts:      execFlags.Contexts,
+// phaseSource describes where a procedure phase's fragments came from, falling
+// back to the procedure's own source for phases not set separately.
+func phaseSource(cfg *config.Config, flags config.CLIFlags, phase string) string {
+	path := "procedures." + flags.ProcedureName + "." + phase
+	source, ok := cfg.Provenance[path]
+	if !ok {
+		path = "procedures." + flags.ProcedureName
+		source = cfg.Provenance[path]
+	}
+	description := source.Describe(path)
+	if source.Tier == config.TierCLIFlag && flags.PhaseMode == config.PhaseModeAppend {
+		description += " (appended)"
 	}
+	return description
+}
+
+func buildCLIFlags(execFlags *ExecutionFlags, procedureName string) config.CLIFlags {
+	flags := configFlags()
+	flags.ProcedureName = procedureName
+	flags.DryRun = execFlags.DryRun
+	flags.Unlimited = execFlags.Unlimited
+	flags.AICmd = execFlags.AICmd
+	flags.AICmdAlias = execFlags.AICmdAlias
+	flags.Contexts = execFlags.Contexts
 
 	if execFlags.MaxIterations > 0 {
 		flags.MaxIterations = &execFlags.MaxIterations
@@ -246,6 +392,7 @@ func buildCLIFlags(execFlags *ExecutionFlags, procedureName string) config.CLIFl
 	if len(execFlags.ActFragments) > 0 {
 		flags.ActFragments = execFlags.ActFragments
 	}
+	flags.PhaseMode = config.PhaseMode(execFlags.PhaseMode)
 
 	return flags
 }
@@ -131,6 +131,16 @@ func TestRunCommandFlags(t *testing.T) {
 			args:    []string{"run", "agents-sync", "--observe", "custom.md", "--dry-run"},
 			wantErr: false,
 		},
+		{
+			name:    "phase-mode append",
+			args:    []string{"run", "agents-sync", "--act", "extra step", "--phase-mode", "append", "--dry-run"},
+			wantErr: false,
+		},
+		{
+			name:    "invalid phase-mode",
+			args:    []string{"run", "agents-sync", "--act", "extra step", "--phase-mode", "merge", "--dry-run"},
+			wantErr: true,
+		},
 		{
 			name:    "mutually exclusive max-iterations and unlimited",
 			args:    []string{"run", "agents-sync", "--max-iterations", "5", "--unlimited"},
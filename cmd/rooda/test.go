@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jomadu/rooda/internal/proctest"
+	"github.com/spf13/cobra"
+)
+
+func newTestCommand() *cobra.Command {
+	var junitPath string
+
+	cmd := &cobra.Command{
+		Use:   "test <file>",
+		Short: "Run declarative procedure tests",
+		Long: `Run the test cases in a suite file against the configured procedures.
+
+Each case asserts on the assembled prompt and can script a sequence of agent
+responses to check the loop's final status and iteration count. No AI CLI is run.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTests(cmd, args[0], junitPath)
+		},
+	}
+
+	cmd.Flags().StringVar(&junitPath, "junit", "", "write results as JUnit XML to this path")
+
+	return cmd
+}
+
+func runTests(cmd *cobra.Command, suitePath string, junitPath string) error {
+	flags := configFlags()
+
+	cfg, err := loadConfig(cmd, flags)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	suite, err := proctest.LoadSuite(suitePath)
+	if err != nil {
+		return fmt.Errorf("failed to load test suite: %w", err)
+	}
+
+	results := proctest.Run(suite, *cfg)
+
+	failed := 0
+	for _, r := range results {
+		if r.Passed() {
+			if !quiet {
+				cmd.Printf("PASS  %s\n", r.Case.Name)
+			}
+			continue
+		}
+		failed++
+		cmd.Printf("FAIL  %s\n", r.Case.Name)
+		for _, f := range r.Failures {
+			cmd.Printf("      %s\n", f)
+		}
+	}
+
+	if junitPath != "" {
+		f, err := os.Create(junitPath)
+		if err != nil {
+			return fmt.Errorf("failed to write JUnit report: %w", err)
+		}
+		err = proctest.WriteJUnit(f, suitePath, results)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write JUnit report: %w", err)
+		}
+	}
+
+	if !quiet {
+		cmd.Printf("\n%d passed, %d failed\n", len(results)-failed, failed)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d test cases failed", failed, len(results))
+	}
+	return nil
+}
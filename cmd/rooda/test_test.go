@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTestCommandIntegration(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "rooda-config.yml")
+	configContent := `
+procedures:
+  review:
+    observe:
+      - content: "Read the {{.target}} code."
+    act:
+      - content: "Review {{.target}}."
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config: %v", err)
+	}
+	suitePath := filepath.Join(tmpDir, "procedures.test.yml")
+	suiteContent := `
+cases:
+  - name: prompt mentions target
+    procedure: review
+    params: {target: api}
+    prompt:
+      contains: ["Review api."]
+      phase_order: [observe, act]
+  - name: succeeds first time
+    procedure: review
+    params: {target: api}
+    responses:
+      - output: "<promise>SUCCESS</promise>"
+    expect:
+      status: success
+      iterations: 2
+`
+	if err := os.WriteFile(suitePath, []byte(suiteContent), 0644); err != nil {
+		t.Fatalf("Failed to create test suite: %v", err)
+	}
+	junitPath := filepath.Join(tmpDir, "junit.xml")
+
+	stdout, stderr, exitCode := runRooda(t, "test", suitePath, "--config", configPath, "--junit", junitPath)
+	output := stdout + stderr
+
+	if exitCode != ExitUserError {
+		t.Errorf("Expected exit code %d for failing case, got %d. output: %s", ExitUserError, exitCode, output)
+	}
+	for _, want := range []string{
+		"PASS  prompt mentions target",
+		"FAIL  succeeds first time",
+		"loop ran 1 iterations, want 2",
+		"1 passed, 1 failed",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got: %s", want, output)
+		}
+	}
+
+	report, err := os.ReadFile(junitPath)
+	if err != nil {
+		t.Fatalf("Expected JUnit report: %v", err)
+	}
+	if !strings.Contains(string(report), `<testsuites tests="2" failures="1">`) {
+		t.Errorf("Unexpected JUnit report: %s", report)
+	}
+}
@@ -0,0 +1,18 @@
+package ai
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ansiPattern matches CSI sequences (colors, cursor movement), OSC sequences
+// (window titles, hyperlinks) and two-character escapes.
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+// StripANSI removes terminal escape sequences and normalizes the CRLF line
+// endings a pseudo-terminal produces, leaving plain text suitable for signal
+// detection and transcripts.
+func StripANSI(s string) string {
+	s = ansiPattern.ReplaceAllString(s, "")
+	return strings.ReplaceAll(s, "\r\n", "\n")
+}
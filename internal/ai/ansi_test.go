@@ -0,0 +1,27 @@
+package ai
+
+import "testing"
+
+func TestStripANSI(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain text", "hello\n", "hello\n"},
+		{"colors", "\x1b[1;31merror\x1b[0m: failed", "error: failed"},
+		{"cursor movement", "\x1b[2K\x1b[1Gspinner", "spinner"},
+		{"osc title", "\x1b]0;agent\x07ready", "ready"},
+		{"osc hyperlink", "\x1b]8;;https://example.com\x1b\\link\x1b]8;;\x1b\\", "link"},
+		{"crlf", "line1\r\nline2\r\n", "line1\nline2\n"},
+		{"signal survives", "\x1b[32m<promise>SUCCESS</promise>\x1b[0m\r\n", "<promise>SUCCESS</promise>\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StripANSI(tt.input); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package ai
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/jomadu/rooda/internal/config"
+)
+
+// ClassifyResult returns the class of the first classifier matching the output and
+// exit code, or "" when none match. Output that carries a promise signal is never
+// classified: the agent ran, so the iteration outcome belongs to the signal.
+//
+// A classifier without exit_codes only matches non-zero exits, so a pattern like
+// "429" cannot turn a successful iteration that merely mentions it into a retry.
+func ClassifyResult(classifiers []config.OutputClassifier, output string, exitCode int) config.ErrorClass {
+	if strings.Contains(output, "<promise>SUCCESS</promise>") || strings.Contains(output, "<promise>FAILURE</promise>") {
+		return ""
+	}
+
+	for _, c := range classifiers {
+		if len(c.ExitCodes) > 0 {
+			if !containsInt(c.ExitCodes, exitCode) {
+				continue
+			}
+		} else if exitCode == 0 {
+			continue
+		}
+
+		if c.Pattern != "" {
+			re, err := regexp.Compile(c.Pattern)
+			if err != nil || !re.MatchString(output) {
+				continue
+			}
+		}
+
+		return c.Class
+	}
+
+	return ""
+}
+
+func containsInt(values []int, v int) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
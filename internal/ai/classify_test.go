@@ -0,0 +1,46 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/jomadu/rooda/internal/config"
+)
+
+func TestClassifyResult(t *testing.T) {
+	classifiers := []config.OutputClassifier{
+		{Class: config.ClassAuthError, Pattern: `(?i)invalid api key`},
+		{Class: config.ClassRateLimited, Pattern: `(?i)rate limit|\b429\b`},
+		{Class: config.ClassTransient, ExitCodes: []int{75}},
+		{Class: config.ClassTransient, Pattern: `ECONNRESET`, ExitCodes: []int{0, 1}},
+	}
+
+	tests := []struct {
+		name     string
+		output   string
+		exitCode int
+		want     config.ErrorClass
+	}{
+		{"auth error", "Error: Invalid API key provided", 1, config.ClassAuthError},
+		{"rate limited", "HTTP 429 Too Many Requests", 1, config.ClassRateLimited},
+		{"exit code only", "temporary failure", 75, config.ClassTransient},
+		{"pattern with explicit exit 0", "read ECONNRESET", 0, config.ClassTransient},
+		{"pattern ignores exit 0 by default", "discussed rate limit handling", 0, ""},
+		{"no match", "compile error", 2, ""},
+		{"signal wins", "429\n<promise>FAILURE</promise>", 1, ""},
+		{"exit code mismatch", "read ECONNRESET", 2, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyResult(classifiers, tt.output, tt.exitCode); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestClassifyResult_NoClassifiers(t *testing.T) {
+	if got := ClassifyResult(nil, "rate limit", 1); got != "" {
+		t.Errorf("expected no classification, got %q", got)
+	}
+}
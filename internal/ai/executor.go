@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/jomadu/rooda/internal/config"
@@ -14,7 +15,12 @@ import (
 )
 
 var ErrTimeout = errors.New("AI CLI execution timeout")
+var ErrIdleTimeout = errors.New("AI CLI idle timeout")
 var ErrInterrupted = errors.New("interrupted by signal")
+var ErrResourceLimit = errors.New("AI CLI exceeded a resource limit")
+
+// outputDrainDelay bounds how long to wait for output pipes to close after the process exits.
+const outputDrainDelay = 500 * time.Millisecond
 
 type AIExecutionResult struct {
 	Output    string
@@ -24,7 +30,52 @@ type AIExecutionResult struct {
 	Error     error
 }
 
+// ExecOptions controls how a single AI CLI invocation is run.
+type ExecOptions struct {
+	Verbose          bool // Stream output to stdout while capturing
+	IterationTimeout *int // Wall-clock timeout in seconds (nil = no timeout)
+	IdleTimeout      *int // Kill after this many seconds without output (nil = no idle watchdog)
+	MaxOutputBuffer  int  // Max captured output in bytes
+	// ResourceLimits are applied to the AI CLI's process group (Linux only, nil = none)
+	ResourceLimits *config.ResourceLimits
+}
+
+// Executor runs a prompt against an AI backend and reports the outcome in the
+// same shape for every backend, so the loop can treat them alike.
+type Executor interface {
+	Execute(prompt string, opts ExecOptions, sigChan <-chan os.Signal) AIExecutionResult
+}
+
+// NewExecutor returns the executor for the resolved AI command's backend.
+func NewExecutor(aiCmd config.AICommand) Executor {
+	if aiCmd.Options.Backend == config.BackendHTTP && aiCmd.Options.HTTP != nil {
+		return NewHTTPExecutor(*aiCmd.Options.HTTP)
+	}
+	return SubprocessExecutor{Command: aiCmd}
+}
+
+// SubprocessExecutor runs an AI CLI binary with the prompt on stdin.
+type SubprocessExecutor struct {
+	Command config.AICommand
+}
+
+func (e SubprocessExecutor) Execute(prompt string, opts ExecOptions, sigChan <-chan os.Signal) AIExecutionResult {
+	return ExecuteAICLIWithOptions(e.Command, prompt, opts, sigChan)
+}
+
 func ExecuteAICLI(aiCmd config.AICommand, prompt string, verbose bool, aiExecutionTimeout *int, maxBuffer int, sigChan <-chan os.Signal) AIExecutionResult {
+	return ExecuteAICLIWithOptions(aiCmd, prompt, ExecOptions{
+		Verbose:          verbose,
+		IterationTimeout: aiExecutionTimeout,
+		MaxOutputBuffer:  maxBuffer,
+	}, sigChan)
+}
+
+// ExecuteAICLIWithOptions runs the AI CLI with the prompt on stdin and captures
+// combined stdout/stderr. The process is killed when the iteration timeout
+// elapses, when no output arrives within the idle timeout, or when a signal
+// is received on sigChan.
+func ExecuteAICLIWithOptions(aiCmd config.AICommand, prompt string, opts ExecOptions, sigChan <-chan os.Signal) AIExecutionResult {
 	startTime := time.Now()
 
 	parts, err := shellquote.Split(aiCmd.Command)
@@ -42,73 +93,137 @@ func ExecuteAICLI(aiCmd config.AICommand, prompt string, verbose bool, aiExecuti
 	cmd.Dir, _ = os.Getwd()
 	cmd.Env = os.Environ()
 	cmd.Stdin = strings.NewReader(prompt)
+	// Children that inherit stdout can keep the pipe open after a kill;
+	// stop waiting on them shortly after the AI CLI itself exits.
+	cmd.WaitDelay = outputDrainDelay
 
 	var outputBuffer bytes.Buffer
 	var outputWriter io.Writer = &outputBuffer
 
-	if verbose {
+	if opts.Verbose {
 		outputWriter = io.MultiWriter(&outputBuffer, os.Stdout)
 	}
 
-	cmd.Stdout = outputWriter
-	cmd.Stderr = outputWriter
+	activity := &activityWriter{w: outputWriter}
+	activity.touch()
 
-	if err := cmd.Start(); err != nil {
-		return AIExecutionResult{
-			Error:    err,
-			Duration: time.Since(startTime),
-		}
+	startCmd := cmd.Start
+	var limiter *processLimiter
+	if opts.ResourceLimits != nil && !opts.ResourceLimits.IsZero() {
+		limiter = newProcessLimiter(*opts.ResourceLimits)
+	}
+	if limiter != nil {
+		defer limiter.release()
+		limiter.prepare(cmd)
+		startCmd = func() error { return limiter.start(cmd, cmd.Start) }
 	}
 
-	done := make(chan error, 1)
-	go func() {
-		done <- cmd.Wait()
-	}()
+	// captured returns the output used for signal detection and transcripts.
+	// Under a PTY the raw stream (shown in verbose mode) carries ANSI escapes.
+	captured := outputBuffer.String
+	var ptyMaster *os.File
+	var ptyCopied chan struct{}
 
-	var waitErr error
-	if aiExecutionTimeout != nil {
-		select {
-		case waitErr = <-done:
-		case <-time.After(time.Duration(*aiExecutionTimeout) * time.Second):
-			cmd.Process.Kill()
-			<-done
+	if aiCmd.Options.TTY {
+		ptyMaster, err = startWithPTY(cmd, startCmd)
+		if err != nil {
 			return AIExecutionResult{
-				Output:   outputBuffer.String(),
+				Error:    err,
 				Duration: time.Since(startTime),
-				Error:    ErrTimeout,
 			}
-		case <-sigChan:
-			// Signal received - kill process
-			cmd.Process.Kill()
-			<-done
+		}
+		ptyCopied = make(chan struct{})
+		go func() {
+			io.Copy(activity, ptyMaster)
+			close(ptyCopied)
+		}()
+		captured = func() string { return StripANSI(outputBuffer.String()) }
+	} else {
+		cmd.Stdout = activity
+		cmd.Stderr = activity
+
+		if err := startCmd(); err != nil {
 			return AIExecutionResult{
-				Output:   outputBuffer.String(),
+				Error:    err,
 				Duration: time.Since(startTime),
-				Error:    ErrInterrupted,
 			}
 		}
-	} else {
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		if ptyMaster != nil {
+			drainPTY(ptyMaster, ptyCopied)
+		}
+		done <- err
+	}()
+
+	killWith := func(reason error) AIExecutionResult {
+		if limiter != nil {
+			limiter.kill(cmd.Process)
+		} else {
+			cmd.Process.Kill()
+		}
+		<-done
+		return AIExecutionResult{
+			Output:   captured(),
+			Duration: time.Since(startTime),
+			Error:    reason,
+		}
+	}
+
+	var timeoutChan <-chan time.Time
+	if opts.IterationTimeout != nil {
+		timer := time.NewTimer(time.Duration(*opts.IterationTimeout) * time.Second)
+		defer timer.Stop()
+		timeoutChan = timer.C
+	}
+
+	var idleTick <-chan time.Time
+	var idleLimit time.Duration
+	if opts.IdleTimeout != nil {
+		idleLimit = time.Duration(*opts.IdleTimeout) * time.Second
+		ticker := time.NewTicker(idleCheckInterval(idleLimit))
+		defer ticker.Stop()
+		idleTick = ticker.C
+	}
+
+	var waitErr error
+wait:
+	for {
 		select {
 		case waitErr = <-done:
+			break wait
+		case <-timeoutChan:
+			return killWith(ErrTimeout)
+		case <-idleTick:
+			if activity.idleFor() >= idleLimit {
+				return killWith(ErrIdleTimeout)
+			}
 		case <-sigChan:
 			// Signal received - kill process
-			cmd.Process.Kill()
-			<-done
-			return AIExecutionResult{
-				Output:   outputBuffer.String(),
-				Duration: time.Since(startTime),
-				Error:    ErrInterrupted,
-			}
+			return killWith(ErrInterrupted)
 		}
 	}
 
 	duration := time.Since(startTime)
-	output := outputBuffer.String()
+	output := captured()
 	truncated := false
 
-	if len(output) > maxBuffer {
+	if len(output) > opts.MaxOutputBuffer {
 		truncated = true
-		output = output[len(output)-maxBuffer:]
+		output = output[len(output)-opts.MaxOutputBuffer:]
+	}
+
+	if limiter != nil && limiter.exceeded(cmd.ProcessState) {
+		return AIExecutionResult{
+			Output:    output,
+			ExitCode:  cmd.ProcessState.ExitCode(),
+			Duration:  duration,
+			Truncated: truncated,
+			Error:     ErrResourceLimit,
+		}
 	}
 
 	exitCode := 0
@@ -134,6 +249,50 @@ func ExecuteAICLI(aiCmd config.AICommand, prompt string, verbose bool, aiExecuti
 	}
 }
 
+// drainPTY waits briefly for remaining terminal output after the process exits,
+// then closes the master so the copy goroutine finishes even if a leftover
+// child still holds the terminal open.
+func drainPTY(master *os.File, copied <-chan struct{}) {
+	select {
+	case <-copied:
+	case <-time.After(outputDrainDelay):
+	}
+	master.Close()
+	<-copied
+}
+
+// activityWriter forwards writes and records when output was last produced,
+// so the idle watchdog can tell a quiet agent from a working one.
+type activityWriter struct {
+	w        io.Writer
+	lastNano atomic.Int64
+}
+
+func (a *activityWriter) Write(p []byte) (int, error) {
+	a.touch()
+	return a.w.Write(p)
+}
+
+func (a *activityWriter) touch() {
+	a.lastNano.Store(time.Now().UnixNano())
+}
+
+func (a *activityWriter) idleFor() time.Duration {
+	return time.Since(time.Unix(0, a.lastNano.Load()))
+}
+
+// idleCheckInterval polls often enough to fire close to the limit without busy-waiting.
+func idleCheckInterval(limit time.Duration) time.Duration {
+	interval := limit / 10
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	if interval > time.Second {
+		interval = time.Second
+	}
+	return interval
+}
+
 func ScanOutputForSignals(output string) (hasSuccess bool, hasFailure bool) {
 	hasSuccess = strings.Contains(output, "<promise>SUCCESS</promise>")
 	hasFailure = strings.Contains(output, "<promise>FAILURE</promise>")
@@ -190,3 +190,38 @@ func TestExecuteAICLI_SignalInterrupt(t *testing.T) {
 		t.Errorf("expected duration >= 100ms, got: %v", result.Duration)
 	}
 }
+
+func TestExecuteAICLI_IdleTimeout(t *testing.T) {
+	idle := 1
+	aiCmd := config.AICommand{
+		Command: "sh -c 'echo started; sleep 10'",
+		Source:  "test",
+	}
+	result := ExecuteAICLIWithOptions(aiCmd, "", ExecOptions{IdleTimeout: &idle, MaxOutputBuffer: 1024}, nil)
+
+	if result.Error != ErrIdleTimeout {
+		t.Fatalf("expected ErrIdleTimeout, got: %v", result.Error)
+	}
+	if !strings.Contains(result.Output, "started") {
+		t.Errorf("expected output captured before idle kill, got: %s", result.Output)
+	}
+	if result.Duration < time.Second || result.Duration > 3*time.Second {
+		t.Errorf("expected duration ~1s, got: %v", result.Duration)
+	}
+}
+
+func TestExecuteAICLI_IdleTimeoutResetByOutput(t *testing.T) {
+	idle := 1
+	aiCmd := config.AICommand{
+		Command: "sh -c 'for i in 1 2 3 4; do echo tick; sleep 0.5; done'",
+		Source:  "test",
+	}
+	result := ExecuteAICLIWithOptions(aiCmd, "", ExecOptions{IdleTimeout: &idle, MaxOutputBuffer: 1024}, nil)
+
+	if result.Error != nil {
+		t.Fatalf("expected steady output to keep process alive, got: %v", result.Error)
+	}
+	if strings.Count(result.Output, "tick") != 4 {
+		t.Errorf("expected 4 ticks, got: %s", result.Output)
+	}
+}
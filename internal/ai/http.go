@@ -0,0 +1,158 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jomadu/rooda/internal/config"
+)
+
+// HTTPExecutor sends the prompt to an OpenAI-compatible chat completions
+// endpoint. The reply content becomes the iteration output. HTTP error
+// responses and connection failures are reported as exit code 1 with the
+// status or error in the output, so alias classifiers can match them like
+// CLI output.
+type HTTPExecutor struct {
+	Backend config.HTTPBackend
+	Client  *http.Client
+}
+
+// NewHTTPExecutor returns an HTTPExecutor using the default HTTP client.
+func NewHTTPExecutor(backend config.HTTPBackend) *HTTPExecutor {
+	return &HTTPExecutor{Backend: backend, Client: http.DefaultClient}
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// Execute sends one chat completion request. The iteration timeout bounds the
+// whole request. The idle timeout does not apply because nothing arrives until
+// the reply is complete.
+func (e *HTTPExecutor) Execute(prompt string, opts ExecOptions, sigChan <-chan os.Signal) AIExecutionResult {
+	startTime := time.Now()
+
+	body, err := json.Marshal(chatRequest{
+		Model:    e.Backend.Model,
+		Messages: []chatMessage{{Role: "user", Content: prompt}},
+	})
+	if err != nil {
+		return AIExecutionResult{Error: err, Duration: time.Since(startTime)}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(e.Backend.BaseURL, "/")+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return AIExecutionResult{Error: err, Duration: time.Since(startTime)}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.Backend.APIKeyEnv != "" {
+		if key := os.Getenv(e.Backend.APIKeyEnv); key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+	}
+
+	type response struct {
+		output   string
+		exitCode int
+	}
+	done := make(chan response, 1)
+	go func() {
+		output, exitCode := e.do(req)
+		done <- response{output, exitCode}
+	}()
+
+	var timeoutChan <-chan time.Time
+	if opts.IterationTimeout != nil {
+		timer := time.NewTimer(time.Duration(*opts.IterationTimeout) * time.Second)
+		defer timer.Stop()
+		timeoutChan = timer.C
+	}
+
+	cancelWith := func(reason error) AIExecutionResult {
+		cancel()
+		<-done
+		return AIExecutionResult{Duration: time.Since(startTime), Error: reason}
+	}
+
+	var resp response
+	select {
+	case resp = <-done:
+	case <-timeoutChan:
+		return cancelWith(ErrTimeout)
+	case <-sigChan:
+		return cancelWith(ErrInterrupted)
+	}
+
+	if opts.Verbose {
+		fmt.Fprintln(os.Stdout, resp.output)
+	}
+
+	output := resp.output
+	truncated := false
+	if len(output) > opts.MaxOutputBuffer {
+		truncated = true
+		output = output[len(output)-opts.MaxOutputBuffer:]
+	}
+
+	return AIExecutionResult{
+		Output:    output,
+		ExitCode:  resp.exitCode,
+		Duration:  time.Since(startTime),
+		Truncated: truncated,
+	}
+}
+
+// do performs the request and returns the output and exit code to report.
+func (e *HTTPExecutor) do(req *http.Request) (string, int) {
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return "", 1
+		}
+		return fmt.Sprintf("http backend: %v", err), 1
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Sprintf("http backend: read response: %v", err), 1
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Sprintf("HTTP %d %s\n%s", resp.StatusCode, http.StatusText(resp.StatusCode), data), 1
+	}
+
+	var chat chatResponse
+	if err := json.Unmarshal(data, &chat); err != nil {
+		return fmt.Sprintf("http backend: invalid chat completions response: %v", err), 1
+	}
+	if len(chat.Choices) == 0 {
+		return "http backend: response has no choices", 1
+	}
+	return chat.Choices[0].Message.Content, 0
+}
@@ -0,0 +1,119 @@
+package ai
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jomadu/rooda/internal/config"
+)
+
+func TestHTTPExecutor_Success(t *testing.T) {
+	t.Setenv("ROODA_TEST_API_KEY", "secret")
+
+	var got chatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("expected /v1/chat/completions, got %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer secret" {
+			t.Errorf("expected bearer token from api_key_env, got %q", auth)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"plan ready\n<promise>SUCCESS</promise>"}}]}`))
+	}))
+	defer server.Close()
+
+	executor := NewExecutor(config.AICommand{Options: config.AliasOptions{
+		Backend: config.BackendHTTP,
+		HTTP:    &config.HTTPBackend{BaseURL: server.URL + "/v1/", Model: "local-model", APIKeyEnv: "ROODA_TEST_API_KEY"},
+	}})
+	result := executor.Execute("write a plan", ExecOptions{MaxOutputBuffer: 1024}, nil)
+
+	if result.Error != nil {
+		t.Fatalf("expected no error, got: %v", result.Error)
+	}
+	if result.ExitCode != 0 {
+		t.Errorf("expected exit code 0, got: %d", result.ExitCode)
+	}
+	if !strings.Contains(result.Output, "<promise>SUCCESS</promise>") {
+		t.Errorf("expected reply content as output, got: %q", result.Output)
+	}
+	if got.Model != "local-model" || len(got.Messages) != 1 || got.Messages[0].Role != "user" || got.Messages[0].Content != "write a plan" {
+		t.Errorf("unexpected request: %+v", got)
+	}
+}
+
+func TestHTTPExecutor_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			t.Errorf("expected no Authorization header without api_key_env, got %q", auth)
+		}
+		http.Error(w, `{"error":"slow down"}`, http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	executor := NewHTTPExecutor(config.HTTPBackend{BaseURL: server.URL, Model: "m"})
+	result := executor.Execute("prompt", ExecOptions{MaxOutputBuffer: 1024}, nil)
+
+	if result.Error != nil {
+		t.Fatalf("expected no error, got: %v", result.Error)
+	}
+	if result.ExitCode != 1 {
+		t.Errorf("expected exit code 1, got: %d", result.ExitCode)
+	}
+	if !strings.Contains(result.Output, "HTTP 429") || !strings.Contains(result.Output, "slow down") {
+		t.Errorf("expected status and body in output, got: %q", result.Output)
+	}
+}
+
+func TestHTTPExecutor_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The server notices the client hanging up only once the body is consumed
+		io.ReadAll(r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	timeout := 1
+	executor := NewHTTPExecutor(config.HTTPBackend{BaseURL: server.URL, Model: "m"})
+	result := executor.Execute("prompt", ExecOptions{IterationTimeout: &timeout, MaxOutputBuffer: 1024}, nil)
+
+	if result.Error != ErrTimeout {
+		t.Errorf("expected ErrTimeout, got: %v", result.Error)
+	}
+}
+
+func TestHTTPExecutor_ConnectionRefused(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	executor := NewHTTPExecutor(config.HTTPBackend{BaseURL: url, Model: "m"})
+	result := executor.Execute("prompt", ExecOptions{MaxOutputBuffer: 1024}, nil)
+
+	if result.Error != nil {
+		t.Fatalf("expected connection failure as exit code, got error: %v", result.Error)
+	}
+	if result.ExitCode != 1 || !strings.Contains(result.Output, "http backend:") {
+		t.Errorf("expected exit code 1 with error output, got %d %q", result.ExitCode, result.Output)
+	}
+}
+
+func TestNewExecutor_Subprocess(t *testing.T) {
+	executor := NewExecutor(config.AICommand{Command: "echo hello"})
+	if _, ok := executor.(SubprocessExecutor); !ok {
+		t.Fatalf("expected SubprocessExecutor, got %T", executor)
+	}
+	result := executor.Execute("", ExecOptions{MaxOutputBuffer: 1024}, nil)
+	if !strings.Contains(result.Output, "hello") {
+		t.Errorf("expected subprocess output, got: %q", result.Output)
+	}
+}
@@ -0,0 +1,243 @@
+package ai
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/jomadu/rooda/internal/config"
+)
+
+// ResourceLimitsSupported reports whether resource_limits are enforced on this platform.
+const ResourceLimitsSupported = true
+
+// rlimitNPROC is RLIMIT_NPROC from asm-generic, which the syscall package
+// does not export. It matches amd64, arm64, 386 and arm.
+const rlimitNPROC = 6
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+var cgroupSeq atomic.Int64
+
+// processLimiter applies resource limits to one AI CLI invocation. The AI CLI
+// runs in its own process group so limits and kills reach everything it
+// starts. Memory and process limits use a dedicated cgroup v2 child group when
+// rooda may create one; otherwise every limit falls back to rlimits.
+type processLimiter struct {
+	limits    config.ResourceLimits
+	cgroupDir string
+	cgroupFD  *os.File
+}
+
+func newProcessLimiter(limits config.ResourceLimits) *processLimiter {
+	l := &processLimiter{limits: limits}
+	if limits.MemoryMB != nil || limits.MaxProcesses != nil {
+		l.cgroupDir, l.cgroupFD = createCgroup(limits)
+	}
+	return l
+}
+
+// prepare configures cmd to start in a new process group, inside the cgroup
+// when one was created. The child is traced so it stops right after exec,
+// before running any of the AI CLI's code, until start has set its rlimits.
+func (l *processLimiter) prepare(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	cmd.SysProcAttr.Ptrace = true
+	if l.cgroupFD != nil {
+		cmd.SysProcAttr.UseCgroupFD = true
+		cmd.SysProcAttr.CgroupFD = int(l.cgroupFD.Fd())
+	}
+}
+
+// start runs startCmd, sets the rlimits on the stopped child and lets it
+// continue. If the limits cannot be applied the child is killed and reaped.
+func (l *processLimiter) start(cmd *exec.Cmd, startCmd func() error) error {
+	// Only the thread that started a traced child may detach from it
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if err := startCmd(); err != nil {
+		return err
+	}
+	pid := cmd.Process.Pid
+
+	var status syscall.WaitStatus
+	if _, err := syscall.Wait4(pid, &status, 0, nil); err != nil || !status.Stopped() {
+		l.kill(cmd.Process)
+		cmd.Wait()
+		return fmt.Errorf("start AI CLI with resource limits: child did not stop after exec")
+	}
+	err := l.setRlimits(pid)
+	if detachErr := syscall.PtraceDetach(pid); err == nil {
+		err = detachErr
+	}
+	if err != nil {
+		l.kill(cmd.Process)
+		cmd.Wait()
+		return err
+	}
+	return nil
+}
+
+// setRlimits sets the rlimits not covered by the cgroup. Children inherit them.
+func (l *processLimiter) setRlimits(pid int) error {
+	type rlimit struct {
+		resource int
+		key      string
+		cur, max uint64
+	}
+	var rlimits []rlimit
+	if l.limits.MemoryMB != nil && l.cgroupDir == "" {
+		bytes := uint64(*l.limits.MemoryMB) << 20
+		rlimits = append(rlimits, rlimit{syscall.RLIMIT_DATA, "memory_mb", bytes, bytes})
+	}
+	if l.limits.CPUSeconds != nil {
+		// SIGXCPU at the soft limit, SIGKILL one second later
+		secs := uint64(*l.limits.CPUSeconds)
+		rlimits = append(rlimits, rlimit{syscall.RLIMIT_CPU, "cpu_seconds", secs, secs + 1})
+	}
+	if l.limits.MaxProcesses != nil && l.cgroupDir == "" {
+		n := uint64(*l.limits.MaxProcesses)
+		rlimits = append(rlimits, rlimit{rlimitNPROC, "max_processes", n, n})
+	}
+	if l.limits.MaxOpenFiles != nil {
+		n := uint64(*l.limits.MaxOpenFiles)
+		rlimits = append(rlimits, rlimit{syscall.RLIMIT_NOFILE, "max_open_files", n, n})
+	}
+
+	for _, r := range rlimits {
+		lim := syscall.Rlimit{Cur: r.cur, Max: r.max}
+		_, _, errno := syscall.RawSyscall6(syscall.SYS_PRLIMIT64, uintptr(pid), uintptr(r.resource), uintptr(unsafe.Pointer(&lim)), 0, 0, 0)
+		if errno != 0 {
+			return fmt.Errorf("set resource_limits.%s: %w", r.key, errno)
+		}
+	}
+	return nil
+}
+
+// kill terminates the AI CLI's whole process group and cgroup.
+func (l *processLimiter) kill(proc *os.Process) {
+	syscall.Kill(-proc.Pid, syscall.SIGKILL)
+	proc.Kill()
+	l.killCgroup()
+}
+
+// exceeded reports whether the process was stopped by one of the limits.
+func (l *processLimiter) exceeded(state *os.ProcessState) bool {
+	if l.cgroupDir != "" && cgroupEventCount(filepath.Join(l.cgroupDir, "memory.events"), "oom_kill") > 0 {
+		return true
+	}
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return false
+	}
+	switch status.Signal() {
+	case syscall.SIGXCPU:
+		return true
+	case syscall.SIGKILL:
+		// The kernel sends SIGKILL at the hard CPU limit, or the OOM killer does
+		if l.limits.CPUSeconds != nil && state.UserTime()+state.SystemTime() >= time.Duration(*l.limits.CPUSeconds)*time.Second {
+			return true
+		}
+	}
+	return false
+}
+
+// release kills processes left in the cgroup and removes it.
+func (l *processLimiter) release() {
+	if l.cgroupFD == nil {
+		return
+	}
+	l.killCgroup()
+	l.cgroupFD.Close()
+	// The kernel may take a moment to reap killed members before rmdir succeeds
+	for i := 0; i < 10; i++ {
+		if err := os.Remove(l.cgroupDir); err == nil || os.IsNotExist(err) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (l *processLimiter) killCgroup() {
+	if l.cgroupDir != "" {
+		os.WriteFile(filepath.Join(l.cgroupDir, "cgroup.kill"), []byte("1"), 0)
+	}
+}
+
+// createCgroup makes a child of rooda's own cgroup v2 group with memory.max
+// and pids.max set. It returns empty values when cgroup v2 is unavailable or
+// the controllers are not delegated, leaving enforcement to rlimits.
+func createCgroup(limits config.ResourceLimits) (string, *os.File) {
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", nil
+	}
+	var self string
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "0::") {
+			self = strings.TrimPrefix(line, "0::")
+			break
+		}
+	}
+	if self == "" {
+		return "", nil
+	}
+
+	dir := filepath.Join(cgroupRoot, self, fmt.Sprintf("rooda-%d-%d", os.Getpid(), cgroupSeq.Add(1)))
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return "", nil
+	}
+	settings := map[string]string{}
+	if limits.MemoryMB != nil {
+		settings["memory.max"] = strconv.Itoa(*limits.MemoryMB << 20)
+		settings["memory.swap.max"] = "0"
+	}
+	if limits.MaxProcesses != nil {
+		settings["pids.max"] = strconv.Itoa(*limits.MaxProcesses)
+	}
+	for file, value := range settings {
+		err := os.WriteFile(filepath.Join(dir, file), []byte(value), 0)
+		if err != nil && file != "memory.swap.max" {
+			os.Remove(dir)
+			return "", nil
+		}
+	}
+
+	fd, err := os.Open(dir)
+	if err != nil {
+		os.Remove(dir)
+		return "", nil
+	}
+	return dir, fd
+}
+
+// cgroupEventCount reads one counter from a cgroup *.events file.
+func cgroupEventCount(path, key string) int {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == key {
+			n, _ := strconv.Atoi(fields[1])
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,82 @@
+package ai
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jomadu/rooda/internal/config"
+)
+
+func TestExecuteAICLI_CPULimit(t *testing.T) {
+	cpu := 1
+	aiCmd := config.AICommand{Command: "sh -c 'while :; do :; done'", Source: "test"}
+	result := ExecuteAICLIWithOptions(aiCmd, "", ExecOptions{
+		MaxOutputBuffer: 1024,
+		ResourceLimits:  &config.ResourceLimits{CPUSeconds: &cpu},
+	}, nil)
+
+	if result.Error != ErrResourceLimit {
+		t.Fatalf("expected ErrResourceLimit, got: %v", result.Error)
+	}
+	if result.Duration > 5*time.Second {
+		t.Errorf("expected kill shortly after 1s of CPU, took %v", result.Duration)
+	}
+}
+
+func TestExecuteAICLI_OpenFilesLimit(t *testing.T) {
+	for _, tty := range []bool{false, true} {
+		files := 64
+		aiCmd := config.AICommand{
+			Command: "sh -c 'ulimit -n'",
+			Source:  "test",
+			Options: config.AliasOptions{TTY: tty},
+		}
+		result := ExecuteAICLIWithOptions(aiCmd, "", ExecOptions{
+			MaxOutputBuffer: 1024,
+			ResourceLimits:  &config.ResourceLimits{MaxOpenFiles: &files},
+		}, nil)
+
+		if result.Error != nil {
+			t.Fatalf("tty=%v: expected no error, got: %v", tty, result.Error)
+		}
+		if strings.TrimSpace(result.Output) != "64" {
+			t.Errorf("tty=%v: expected open files limit 64 from the first instruction, got: %q", tty, result.Output)
+		}
+	}
+}
+
+func TestExecuteAICLI_LimitsKillProcessGroup(t *testing.T) {
+	files := 256
+	timeout := 1
+	// The background sleep would otherwise outlive the killed shell
+	aiCmd := config.AICommand{Command: "sh -c 'sleep 30 & echo $!; wait'", Source: "test"}
+	result := ExecuteAICLIWithOptions(aiCmd, "", ExecOptions{
+		IterationTimeout: &timeout,
+		MaxOutputBuffer:  1024,
+		ResourceLimits:   &config.ResourceLimits{MaxOpenFiles: &files},
+	}, nil)
+
+	if result.Error != ErrTimeout {
+		t.Fatalf("expected ErrTimeout, got: %v", result.Error)
+	}
+	pid := strings.TrimSpace(result.Output)
+	time.Sleep(100 * time.Millisecond)
+	stat, err := os.ReadFile("/proc/" + pid + "/stat")
+	if err == nil && !strings.Contains(string(stat), ") Z ") {
+		syscall.Kill(atoi(t, pid), syscall.SIGKILL)
+		t.Errorf("expected background child %s killed with the process group", pid)
+	}
+}
+
+func atoi(t *testing.T, s string) int {
+	t.Helper()
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		t.Fatalf("expected a pid, got %q", s)
+	}
+	return n
+}
@@ -0,0 +1,29 @@
+//go:build !linux
+
+package ai
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/jomadu/rooda/internal/config"
+)
+
+// ResourceLimitsSupported reports whether resource_limits are enforced on this platform.
+const ResourceLimitsSupported = false
+
+// processLimiter is only implemented on Linux; newProcessLimiter returns nil
+// so limits are not applied.
+type processLimiter struct{}
+
+func newProcessLimiter(config.ResourceLimits) *processLimiter { return nil }
+
+func (l *processLimiter) prepare(*exec.Cmd) {}
+
+func (l *processLimiter) start(_ *exec.Cmd, startCmd func() error) error { return startCmd() }
+
+func (l *processLimiter) kill(proc *os.Process) { proc.Kill() }
+
+func (l *processLimiter) exceeded(*os.ProcessState) bool { return false }
+
+func (l *processLimiter) release() {}
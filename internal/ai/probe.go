@@ -0,0 +1,96 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ProbeResult reports how an AI command handled a probe prompt.
+type ProbeResult struct {
+	Latency     time.Duration
+	OutputBytes int
+	ExitCode    int
+	Signal      string   // "SUCCESS", "FAILURE" or "" when none was emitted
+	Violations  []string // Problems found; empty when the command followed the protocol
+}
+
+// OK reports whether the probe found no violations.
+func (r ProbeResult) OK() bool {
+	return len(r.Violations) == 0
+}
+
+// promiseTag also matches near misses such as <PROMISE>SUCCESS</PROMISE> so they can be reported.
+var promiseTag = regexp.MustCompile(`(?i)<promise>(.*?)</promise>`)
+
+// ProbeInstructions asks the agent to answer with the success signal and the
+// token, without doing any work. The token shows that the prompt was read.
+func ProbeInstructions(token string) string {
+	return fmt.Sprintf("This is a connectivity probe from rooda. Do not read or modify any files and do not run any commands.\n"+
+		"Reply with exactly this line and nothing else:\n\n<promise>SUCCESS</promise> %s", token)
+}
+
+// Probe sends prompt to the executor and checks that the command started,
+// read the prompt from stdin (by echoing token), finished within the
+// timeout, exited zero and emitted exactly one SUCCESS signal.
+func Probe(executor Executor, prompt string, token string, opts ExecOptions, sigChan <-chan os.Signal) ProbeResult {
+	result := executor.Execute(prompt, opts, sigChan)
+
+	probe := ProbeResult{
+		Latency:     result.Duration,
+		OutputBytes: len(result.Output),
+		ExitCode:    result.ExitCode,
+	}
+	violate := func(format string, args ...interface{}) {
+		probe.Violations = append(probe.Violations, fmt.Sprintf(format, args...))
+	}
+
+	switch {
+	case errors.Is(result.Error, ErrTimeout):
+		violate("did not finish within the timeout")
+		return probe
+	case errors.Is(result.Error, ErrInterrupted):
+		violate("interrupted before finishing")
+		return probe
+	case result.Error != nil:
+		violate("failed to run: %v", result.Error)
+		return probe
+	case result.ExitCode != 0:
+		violate("exited with code %d", result.ExitCode)
+	}
+
+	if strings.TrimSpace(result.Output) == "" {
+		violate("produced no output")
+		return probe
+	}
+
+	tags := promiseTag.FindAllStringSubmatch(result.Output, -1)
+	for _, tag := range tags {
+		switch tag[0] {
+		case "<promise>SUCCESS</promise>", "<promise>FAILURE</promise>":
+			if probe.Signal == "" {
+				probe.Signal = tag[1]
+			}
+		default:
+			violate("malformed signal %q (expected <promise>SUCCESS</promise> or <promise>FAILURE</promise>)", tag[0])
+		}
+	}
+	hasSuccess, hasFailure := ScanOutputForSignals(result.Output)
+	switch {
+	case !hasSuccess && !hasFailure:
+		violate("no <promise> signal in output")
+	case hasSuccess && hasFailure:
+		violate("emitted both SUCCESS and FAILURE signals")
+	case hasFailure:
+		violate("emitted FAILURE instead of SUCCESS")
+	}
+
+	if !strings.Contains(result.Output, token) {
+		violate("did not echo the probe token; the command may not read the prompt from stdin")
+	}
+
+	return probe
+}
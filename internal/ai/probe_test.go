@@ -0,0 +1,97 @@
+package ai
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jomadu/rooda/internal/config"
+)
+
+func TestProbe(t *testing.T) {
+	const token = "rooda-probe-1234"
+	tests := []struct {
+		name           string
+		result         AIExecutionResult
+		wantSignal     string
+		wantViolations []string
+	}{
+		{
+			name:       "compliant",
+			result:     AIExecutionResult{Output: "<promise>SUCCESS</promise> rooda-probe-1234\n", Duration: 2 * time.Second},
+			wantSignal: "SUCCESS",
+		},
+		{
+			name:   "timeout",
+			result: AIExecutionResult{Output: "thinking", Error: ErrTimeout},
+			wantViolations: []string{
+				"did not finish within the timeout",
+			},
+		},
+		{
+			name:   "failed to start",
+			result: AIExecutionResult{Error: errors.New(`exec: "nope": executable file not found in $PATH`)},
+			wantViolations: []string{
+				`failed to run: exec: "nope": executable file not found in $PATH`,
+			},
+		},
+		{
+			name:   "no output",
+			result: AIExecutionResult{ExitCode: 1},
+			wantViolations: []string{
+				"exited with code 1",
+				"produced no output",
+			},
+		},
+		{
+			name:   "ignores protocol and prompt",
+			result: AIExecutionResult{Output: "Hello! How can I help?"},
+			wantViolations: []string{
+				"no <promise> signal in output",
+				"did not echo the probe token; the command may not read the prompt from stdin",
+			},
+		},
+		{
+			name:       "malformed and conflicting signals",
+			result:     AIExecutionResult{Output: "<promise>DONE</promise> <PROMISE>SUCCESS</PROMISE> <promise>FAILURE</promise> <promise>SUCCESS</promise> rooda-probe-1234"},
+			wantSignal: "FAILURE",
+			wantViolations: []string{
+				`malformed signal "<promise>DONE</promise>" (expected <promise>SUCCESS</promise> or <promise>FAILURE</promise>)`,
+				`malformed signal "<PROMISE>SUCCESS</PROMISE>" (expected <promise>SUCCESS</promise> or <promise>FAILURE</promise>)`,
+				"emitted both SUCCESS and FAILURE signals",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent := &ScriptedExecutor{Responses: []AIExecutionResult{tt.result}}
+			probe := Probe(agent, "probe prompt", token, ExecOptions{}, nil)
+			if probe.Signal != tt.wantSignal {
+				t.Errorf("expected signal %q, got %q", tt.wantSignal, probe.Signal)
+			}
+			if strings.Join(probe.Violations, "\n") != strings.Join(tt.wantViolations, "\n") {
+				t.Errorf("unexpected violations:\n%s", strings.Join(probe.Violations, "\n"))
+			}
+			if probe.OK() != (len(tt.wantViolations) == 0) {
+				t.Errorf("OK() = %v with violations %v", probe.OK(), probe.Violations)
+			}
+			if probe.OutputBytes != len(tt.result.Output) || probe.Latency != tt.result.Duration {
+				t.Errorf("unexpected measurements: %+v", probe)
+			}
+		})
+	}
+}
+
+func TestProbe_ReadsPromptFromStdin(t *testing.T) {
+	token := "rooda-probe-abcd"
+	aiCmd := config.AICommand{Command: "cat"}
+	timeout := 10
+	probe := Probe(NewExecutor(aiCmd), ProbeInstructions(token), token, ExecOptions{
+		IterationTimeout: &timeout,
+		MaxOutputBuffer:  config.DefaultMaxOutputBuffer,
+	}, nil)
+	if !probe.OK() || probe.Signal != "SUCCESS" {
+		t.Errorf("expected cat to echo a compliant reply, got: %+v", probe)
+	}
+}
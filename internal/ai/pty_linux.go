@@ -0,0 +1,92 @@
+package ai
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"unsafe"
+)
+
+// startWithPTY starts cmd with stdout and stderr attached to a new
+// pseudo-terminal, making it the controlling terminal of a new session.
+// Stdin is left as configured so the prompt is still delivered through a pipe
+// and is not echoed back into the captured output. The caller reads the
+// returned master until it reports an error (EIO once the child side closes).
+// startCmd starts cmd once it is configured, normally cmd.Start.
+func startWithPTY(cmd *exec.Cmd, startCmd func() error) (*os.File, error) {
+	master, slave, err := openPTY()
+	if err != nil {
+		return nil, err
+	}
+	defer slave.Close()
+
+	cmd.Stdout = slave
+	cmd.Stderr = slave
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setsid = true
+	cmd.SysProcAttr.Setctty = true
+	cmd.SysProcAttr.Ctty = 1 // stdout in the child
+	// A new session is also a new process group; setpgid would fail after setsid
+	cmd.SysProcAttr.Setpgid = false
+
+	if err := startCmd(); err != nil {
+		master.Close()
+		return nil, err
+	}
+	return master, nil
+}
+
+// openPTY allocates a pseudo-terminal pair via /dev/ptmx.
+func openPTY() (master *os.File, slave *os.File, err error) {
+	master, err = os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open pty: %w", err)
+	}
+
+	var unlock int32
+	if err := ioctl(master.Fd(), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("unlock pty: %w", err)
+	}
+
+	var n uint32
+	if err := ioctl(master.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n))); err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("get pty number: %w", err)
+	}
+
+	slave, err = os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("open pty slave: %w", err)
+	}
+
+	setWindowSize(slave)
+	return master, slave, nil
+}
+
+// winsize mirrors struct winsize from <sys/ioctl.h>.
+type winsize struct {
+	Rows, Cols, X, Y uint16
+}
+
+// setWindowSize copies the size of rooda's own terminal when there is one,
+// otherwise uses a conventional 80x24, so CLIs that wrap output behave sanely.
+func setWindowSize(slave *os.File) {
+	ws := winsize{Rows: 24, Cols: 80}
+	var own winsize
+	if ioctl(os.Stdout.Fd(), syscall.TIOCGWINSZ, uintptr(unsafe.Pointer(&own))) == nil && own.Cols > 0 {
+		ws = own
+	}
+	ioctl(slave.Fd(), syscall.TIOCSWINSZ, uintptr(unsafe.Pointer(&ws)))
+}
+
+func ioctl(fd uintptr, req uint, arg uintptr) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, uintptr(req), arg); errno != 0 {
+		return errno
+	}
+	return nil
+}
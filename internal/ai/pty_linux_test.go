@@ -0,0 +1,49 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jomadu/rooda/internal/config"
+)
+
+func TestExecuteAICLI_TTY(t *testing.T) {
+	aiCmd := config.AICommand{
+		Command: `sh -c '[ -t 1 ] && echo is-tty || echo not-tty; printf "\033[31mred\033[0m\n"'`,
+		Source:  "test",
+		Options: config.AliasOptions{TTY: true},
+	}
+	result := ExecuteAICLIWithOptions(aiCmd, "", ExecOptions{MaxOutputBuffer: 1024}, nil)
+
+	if result.Error != nil {
+		t.Fatalf("expected no error, got: %v", result.Error)
+	}
+	if !strings.Contains(result.Output, "is-tty") {
+		t.Errorf("expected stdout to be a terminal, got: %q", result.Output)
+	}
+	if !strings.Contains(result.Output, "red\n") {
+		t.Errorf("expected colored text with escapes stripped, got: %q", result.Output)
+	}
+	if strings.Contains(result.Output, "\x1b") || strings.Contains(result.Output, "\r") {
+		t.Errorf("expected ANSI escapes and carriage returns stripped, got: %q", result.Output)
+	}
+}
+
+func TestExecuteAICLI_TTYPromptOnStdin(t *testing.T) {
+	aiCmd := config.AICommand{
+		Command: "sh -c 'cat; exit 3'",
+		Source:  "test",
+		Options: config.AliasOptions{TTY: true},
+	}
+	result := ExecuteAICLIWithOptions(aiCmd, "prompt text", ExecOptions{MaxOutputBuffer: 1024}, nil)
+
+	if result.Error != nil {
+		t.Fatalf("expected no error, got: %v", result.Error)
+	}
+	if result.ExitCode != 3 {
+		t.Errorf("expected exit code 3, got: %d", result.ExitCode)
+	}
+	if strings.Count(result.Output, "prompt text") != 1 {
+		t.Errorf("expected prompt echoed once by cat, got: %q", result.Output)
+	}
+}
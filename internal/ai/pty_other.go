@@ -0,0 +1,14 @@
+//go:build !linux
+
+package ai
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// startWithPTY is only implemented on Linux.
+func startWithPTY(cmd *exec.Cmd, startCmd func() error) (*os.File, error) {
+	return nil, errors.New("tty: true is only supported on Linux")
+}
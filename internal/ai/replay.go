@@ -0,0 +1,186 @@
+package ai
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultRunsDir is the workspace-local directory where recorded runs are saved.
+const DefaultRunsDir = ".rooda/runs"
+
+const (
+	runFile        = "run.json"
+	iterationsFile = "iterations.jsonl"
+)
+
+// RunInfo describes a recorded run.
+type RunInfo struct {
+	Procedure string    `json:"procedure"`
+	AICmd     string    `json:"ai_cmd"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// RecordedInvocation is one AI invocation saved by a Recorder.
+type RecordedInvocation struct {
+	PromptSHA256 string `json:"prompt_sha256"`
+	Output       string `json:"output"`
+	ExitCode     int    `json:"exit_code"`
+	DurationMS   int64  `json:"duration_ms"`
+	Error        string `json:"error,omitempty"` // Execution error, e.g. a timeout kill
+}
+
+// recordedErrors maps errors the loop distinguishes to stable names in recordings.
+var recordedErrors = map[string]error{
+	"timeout":        ErrTimeout,
+	"idle-timeout":   ErrIdleTimeout,
+	"resource-limit": ErrResourceLimit,
+}
+
+// PromptHash returns the hex SHA-256 of a prompt.
+func PromptHash(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+// Recorder appends each AI invocation of a run to <dir>/iterations.jsonl.
+type Recorder struct {
+	Dir  string
+	file *os.File
+}
+
+// NewRecorder creates dir and writes its run.json.
+func NewRecorder(dir string, info RunInfo) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, runFile), append(data, '\n'), 0644); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(filepath.Join(dir, iterationsFile), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{Dir: dir, file: file}, nil
+}
+
+// Record appends one invocation. Interrupted invocations are not recorded
+// because they say nothing about how the AI responded.
+func (r *Recorder) Record(prompt string, result AIExecutionResult) error {
+	if result.Error == ErrInterrupted {
+		return nil
+	}
+	entry := RecordedInvocation{
+		PromptSHA256: PromptHash(prompt),
+		Output:       result.Output,
+		ExitCode:     result.ExitCode,
+		DurationMS:   result.Duration.Milliseconds(),
+	}
+	if result.Error != nil {
+		entry.Error = result.Error.Error()
+		for name, err := range recordedErrors {
+			if result.Error == err {
+				entry.Error = name
+			}
+		}
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = r.file.Write(append(data, '\n'))
+	return err
+}
+
+// Close closes the iterations file.
+func (r *Recorder) Close() error {
+	return r.file.Close()
+}
+
+// ReplayExecutor returns recorded invocations in order instead of calling an AI.
+type ReplayExecutor struct {
+	Info    RunInfo
+	entries []RecordedInvocation
+	next    int
+
+	// OnPromptMismatch is called when a prompt differs from the recorded one.
+	// The recorded output is still returned.
+	OnPromptMismatch func(invocation int)
+}
+
+// LoadReplay reads a run directory written by a Recorder.
+func LoadReplay(dir string) (*ReplayExecutor, error) {
+	replay := &ReplayExecutor{}
+
+	data, err := os.ReadFile(filepath.Join(dir, runFile))
+	if err != nil {
+		return nil, fmt.Errorf("not a recorded run: %w", err)
+	}
+	if err := json.Unmarshal(data, &replay.Info); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", filepath.Join(dir, runFile), err)
+	}
+
+	file, err := os.Open(filepath.Join(dir, iterationsFile))
+	if err != nil {
+		return nil, fmt.Errorf("not a recorded run: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var entry RecordedInvocation
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid %s line %d: %w", filepath.Join(dir, iterationsFile), line, err)
+		}
+		replay.entries = append(replay.entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return replay, nil
+}
+
+// Remaining returns the number of recorded invocations not yet replayed.
+func (r *ReplayExecutor) Remaining() int {
+	return len(r.entries) - r.next
+}
+
+func (r *ReplayExecutor) Execute(prompt string, opts ExecOptions, sigChan <-chan os.Signal) AIExecutionResult {
+	if r.next >= len(r.entries) {
+		return AIExecutionResult{Error: fmt.Errorf("replay: recording has only %d invocations", len(r.entries))}
+	}
+	entry := r.entries[r.next]
+	r.next++
+
+	if entry.PromptSHA256 != PromptHash(prompt) && r.OnPromptMismatch != nil {
+		r.OnPromptMismatch(r.next)
+	}
+
+	result := AIExecutionResult{
+		Output:   entry.Output,
+		ExitCode: entry.ExitCode,
+		Duration: time.Duration(entry.DurationMS) * time.Millisecond,
+	}
+	if entry.Error != "" {
+		if err, ok := recordedErrors[entry.Error]; ok {
+			result.Error = err
+		} else {
+			result.Error = errors.New(entry.Error)
+		}
+	}
+	if opts.Verbose {
+		fmt.Fprint(os.Stdout, result.Output)
+	}
+	return result
+}
@@ -0,0 +1,98 @@
+package ai
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "run")
+	recorder, err := NewRecorder(dir, RunInfo{Procedure: "build", AICmd: "test", StartedAt: time.Now()})
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+	invocations := []struct {
+		prompt string
+		result AIExecutionResult
+	}{
+		{"first prompt", AIExecutionResult{Output: "working\n", ExitCode: 0, Duration: 1500 * time.Millisecond}},
+		{"second prompt", AIExecutionResult{Output: "stuck", Duration: time.Second, Error: ErrIdleTimeout}},
+		{"third prompt", AIExecutionResult{Error: ErrInterrupted}},
+		{"fourth prompt", AIExecutionResult{Output: "<promise>FAILURE</promise>", ExitCode: 2}},
+	}
+	for _, inv := range invocations {
+		if err := recorder.Record(inv.prompt, inv.result); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+	recorder.Close()
+
+	replay, err := LoadReplay(dir)
+	if err != nil {
+		t.Fatalf("LoadReplay failed: %v", err)
+	}
+	if replay.Info.Procedure != "build" {
+		t.Errorf("expected run info procedure build, got %q", replay.Info.Procedure)
+	}
+	if replay.Remaining() != 3 {
+		t.Fatalf("expected interrupted invocation skipped, got %d recorded", replay.Remaining())
+	}
+
+	var mismatches []int
+	replay.OnPromptMismatch = func(invocation int) { mismatches = append(mismatches, invocation) }
+
+	first := replay.Execute("first prompt", ExecOptions{}, nil)
+	if first.Output != "working\n" || first.ExitCode != 0 || first.Duration != 1500*time.Millisecond || first.Error != nil {
+		t.Errorf("unexpected first replay: %+v", first)
+	}
+	second := replay.Execute("second prompt", ExecOptions{}, nil)
+	if second.Error != ErrIdleTimeout || second.Output != "stuck" {
+		t.Errorf("expected idle timeout restored, got %+v", second)
+	}
+	third := replay.Execute("changed prompt", ExecOptions{}, nil)
+	if third.ExitCode != 2 || third.Output != "<promise>FAILURE</promise>" {
+		t.Errorf("expected recorded output despite prompt change, got %+v", third)
+	}
+	if len(mismatches) != 1 || mismatches[0] != 3 {
+		t.Errorf("expected mismatch reported for invocation 3, got %v", mismatches)
+	}
+
+	if result := replay.Execute("fifth prompt", ExecOptions{}, nil); result.Error == nil {
+		t.Error("expected error once the recording is exhausted")
+	}
+}
+
+func TestRecordUnknownError(t *testing.T) {
+	dir := t.TempDir()
+	recorder, err := NewRecorder(dir, RunInfo{Procedure: "build"})
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+	recorder.Record("prompt", AIExecutionResult{Error: errors.New("exec: \"missing-ai\": executable file not found")})
+	recorder.Close()
+
+	replay, err := LoadReplay(dir)
+	if err != nil {
+		t.Fatalf("LoadReplay failed: %v", err)
+	}
+	result := replay.Execute("prompt", ExecOptions{}, nil)
+	if result.Error == nil || result.Error.Error() != "exec: \"missing-ai\": executable file not found" {
+		t.Errorf("expected recorded error message, got %v", result.Error)
+	}
+}
+
+func TestLoadReplay_NotARun(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LoadReplay(dir); err == nil {
+		t.Error("expected error for a directory without run.json")
+	}
+
+	os.WriteFile(filepath.Join(dir, "run.json"), []byte(`{"procedure":"build"}`), 0644)
+	os.WriteFile(filepath.Join(dir, "iterations.jsonl"), []byte("not json\n"), 0644)
+	if _, err := LoadReplay(dir); err == nil {
+		t.Error("expected error for a malformed iterations file")
+	}
+}
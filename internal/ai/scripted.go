@@ -0,0 +1,22 @@
+package ai
+
+import (
+	"fmt"
+	"os"
+)
+
+// ScriptedExecutor returns canned responses in order, standing in for an agent
+// in procedure tests. The prompts it receives are kept for assertions.
+type ScriptedExecutor struct {
+	Responses []AIExecutionResult
+	Prompts   []string
+}
+
+func (s *ScriptedExecutor) Execute(prompt string, opts ExecOptions, sigChan <-chan os.Signal) AIExecutionResult {
+	s.Prompts = append(s.Prompts, prompt)
+	n := len(s.Prompts)
+	if n > len(s.Responses) {
+		return AIExecutionResult{Error: fmt.Errorf("scripted agent has only %d responses, invocation %d requested", len(s.Responses), n)}
+	}
+	return s.Responses[n-1]
+}
@@ -0,0 +1,23 @@
+package ai
+
+import "testing"
+
+func TestScriptedExecutor(t *testing.T) {
+	s := &ScriptedExecutor{Responses: []AIExecutionResult{
+		{Output: "working"},
+		{Output: "<promise>SUCCESS</promise>", ExitCode: 0},
+	}}
+
+	if r := s.Execute("first", ExecOptions{}, nil); r.Output != "working" {
+		t.Errorf("expected first response, got %+v", r)
+	}
+	if r := s.Execute("second", ExecOptions{}, nil); r.Output != "<promise>SUCCESS</promise>" {
+		t.Errorf("expected second response, got %+v", r)
+	}
+	if r := s.Execute("third", ExecOptions{}, nil); r.Error == nil {
+		t.Error("expected error once responses run out")
+	}
+	if len(s.Prompts) != 3 || s.Prompts[0] != "first" || s.Prompts[2] != "third" {
+		t.Errorf("expected prompts kept in order, got %v", s.Prompts)
+	}
+}
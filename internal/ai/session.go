@@ -0,0 +1,37 @@
+package ai
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/jomadu/rooda/internal/config"
+	"github.com/kballard/go-shellquote"
+)
+
+// CaptureSessionID returns the session ID in output, or "" when the adapter's
+// pattern does not match. The last match wins so a CLI that reports its session
+// at start and at exit yields the final one. The ID is the pattern's capture
+// group when it has one, otherwise the whole match.
+func CaptureSessionID(session *config.SessionAdapter, output string) string {
+	if session == nil || session.IDPattern == "" {
+		return ""
+	}
+	re, err := regexp.Compile(session.IDPattern)
+	if err != nil {
+		return ""
+	}
+	matches := re.FindAllStringSubmatch(output, -1)
+	if len(matches) == 0 {
+		return ""
+	}
+	last := matches[len(matches)-1]
+	return strings.TrimSpace(last[len(last)-1])
+}
+
+// ResumeCommand returns aiCmd with its command replaced by the alias's resume
+// command for sessionID. The ID is shell-quoted before substitution.
+func ResumeCommand(aiCmd config.AICommand, sessionID string) config.AICommand {
+	resumed := aiCmd
+	resumed.Command = strings.ReplaceAll(aiCmd.Options.Session.ResumeCommand, config.SessionPlaceholder, shellquote.Join(sessionID))
+	return resumed
+}
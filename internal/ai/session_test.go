@@ -0,0 +1,54 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/jomadu/rooda/internal/config"
+)
+
+func TestCaptureSessionID(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		output  string
+		want    string
+	}{
+		{"capture group", `session: (\S+)`, "starting\nsession: abc-123\ndone", "abc-123"},
+		{"whole match", `sess_[0-9a-f]+`, "id sess_beef42 ready", "sess_beef42"},
+		{"last match wins", `session: (\S+)`, "session: first\nsession: second", "second"},
+		{"no match", `session: (\S+)`, "nothing here", ""},
+		{"no pattern", "", "session: abc", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CaptureSessionID(&config.SessionAdapter{IDPattern: tt.pattern}, tt.output)
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	if got := CaptureSessionID(nil, "session: abc"); got != "" {
+		t.Errorf("expected no ID without an adapter, got %q", got)
+	}
+}
+
+func TestResumeCommand(t *testing.T) {
+	aiCmd := config.AICommand{
+		Command: "claude -p",
+		Source:  "loop.ai_cmd_alias=claude",
+		Options: config.AliasOptions{Session: &config.SessionAdapter{
+			IDPattern:     `"session_id":"([^"]+)"`,
+			ResumeCommand: "claude -p --resume {session_id}",
+		}},
+	}
+
+	resumed := ResumeCommand(aiCmd, "abc 123")
+	if resumed.Command != "claude -p --resume 'abc 123'" {
+		t.Errorf("expected quoted session ID in resume command, got %q", resumed.Command)
+	}
+	if resumed.Source != aiCmd.Source || aiCmd.Command != "claude -p" {
+		t.Errorf("expected source kept and original command untouched, got %+v / %+v", resumed, aiCmd)
+	}
+}
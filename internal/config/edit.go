@@ -0,0 +1,574 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GetValue returns the merged value of the setting at path, e.g.
+// procedures.build.iteration_timeout: a single value as its text, and a
+// mapping or list as YAML.
+func GetValue(config *Config, path string) (string, error) {
+	keys, err := settingKeys(path)
+	if err != nil {
+		return "", err
+	}
+
+	var doc yaml.Node
+	if err := doc.Encode(newConfigView(config)); err != nil {
+		return "", err
+	}
+	n := &doc
+	for _, key := range keys {
+		if n = mappingValue(n, key); n == nil {
+			return "", fmt.Errorf("%s is not set", path)
+		}
+	}
+	if n.Kind == yaml.ScalarNode {
+		return n.Value, nil
+	}
+	return encodeYAML(n, 2)
+}
+
+// SetValue sets the setting at path in the config file to value, creating the
+// file and any missing parent keys. value is parsed as a YAML scalar or flow
+// collection, so 900 is a number and [a, b] a list; anything else is a
+// string. Only the lines of the changed setting are rewritten, so comments,
+// formatting and ${...} templates elsewhere in the file are kept. The file is
+// replaced only if the configuration loaded with flags is still valid.
+func SetValue(file string, path string, value string, flags CLIFlags) error {
+	keys, err := settingKeys(path)
+	if err != nil {
+		return err
+	}
+	return editFile(file, path, flags, func(s *source) ([]textEdit, error) {
+		n := s.root
+		t := reflect.TypeOf(configFile{})
+		for i, key := range keys {
+			t = fieldType(t, key)
+			next := mappingValue(n, key)
+			if next == nil {
+				next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+				n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, next)
+			}
+			n = next
+			if i == len(keys)-1 {
+				break
+			}
+			if err := asMapping(n, t, strings.Join(keys[:i+1], "."), file); err != nil {
+				return nil, err
+			}
+		}
+
+		// Replace the value in place, keeping its comments
+		v := valueNode(value)
+		n.Kind, n.Tag, n.Value, n.Style, n.Content = v.Kind, v.Tag, v.Value, v.Style, v.Content
+		return []textEdit{s.rewrite(keys)}, nil
+	})
+}
+
+// UnsetValue removes the setting at path from the config file, along with
+// any parent keys it leaves empty. The file is replaced only if the
+// configuration loaded with flags is still valid.
+func UnsetValue(file string, path string, flags CLIFlags) error {
+	keys, err := settingKeys(path)
+	if err != nil {
+		return err
+	}
+	return editFile(file, path, flags, func(s *source) ([]textEdit, error) {
+		parents := []*yaml.Node{s.root}
+		for _, key := range keys[:len(keys)-1] {
+			n := mappingValue(parents[len(parents)-1], key)
+			if n == nil || n.Kind != yaml.MappingNode {
+				return nil, fmt.Errorf("%s is not set in %s", path, file)
+			}
+			parents = append(parents, n)
+		}
+		if mappingValue(parents[len(parents)-1], keys[len(keys)-1]) == nil {
+			return nil, fmt.Errorf("%s is not set in %s", path, file)
+		}
+
+		// Remove the outermost key the removal leaves empty
+		top := len(keys) - 1
+		for top > 0 && len(parents[top].Content) == 2 {
+			top--
+		}
+		edit := s.remove(keys[:top+1])
+		removeKey(parents[top], keys[top])
+		return []textEdit{edit}, nil
+	})
+}
+
+// editFile applies edit to the config file and replaces the file with the
+// result, once the configuration loaded with flags is known to be valid with
+// it and the edit added no unknown keys under path. The file is left as it
+// was on any error.
+func editFile(file string, path string, flags CLIFlags, edit func(s *source) ([]textEdit, error)) error {
+	original, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	s, err := parseSource(file, original)
+	if err != nil {
+		return err
+	}
+	edits, err := edit(s)
+	if err != nil {
+		return err
+	}
+	data, err := s.apply(edits)
+	if err != nil {
+		return err
+	}
+
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	flags.pending = map[string][]byte{abs: data}
+	config, err := LoadConfig(flags)
+	if err != nil {
+		return err
+	}
+	var unknown ValidationErrors
+	for _, w := range config.Warnings {
+		if w.File == file && (w.Path == path || strings.HasPrefix(w.Path, path+".")) {
+			unknown = append(unknown, w)
+		}
+	}
+	if len(unknown) > 0 {
+		return unknown
+	}
+	return writeFileAtomic(file, data)
+}
+
+// writeFileAtomic replaces the file at path with data by renaming a temporary
+// file over it, so it is never left half-written. An existing file keeps its
+// permissions, and a symlink is followed rather than replaced.
+func writeFileAtomic(path string, data []byte) error {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // Fails harmlessly once renamed
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// settingKeys splits a setting path into config file keys, checking each
+// against the config file structure.
+func settingKeys(path string) ([]string, error) {
+	keys := strings.Split(path, ".")
+	t := reflect.TypeOf(configFile{})
+	for i, key := range keys {
+		if key == "" {
+			return nil, fmt.Errorf("invalid setting path %q", path)
+		}
+		parent := strings.Join(keys[:i], ".")
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		switch {
+		case t.Kind() == reflect.Map && t.Elem().Kind() == reflect.Interface:
+			return keys, nil // free-form, e.g. fragment parameters
+		case t.Kind() == reflect.Map:
+			t = t.Elem()
+		case t.Kind() == reflect.Struct && !envScalar(t):
+			fields := yamlFields(t)
+			known := make([]string, len(fields))
+			for j, f := range fields {
+				known[j] = f.key
+			}
+			next := fieldType(t, key)
+			if next == nil {
+				return nil, fmt.Errorf("%s", unknownKeyError("", &yaml.Node{Value: key}, path, parent, known).Message)
+			}
+			t = next
+		default:
+			return nil, fmt.Errorf("%s is a single value, it has no key %q", parent, key)
+		}
+	}
+	return keys, nil
+}
+
+// fieldType returns the type key decodes into within t: the element type of
+// a map, or the type of a struct's field. It is nil when t has no such key.
+func fieldType(t reflect.Type, key string) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == nil:
+		return nil
+	case t.Kind() == reflect.Map:
+		return t.Elem()
+	case t.Kind() == reflect.Struct:
+		for _, f := range yamlFields(t) {
+			if f.key == key {
+				return f.typ
+			}
+		}
+	}
+	return nil
+}
+
+// asMapping makes n, the value at path, a mapping that keys can be added to.
+// An empty value becomes an empty mapping and an alias's command string
+// becomes its mapping form.
+func asMapping(n *yaml.Node, t reflect.Type, path string, file string) error {
+	switch {
+	case n.Kind == yaml.MappingNode:
+		return nil
+	case n.Kind == yaml.ScalarNode && n.Tag == "!!null":
+		n.Kind, n.Tag, n.Value, n.Style = yaml.MappingNode, "!!map", "", 0
+		return nil
+	case n.Kind == yaml.ScalarNode && t == reflect.TypeOf(aliasYAML{}):
+		command := &yaml.Node{Kind: yaml.ScalarNode, Tag: n.Tag, Value: n.Value, Style: n.Style}
+		n.Kind, n.Tag, n.Value, n.Style = yaml.MappingNode, "!!map", "", 0
+		n.Content = []*yaml.Node{{Kind: yaml.ScalarNode, Tag: "!!str", Value: "command"}, command}
+		return nil
+	}
+	return fmt.Errorf("%s in %s is not a mapping, set it as a whole instead", path, file)
+}
+
+// valueNode parses a value given on the command line. Quoted scalars and
+// flow collections are taken as written; text that YAML would read as
+// something else, e.g. "a: b" or "run # now", stays a plain string.
+func valueNode(value string) *yaml.Node {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &doc); err == nil && len(doc.Content) == 1 {
+		n := doc.Content[0]
+		quoted := n.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle) != 0
+		switch {
+		case n.Kind == yaml.ScalarNode && (n.Value == value || quoted):
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: n.Tag, Value: n.Value, Style: n.Style}
+		case (n.Kind == yaml.MappingNode || n.Kind == yaml.SequenceNode) && n.Style&yaml.FlowStyle != 0:
+			return n
+		}
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// mappingValue returns the value of key in mapping n, or nil.
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// removeKey removes key and its value from mapping n.
+func removeKey(n *yaml.Node, key string) bool {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			n.Content = append(n.Content[:i], n.Content[i+2:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func encodeYAML(n *yaml.Node, indent int) (string, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(indent)
+	if err := enc.Encode(n); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// source is a config file's lines and YAML node tree, for edits that rewrite
+// only the entries they change and leave every other byte of the file as it
+// was.
+type source struct {
+	lines  []string            // Lines of the file, each with its newline
+	doc    yaml.Node           // Document, edited in place
+	root   *yaml.Node          // Top-level mapping
+	indent int                 // Indentation of nested mappings in the file
+	block  map[*yaml.Node]bool // Block-style mappings as parsed, before any edit
+}
+
+// textEdit replaces lines [start, end) of a source with an entry encoded once
+// every change to the node tree is made. A nil key removes the lines.
+type textEdit struct {
+	start, end int
+	key, value *yaml.Node
+	column     int // Indentation of the encoded entry
+}
+
+func parseSource(file string, data []byte) (*source, error) {
+	s := &source{block: make(map[*yaml.Node]bool)}
+	if err := yaml.Unmarshal(data, &s.doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	if s.doc.Kind == 0 {
+		s.doc.Kind = yaml.DocumentNode
+	}
+	if len(s.doc.Content) == 0 {
+		s.doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	s.root = s.doc.Content[0]
+	if s.root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: top level must be a mapping", file)
+	}
+
+	s.lines = strings.SplitAfter(string(data), "\n")
+	if s.lines[len(s.lines)-1] == "" {
+		s.lines = s.lines[:len(s.lines)-1]
+	} else {
+		s.lines[len(s.lines)-1] += "\n"
+	}
+
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		if n.Kind == yaml.MappingNode && n.Style&yaml.FlowStyle == 0 {
+			s.block[n] = true
+		}
+		for _, c := range n.Content {
+			walk(c)
+		}
+	}
+	walk(s.root)
+	s.indent = detectIndent(s.root)
+	return s, nil
+}
+
+// detectIndent returns the smallest step in column from a key to the keys of
+// its block mapping value, or 2 when the file nests none.
+func detectIndent(root *yaml.Node) int {
+	indent := 0
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		for i := 0; i < len(n.Content); i++ {
+			c := n.Content[i]
+			if n.Kind == yaml.MappingNode && i%2 == 1 && c.Kind == yaml.MappingNode && len(c.Content) > 0 {
+				key := n.Content[i-1]
+				if step := c.Content[0].Column - key.Column; c.Content[0].Line > key.Line && step > 0 && (indent == 0 || step < indent) {
+					indent = step
+				}
+			}
+			walk(c)
+		}
+	}
+	walk(root)
+	if indent == 0 {
+		return 2
+	}
+	return indent
+}
+
+// rewrite returns the edit writing the entry at keys as the node tree now
+// has it. A key the edit added is inserted after its mapping's last entry;
+// an entry inside a flow mapping rewrites the outermost block entry holding
+// it.
+func (s *source) rewrite(keys []string) textEdit {
+	n, limit := s.root, s.contentEnd()
+	for d, key := range keys {
+		i := keyIndex(n, key)
+		k, v := n.Content[i], n.Content[i+1]
+		if k.Line == 0 {
+			at := s.mappingEnd(n, limit)
+			return textEdit{start: at, end: at, key: k, value: v, column: s.childColumn(n)}
+		}
+		start, end := s.entrySpan(n, i, limit)
+		if d == len(keys)-1 || !s.block[v] {
+			return textEdit{start: start, end: end, key: k, value: v, column: k.Column - 1}
+		}
+		n, limit = v, end
+	}
+	panic("unreachable")
+}
+
+// remove returns the edit deleting the entry at keys, with the comment lines
+// heading it. Call it before removing the entry from the node tree.
+func (s *source) remove(keys []string) textEdit {
+	n, limit := s.root, s.contentEnd()
+	for d, key := range keys {
+		i := keyIndex(n, key)
+		k, v := n.Content[i], n.Content[i+1]
+		start, end := s.entrySpan(n, i, limit)
+		if d < len(keys)-1 && !s.block[v] {
+			return textEdit{start: start, end: end, key: k, value: v, column: k.Column - 1}
+		}
+		if d == len(keys)-1 {
+			for k.HeadComment != "" && start > 0 && isComment(s.lines[start-1], k.Column-1) {
+				start--
+			}
+			// Keep one blank line where the entry was set apart by two
+			if start > 0 && strings.TrimSpace(s.lines[start-1]) == "" && (end == len(s.lines) || strings.TrimSpace(s.lines[end]) == "") {
+				start--
+			}
+			return textEdit{start: start, end: end}
+		}
+		n, limit = v, end
+	}
+	panic("unreachable")
+}
+
+// entrySpan returns the lines of entry i of block mapping n: from its key to
+// the next key, less the blank and comment lines before that key. limit is
+// where the entry holding n ends.
+func (s *source) entrySpan(n *yaml.Node, i int, limit int) (int, int) {
+	key := n.Content[i]
+	start, end := key.Line-1, limit
+	if i+2 < len(n.Content) && n.Content[i+2].Line > 0 {
+		end = n.Content[i+2].Line - 1
+	}
+	for end > start+1 && (strings.TrimSpace(s.lines[end-1]) == "" || isComment(s.lines[end-1], key.Column-1)) {
+		end--
+	}
+	return start, end
+}
+
+// mappingEnd returns where an entry added to n goes: after its last entry
+// from the file, or at the end of an empty file.
+func (s *source) mappingEnd(n *yaml.Node, limit int) int {
+	last := -1
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Line > 0 {
+			last = i
+		}
+	}
+	if last < 0 {
+		return limit
+	}
+	_, end := s.entrySpan(n, last, limit)
+	return end
+}
+
+// childColumn returns the indentation of n's keys.
+func (s *source) childColumn(n *yaml.Node) int {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Line > 0 {
+			return n.Content[i].Column - 1
+		}
+	}
+	return 0 // Only the top-level mapping can have no keys in the file
+}
+
+// contentEnd returns the number of lines, less trailing blank and comment lines.
+func (s *source) contentEnd() int {
+	end := len(s.lines)
+	for end > 0 && (strings.TrimSpace(s.lines[end-1]) == "" || isComment(s.lines[end-1], 0)) {
+		end--
+	}
+	if end == 0 {
+		return len(s.lines)
+	}
+	return end
+}
+
+// apply returns the file with edits made. Edits of the same lines, e.g. two
+// changes inside one flow mapping, are made once. A file that is a single
+// flow mapping is encoded whole.
+func (s *source) apply(edits []textEdit) ([]byte, error) {
+	if !s.block[s.root] && len(s.root.Content) > 0 {
+		text, err := encodeYAML(&s.doc, s.indent)
+		return []byte(text), err
+	}
+
+	// From the end, so earlier line numbers stay valid, and a replacement
+	// before an insertion at the same line
+	sort.SliceStable(edits, func(i, j int) bool {
+		if edits[i].start != edits[j].start {
+			return edits[i].start > edits[j].start
+		}
+		return edits[i].end > edits[j].end
+	})
+	lines := append([]string(nil), s.lines...)
+	for i, e := range edits {
+		if i > 0 && e.start == edits[i-1].start && e.end == edits[i-1].end && e.start != e.end {
+			continue
+		}
+		var text []string
+		if e.key != nil {
+			entry, err := s.encodeEntry(e.key, e.value, e.column)
+			if err != nil {
+				return nil, err
+			}
+			text = strings.SplitAfter(entry, "\n")
+			text = text[:len(text)-1]
+		}
+		lines = append(lines[:e.start], append(text, lines[e.end:]...)...)
+	}
+	return []byte(strings.Join(lines, "")), nil
+}
+
+// encodeEntry encodes one mapping entry at column. The comments heading and
+// following the entry stay in the file, so they are left out.
+func (s *source) encodeEntry(key, value *yaml.Node, column int) (string, error) {
+	k, v := *key, *value
+	k.HeadComment, k.FootComment, v.FootComment = "", "", ""
+	text, err := encodeYAML(&yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{&k, &v}}, s.indent)
+	if err != nil {
+		return "", err
+	}
+	prefix := strings.Repeat(" ", column)
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, ""), nil
+}
+
+// isComment reports whether line is only a comment, indented at most column.
+func isComment(line string, column int) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	return strings.HasPrefix(trimmed, "#") && len(line)-len(trimmed) <= column
+}
+
+// keyIndex returns the index of key's node in mapping n's content, or -1.
+func keyIndex(n *yaml.Node, key string) int {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,233 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestSetValue verifies edits keep comments and key order, parse values as
+// YAML and create missing keys
+func TestSetValue(t *testing.T) {
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+	configPath := filepath.Join(t.TempDir(), "rooda-config.yml")
+	os.WriteFile(configPath, []byte(`# Team config
+loop:
+  log_level: info # keep quiet
+  ai_cmd: "${HOME}/bin/agent"
+ai_cmd_aliases:
+  fast: fast-ai
+`), 0644)
+	flags := CLIFlags{ConfigPath: configPath}
+
+	for _, edit := range [][2]string{
+		{"loop.log_level", "debug"},
+		{"procedures.build.iteration_timeout", "900"},
+		{"procedures.build.summary", "yes"},
+		{"procedures.build.observe", "[{path: observe.md}]"},
+		{"ai_cmd_aliases.fast.tty", "true"},
+	} {
+		if err := SetValue(configPath, edit[0], edit[1], flags); err != nil {
+			t.Fatalf("SetValue(%s) failed: %v", edit[0], err)
+		}
+	}
+
+	data, _ := os.ReadFile(configPath)
+	want := `# Team config
+loop:
+  log_level: debug # keep quiet
+  ai_cmd: "${HOME}/bin/agent"
+ai_cmd_aliases:
+  fast:
+    command: fast-ai
+    tty: true
+procedures:
+  build:
+    iteration_timeout: 900
+    summary: yes
+    observe: [{path: observe.md}]
+`
+	if string(data) != want {
+		t.Errorf("Unexpected file after edits:\n%s\nwant:\n%s", data, want)
+	}
+
+	config, err := LoadConfig(flags)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if build := config.Procedures["build"]; build.IterationTimeout == nil || *build.IterationTimeout != 900 || build.Summary != "yes" {
+		t.Errorf("Expected edited procedure to load, got %+v", build)
+	}
+}
+
+// TestSetValueKeepsFormatting verifies an edit rewrites only the lines of the
+// setting it changes, in the file's own indentation
+func TestSetValueKeepsFormatting(t *testing.T) {
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "rooda-config.yml")
+	original := `# Team config
+
+loop:
+    default_max_iterations: 3   # keep low
+    log_level:   info
+
+    # Quiet CLIs
+    idle_timeout: 600
+
+procedures:
+    build:
+        summary: "Build it"    # shown in list
+        iteration_timeout: 300
+# End of config
+`
+	os.WriteFile(configPath, []byte(original), 0644)
+	flags := CLIFlags{ConfigPath: configPath}
+
+	if err := SetValue(configPath, "loop.log_level", "debug", flags); err != nil {
+		t.Fatalf("SetValue failed: %v", err)
+	}
+	data, _ := os.ReadFile(configPath)
+	if want := strings.Replace(original, "    log_level:   info\n", "    log_level: debug\n", 1); string(data) != want {
+		t.Errorf("Expected only log_level changed, got:\n%s\nwant:\n%s", data, want)
+	}
+
+	for _, edit := range [][2]string{
+		{"procedures.build.idle_timeout", "60"},
+		{"procedures.review.summary", "Review it"},
+		{"ai_cmd_aliases.fast", "fast-ai"},
+	} {
+		if err := SetValue(configPath, edit[0], edit[1], flags); err != nil {
+			t.Fatalf("SetValue(%s) failed: %v", edit[0], err)
+		}
+	}
+	if err := UnsetValue(configPath, "loop.idle_timeout", flags); err != nil {
+		t.Fatalf("UnsetValue failed: %v", err)
+	}
+	data, _ = os.ReadFile(configPath)
+	want := `# Team config
+
+loop:
+    default_max_iterations: 3   # keep low
+    log_level: debug
+
+procedures:
+    build:
+        summary: "Build it"    # shown in list
+        iteration_timeout: 300
+        idle_timeout: 60
+    review:
+        summary: Review it
+ai_cmd_aliases:
+    fast: fast-ai
+# End of config
+`
+	if string(data) != want {
+		t.Errorf("Unexpected file after edits:\n%s\nwant:\n%s", data, want)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Expected no temporary files left, got %v", entries)
+	}
+}
+
+// TestSetValueInvalid verifies rejected edits leave the file unchanged
+func TestSetValueInvalid(t *testing.T) {
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "rooda-config.yml")
+	original := "loop:\n  log_level: info\n"
+	os.WriteFile(configPath, []byte(original), 0644)
+	flags := CLIFlags{ConfigPath: configPath}
+
+	for _, tt := range []struct {
+		path, value, err string
+	}{
+		{"loop.log_level", "loud", `invalid log_level "loud"`},
+		{"loop.failure_threshold", "0", "loop.failure_threshold must be >= 1"},
+		{"loop.max_retries", "3 # retries", "cannot unmarshal !!str `3 # ret...` into int"},
+		{"loop.iteraton_timeout", "30", `unknown key "iteraton_timeout" in loop (did you mean "iteration_timeout"?)`},
+		{"loop.log_level.color", "true", `loop.log_level is a single value, it has no key "color"`},
+		{"ai_cmd_aliases.fast", "{command: fast-ai, ttty: true}", `unknown key "ttty" in ai_cmd_aliases.fast`},
+		{"loop..log_level", "info", `invalid setting path "loop..log_level"`},
+	} {
+		err := SetValue(configPath, tt.path, tt.value, flags)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("SetValue(%s, %s): expected error %q, got %v", tt.path, tt.value, tt.err, err)
+		}
+		if data, _ := os.ReadFile(configPath); string(data) != original {
+			t.Errorf("SetValue(%s, %s): expected file unchanged, got:\n%s", tt.path, tt.value, data)
+		}
+	}
+
+	newPath := filepath.Join(dir, "new", "rooda-config.yml")
+	if err := SetValue(newPath, "loop.log_level", "loud", CLIFlags{ConfigPath: newPath}); err == nil {
+		t.Error("Expected invalid value to be rejected")
+	}
+	if _, err := os.Stat(filepath.Dir(newPath)); !os.IsNotExist(err) {
+		t.Error("Expected a rejected edit not to create the file or its directory")
+	}
+}
+
+// TestUnsetValue verifies a removed setting takes any keys it empties with it
+func TestUnsetValue(t *testing.T) {
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+	configPath := filepath.Join(t.TempDir(), "rooda-config.yml")
+	os.WriteFile(configPath, []byte(`loop:
+  log_level: debug # noisy
+procedures:
+  build:
+    resource_limits:
+      memory_mb: 512
+`), 0644)
+	flags := CLIFlags{ConfigPath: configPath}
+
+	if err := UnsetValue(configPath, "procedures.build.resource_limits.memory_mb", flags); err != nil {
+		t.Fatalf("UnsetValue failed: %v", err)
+	}
+	if data, _ := os.ReadFile(configPath); string(data) != "loop:\n  log_level: debug # noisy\n" {
+		t.Errorf("Expected empty parents removed, got:\n%s", data)
+	}
+
+	err := UnsetValue(configPath, "loop.max_retries", flags)
+	if err == nil || err.Error() != "loop.max_retries is not set in "+configPath {
+		t.Errorf("Expected not set error, got %v", err)
+	}
+}
+
+// TestGetValue verifies merged values print as text or YAML
+func TestGetValue(t *testing.T) {
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+	configPath := filepath.Join(t.TempDir(), "rooda-config.yml")
+	os.WriteFile(configPath, []byte(`loop:
+  iteration_timeout: auto
+procedures:
+  build:
+    default_max_iterations: 4
+    resource_limits:
+      memory_mb: 256
+`), 0644)
+
+	config, err := LoadConfig(CLIFlags{ConfigPath: configPath})
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	for path, want := range map[string]string{
+		"loop.iteration_timeout":                  "auto",
+		"loop.log_level":                          "info",
+		"procedures.build.default_max_iterations": "4",
+		"procedures.build.resource_limits":        "memory_mb: 256\n",
+	} {
+		if got, err := GetValue(config, path); err != nil || got != want {
+			t.Errorf("GetValue(%s) = %q, %v; want %q", path, got, err, want)
+		}
+	}
+	if _, err := GetValue(config, "loop.idle_timeout"); err == nil || err.Error() != "loop.idle_timeout is not set" {
+		t.Errorf("Expected not set error, got %v", err)
+	}
+	if _, err := GetValue(config, "lop.log_level"); err == nil || !strings.Contains(err.Error(), `unknown top-level key "lop"`) {
+		t.Errorf("Expected unknown key error, got %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts every environment variable rooda reads.
+const EnvPrefix = "ROODA_"
+
+// EnvVar is an environment variable that sets a config value. Variables are
+// named after the setting path: loop.log_level is ROODA_LOOP_LOG_LEVEL and
+// procedures.build.ai_cmd_alias is ROODA_PROCEDURES_BUILD_AI_CMD_ALIAS.
+type EnvVar struct {
+	Name string // e.g. ROODA_LOOP_LOG_LEVEL
+	Path string // Setting path, e.g. loop.log_level
+	Type string // Accepted values, e.g. "integer" or "debug|info|warn|error"
+
+	key []string     // Config file keys leading to the value
+	typ reflect.Type // Type the value decodes into
+}
+
+// EnvVarName returns the environment variable that sets the setting at path,
+// e.g. loop.log_level -> ROODA_LOOP_LOG_LEVEL.
+func EnvVarName(path string) string {
+	return EnvPrefix + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(path))
+}
+
+// EnvVars lists the variables that set config values: every loop setting,
+// every setting of the procedures in config, and the command of each alias.
+// The list is generated from the config file structure, so every scalar
+// setting has a variable.
+func EnvVars(config *Config) []EnvVar {
+	var vars []EnvVar
+	vars = append(vars, envVarsFor(reflect.TypeOf(loopYAML{}), []string{"loop"})...)
+
+	for _, name := range sortedNames(config.AICmdAliases) {
+		alias := newEnvVar([]string{"ai_cmd_aliases", name}, reflect.TypeOf(""))
+		alias.Type = "command"
+		vars = append(vars, alias)
+	}
+	for _, name := range sortedNames(config.Procedures) {
+		vars = append(vars, envVarsFor(reflect.TypeOf(procedureYAML{}), []string{"procedures", name})...)
+	}
+	return vars
+}
+
+// envVarsFor lists a variable for each scalar setting in t, a struct decoded
+// at key.
+func envVarsFor(t reflect.Type, key []string) []EnvVar {
+	var vars []EnvVar
+	for _, f := range yamlFields(t) {
+		fieldKey := append(append([]string(nil), key...), f.key)
+		ft := f.typ
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		switch {
+		case envScalar(ft):
+			vars = append(vars, newEnvVar(fieldKey, ft))
+		case ft.Kind() == reflect.Struct:
+			vars = append(vars, envVarsFor(ft, fieldKey)...)
+		}
+		// Maps and lists (e.g. fragment parameters) have no variable
+	}
+	return vars
+}
+
+// envScalar reports whether values of t are written as a single scalar: plain
+// values, and types like timeouts and phases that accept a scalar form.
+func envScalar(t reflect.Type) bool {
+	switch t {
+	case reflect.TypeOf(timeoutValue{}), reflect.TypeOf(phaseYAML{}):
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Int, reflect.Bool, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func newEnvVar(key []string, t reflect.Type) EnvVar {
+	path := strings.Join(key, ".")
+	return EnvVar{Name: EnvVarName(path), Path: path, Type: envType(t, key[len(key)-1]), key: key, typ: t}
+}
+
+// envType describes the values a variable of type t accepts.
+func envType(t reflect.Type, key string) string {
+	switch t {
+	case reflect.TypeOf(timeoutValue{}):
+		return "seconds or " + IterationTimeoutAuto
+	case reflect.TypeOf(phaseYAML{}):
+		return "fragment path"
+	}
+	switch t.Kind() {
+	case reflect.Int:
+		return "integer"
+	case reflect.Float64:
+		return "number"
+	case reflect.Bool:
+		return "true or false"
+	}
+	if values, ok := schemaEnums[key]; ok {
+		return strings.Join(values, "|")
+	}
+	return "string"
+}
+
+// envReserved are ROODA_ variables that select config rather than set it.
+var envReserved = map[string]bool{
+	"ROODA_CONFIG_HOME": true,
+	ProfileEnvVar:       true,
+}
+
+// applyEnvVars merges ROODA_* variables over the config files. Each value
+// decodes as the config file value at its path would, so a bad value is an
+// error rather than ignored. Unknown loop and procedure variables are
+// warnings, and an unknown alias variable defines a new alias.
+func applyEnvVars(config *Config, provenance map[string]ConfigSource) {
+	vars := EnvVars(config)
+	byName := make(map[string]EnvVar, len(vars))
+	names := make([]string, len(vars))
+	for i, v := range vars {
+		byName[v.Name] = v
+		names[i] = strings.ToLower(v.Name) // closestKey compares in lowercase
+	}
+
+	var set []string
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(name, EnvPrefix) && value != "" && !envReserved[name] {
+			set = append(set, name)
+		}
+	}
+	sort.Strings(set)
+
+	for _, name := range set {
+		value := os.Getenv(name)
+		v, ok := byName[name]
+		switch {
+		case ok && v.key[0] == "ai_cmd_aliases":
+			setEnvAlias(config, provenance, v.key[1], value)
+			continue
+		case strings.HasPrefix(name, EnvPrefix+"AI_CMD_ALIASES_"):
+			alias := strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(name, EnvPrefix+"AI_CMD_ALIASES_"), "_", "-"))
+			setEnvAlias(config, provenance, alias, value)
+			continue
+		case !ok:
+			if strings.HasPrefix(name, EnvPrefix+"LOOP_") || strings.HasPrefix(name, EnvPrefix+"PROCEDURES_") {
+				msg := fmt.Sprintf("unknown environment variable %s", name)
+				if suggestion := closestKey(name, names); suggestion != "" {
+					msg += fmt.Sprintf(" (did you mean %s?)", strings.ToUpper(suggestion))
+				}
+				config.Warnings = append(config.Warnings, ValidationError{Message: msg})
+			}
+			continue
+		}
+
+		overlay, err := envOverlay(v, value)
+		if err != nil {
+			config.mergeErrors = append(config.mergeErrors, ValidationError{
+				Message: fmt.Sprintf("%s: invalid value %q for %s, expected %s", name, value, v.Path, v.Type),
+			})
+			continue
+		}
+		mergeConfig(config, overlay, provenance, TierEnvVar, "", ".")
+	}
+}
+
+// envOverlay decodes one variable's value as a config file setting it.
+// Booleans take whatever strconv.ParseBool does (1, t, FALSE, ...), as they
+// always have, not only YAML's true and false.
+func envOverlay(v EnvVar, value string) (*configFile, error) {
+	n := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+	switch v.typ.Kind() {
+	case reflect.String:
+		n.Tag = "!!str" // e.g. a summary of "yes" stays a string
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, err
+		}
+		n.Value = strconv.FormatBool(b)
+	}
+	for i := len(v.key) - 1; i >= 0; i-- {
+		n = &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{{Kind: yaml.ScalarNode, Value: v.key[i]}, n}}
+	}
+	var cf configFile
+	if err := n.Decode(&cf); err != nil {
+		return nil, err
+	}
+	return &cf, nil
+}
+
+// setEnvAlias sets an alias command, keeping any options the alias has.
+func setEnvAlias(config *Config, provenance map[string]ConfigSource, name string, command string) {
+	config.AICmdAliases[name] = command
+	provenance["ai_cmd_aliases."+name] = ConfigSource{TierEnvVar, "", command}
+}
+
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestEnvVars verifies variables are generated for every scalar setting
+func TestEnvVars(t *testing.T) {
+	config := builtInDefaults()
+	config.AICmdAliases["kiro-cli"] = "kiro-cli chat"
+	config.Procedures["agents-sync"] = Procedure{}
+
+	vars := make(map[string]EnvVar)
+	for _, v := range EnvVars(config) {
+		vars[v.Name] = v
+	}
+	for name, want := range map[string]struct{ path, typ string }{
+		"ROODA_LOOP_MAX_OUTPUT_BUFFER":                             {"loop.max_output_buffer", "integer"},
+		"ROODA_LOOP_ADAPTIVE_TIMEOUT_K":                            {"loop.adaptive_timeout.k", "number"},
+		"ROODA_LOOP_RESOURCE_LIMITS_MEMORY_MB":                     {"loop.resource_limits.memory_mb", "integer"},
+		"ROODA_LOOP_ITERATION_TIMEOUT":                             {"loop.iteration_timeout", "seconds or auto"},
+		"ROODA_LOOP_LOG_LEVEL":                                     {"loop.log_level", "debug|info|warn|error"},
+		"ROODA_LOOP_SHOW_AI_OUTPUT":                                {"loop.show_ai_output", "true or false"},
+		"ROODA_AI_CMD_ALIASES_KIRO_CLI":                            {"ai_cmd_aliases.kiro-cli", "command"},
+		"ROODA_PROCEDURES_AGENTS_SYNC_AI_CMD_ALIAS":                {"procedures.agents-sync.ai_cmd_alias", "string"},
+		"ROODA_PROCEDURES_AGENTS_SYNC_OBSERVE":                     {"procedures.agents-sync.observe", "fragment path"},
+		"ROODA_PROCEDURES_AGENTS_SYNC_CONTEXT":                     {"procedures.agents-sync.context", "fresh|continue|continue-until-failure"},
+		"ROODA_PROCEDURES_AGENTS_SYNC_RESOURCE_LIMITS_CPU_SECONDS": {"procedures.agents-sync.resource_limits.cpu_seconds", "integer"},
+	} {
+		v, ok := vars[name]
+		if !ok {
+			t.Errorf("Expected variable %s", name)
+			continue
+		}
+		if v.Path != want.path || v.Type != want.typ {
+			t.Errorf("%s: got path %q type %q, want %q %q", name, v.Path, v.Type, want.path, want.typ)
+		}
+	}
+	if _, ok := vars["ROODA_LOOP_ADAPTIVE_TIMEOUT"]; ok {
+		t.Error("Expected no variable for the adaptive_timeout mapping itself")
+	}
+}
+
+// TestLoadConfigEnvVarsGenerated verifies procedure, alias and nested loop
+// variables apply like config file values
+func TestLoadConfigEnvVarsGenerated(t *testing.T) {
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+	configPath := filepath.Join(t.TempDir(), "rooda-config.yml")
+	os.WriteFile(configPath, []byte(`loop:
+  show_ai_output: true
+ai_cmd_aliases:
+  fast:
+    command: "fast-ai"
+    tty: true
+procedures:
+  agents-sync:
+    summary: "Sync"
+    ai_cmd_alias: fast
+`), 0644)
+	t.Setenv("ROODA_LOOP_SHOW_AI_OUTPUT", "false")
+	t.Setenv("ROODA_LOOP_MAX_OUTPUT_BUFFER", "2048")
+	t.Setenv("ROODA_LOOP_RESOURCE_LIMITS_MEMORY_MB", "512")
+	t.Setenv("ROODA_PROCEDURES_AGENTS_SYNC_SUMMARY", "yes")
+	t.Setenv("ROODA_PROCEDURES_AGENTS_SYNC_DEFAULT_MAX_ITERATIONS", "3")
+	t.Setenv("ROODA_AI_CMD_ALIASES_FAST", "faster-ai")
+	t.Setenv("ROODA_AI_CMD_ALIASES_MY_AGENT", "my-agent --run")
+	t.Setenv("ROODA_LOOP_FAILURE_THRESHOLDS", "4")
+
+	config, err := LoadConfig(CLIFlags{ConfigPath: configPath})
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if config.Loop.ShowAIOutput {
+		t.Error("Expected ROODA_LOOP_SHOW_AI_OUTPUT=false to override the config file")
+	}
+	if config.Loop.MaxOutputBuffer != 2048 {
+		t.Errorf("Expected max_output_buffer 2048, got %d", config.Loop.MaxOutputBuffer)
+	}
+	if m := config.Loop.ResourceLimits.MemoryMB; m == nil || *m != 512 {
+		t.Errorf("Expected memory_mb 512, got %v", m)
+	}
+	proc := config.Procedures["agents-sync"]
+	if proc.Summary != "yes" || proc.AICmdAlias != "fast" || proc.DefaultMaxIterations == nil || *proc.DefaultMaxIterations != 3 {
+		t.Errorf("Expected procedure env overrides merged with config, got %+v", proc)
+	}
+	if src := config.Provenance["procedures.agents-sync.summary"]; src.Tier != TierEnvVar || src.Describe("procedures.agents-sync.summary") != "env ROODA_PROCEDURES_AGENTS_SYNC_SUMMARY" {
+		t.Errorf("Expected env provenance for summary, got %+v", src)
+	}
+	if config.AICmdAliases["fast"] != "faster-ai" || !config.AliasOptions["fast"].TTY {
+		t.Errorf("Expected alias command replaced and options kept, got %q %+v", config.AICmdAliases["fast"], config.AliasOptions["fast"])
+	}
+	if config.AICmdAliases["my-agent"] != "my-agent --run" {
+		t.Errorf("Expected new alias my-agent, got %v", config.AICmdAliases)
+	}
+	if len(config.Warnings) != 1 || !strings.Contains(config.Warnings[0].Message, "unknown environment variable ROODA_LOOP_FAILURE_THRESHOLDS (did you mean ROODA_LOOP_FAILURE_THRESHOLD?)") {
+		t.Errorf("Expected unknown variable warning, got %v", config.Warnings)
+	}
+}
+
+// TestLoadConfigEnvVarsBool verifies booleans accept what strconv.ParseBool does
+func TestLoadConfigEnvVarsBool(t *testing.T) {
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+	configPath := filepath.Join(t.TempDir(), "missing.yml")
+
+	for value, want := range map[string]bool{"1": true, "0": false, "t": true, "F": false, "TRUE": true} {
+		t.Setenv("ROODA_LOOP_SHOW_AI_OUTPUT", value)
+		t.Setenv("ROODA_LOOP_IDLE_TIMEOUT_FAILURE", value)
+		config, err := LoadConfig(CLIFlags{ConfigPath: configPath})
+		if err != nil {
+			t.Fatalf("ROODA_LOOP_SHOW_AI_OUTPUT=%s: LoadConfig failed: %v", value, err)
+		}
+		if config.Loop.ShowAIOutput != want || config.Loop.IdleTimeoutFailure != want {
+			t.Errorf("=%s: expected %v, got show_ai_output %v idle_timeout_failure %v", value, want, config.Loop.ShowAIOutput, config.Loop.IdleTimeoutFailure)
+		}
+		if src := config.Provenance["loop.show_ai_output"]; src.Tier != TierEnvVar || src.Value != want {
+			t.Errorf("=%s: expected env provenance with %v, got %+v", value, want, src)
+		}
+	}
+}
+
+// TestLoadConfigEnvVarsInvalid verifies unparsable values are errors
+func TestLoadConfigEnvVarsInvalid(t *testing.T) {
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+	t.Setenv("ROODA_LOOP_FAILURE_THRESHOLD", "abc")
+	t.Setenv("ROODA_LOOP_IDLE_TIMEOUT_FAILURE", "sometimes")
+	t.Setenv("ROODA_LOOP_ITERATION_TIMEOUT", "soon")
+
+	_, err := LoadConfig(CLIFlags{ConfigPath: filepath.Join(t.TempDir(), "missing.yml")})
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("Expected ValidationErrors, got %v", err)
+	}
+	for _, want := range []string{
+		`ROODA_LOOP_FAILURE_THRESHOLD: invalid value "abc" for loop.failure_threshold, expected integer`,
+		`ROODA_LOOP_IDLE_TIMEOUT_FAILURE: invalid value "sometimes" for loop.idle_timeout_failure, expected true or false`,
+		`ROODA_LOOP_ITERATION_TIMEOUT: invalid value "soon" for loop.iteration_timeout, expected seconds or auto`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error %q, got:\n%v", want, err)
+		}
+	}
+}
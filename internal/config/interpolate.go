@@ -0,0 +1,278 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BuiltinPrefix namespaces the values rooda provides for interpolation, e.g. ${rooda.date}.
+const BuiltinPrefix = "rooda."
+
+// builtinValues are the ${rooda.*} values, computed when referenced.
+var builtinValues = map[string]func(file string) (string, error){
+	"workspace_root": func(string) (string, error) {
+		return os.Getwd()
+	},
+	"config_dir": func(file string) (string, error) {
+		return filepath.Abs(filepath.Dir(file))
+	},
+	"git_branch": func(string) (string, error) {
+		out, err := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD").Output()
+		if err != nil {
+			return "", fmt.Errorf("not in a git repository")
+		}
+		return strings.TrimSpace(string(out)), nil
+	},
+	"date": func(string) (string, error) {
+		return time.Now().Format("2006-01-02"), nil
+	},
+}
+
+// reference matches ${name}, ${name:-default} and the $$ escape.
+var reference = regexp.MustCompile(`\$\$|\$\{([^}]*)\}`)
+
+// errReferenceFailed marks a reference to a setting whose own expansion
+// failed and was already reported.
+var errReferenceFailed = errors.New("referenced setting failed to expand")
+
+// Interpolation records a config value that contained ${...} references.
+type Interpolation struct {
+	Path       string   // Setting path, e.g. loop.ai_cmd or procedures.build.act[0].content
+	File       string   // Config file the value was written in
+	Template   string   // Value as written
+	Value      string   // Value after expansion
+	References []string // Where each reference came from, e.g. "env HOME", "config loop.ai_cmd_alias"
+}
+
+// interpolator expands references in the scalar values of one config file.
+type interpolator struct {
+	file      string
+	scalars   map[string]*yaml.Node            // Setting path -> scalar value node in this file
+	lookup    func(path string) (string, bool) // Values loaded from earlier files
+	expanded  map[string]Interpolation
+	expanding map[string]bool
+	failed    map[string]bool
+	errs      ValidationErrors
+}
+
+// interpolate expands ${...} references in every scalar value of doc in
+// place. References are environment variables (${HOME}, ${MODEL:-sonnet}),
+// other settings by path (${loop.ai_cmd_alias}), looked up in this file and
+// then in files loaded earlier, and built-in values (${rooda.git_branch}).
+// Returns the values that contained references, by path.
+func interpolate(doc *yaml.Node, file string, lookup func(path string) (string, bool)) (map[string]Interpolation, error) {
+	in := &interpolator{
+		file:      file,
+		scalars:   make(map[string]*yaml.Node),
+		lookup:    lookup,
+		expanded:  make(map[string]Interpolation),
+		expanding: make(map[string]bool),
+		failed:    make(map[string]bool),
+	}
+	var index func(n *yaml.Node, path string)
+	index = func(n *yaml.Node, path string) {
+		switch n.Kind {
+		case yaml.DocumentNode:
+			for _, c := range n.Content {
+				index(c, path)
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if path == "" && n.Content[i].Value == "profiles" {
+					continue // Expanded when the profile is applied
+				}
+				index(n.Content[i+1], joinPath(path, n.Content[i].Value))
+			}
+		case yaml.SequenceNode:
+			for i, c := range n.Content {
+				index(c, fmt.Sprintf("%s[%d]", path, i))
+			}
+		case yaml.ScalarNode:
+			in.scalars[path] = n
+		}
+	}
+	index(doc, "")
+
+	paths := make([]string, 0, len(in.scalars))
+	for path := range in.scalars {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		in.expand(path)
+	}
+
+	if len(in.errs) > 0 {
+		sort.SliceStable(in.errs, func(i, j int) bool {
+			if in.errs[i].Line != in.errs[j].Line {
+				return in.errs[i].Line < in.errs[j].Line
+			}
+			return in.errs[i].Column < in.errs[j].Column
+		})
+		return nil, in.errs
+	}
+
+	interpolations := make(map[string]Interpolation)
+	for path, result := range in.expanded {
+		if result.Template != result.Value || len(result.References) > 0 {
+			interpolations[path] = result
+		}
+	}
+	return interpolations, nil
+}
+
+// expand expands the scalar at path, once, and returns its value.
+func (in *interpolator) expand(path string) (string, bool) {
+	if result, ok := in.expanded[path]; ok {
+		return result.Value, true
+	}
+	n := in.scalars[path]
+	if !strings.Contains(n.Value, "$") {
+		return n.Value, true
+	}
+	if in.failed[path] {
+		return "", false
+	}
+	if in.expanding[path] {
+		in.fail(path, "interpolation cycle through %s", path)
+		return "", false
+	}
+	in.expanding[path] = true
+	defer delete(in.expanding, path)
+
+	result := Interpolation{Path: path, File: in.file, Template: n.Value}
+	ok := true
+	result.Value = reference.ReplaceAllStringFunc(n.Value, func(match string) string {
+		if match == "$$" {
+			return "$"
+		}
+		name := match[2 : len(match)-1]
+		def, hasDefault := "", false
+		if i := strings.Index(name, ":-"); i >= 0 {
+			name, def, hasDefault = name[:i], name[i+2:], true
+		}
+		value, source, err := in.resolve(name)
+		if err == nil && value != "" {
+			result.References = append(result.References, source)
+			return value
+		}
+		if hasDefault {
+			result.References = append(result.References, "default for "+name)
+			return def
+		}
+		if err == nil {
+			err = fmt.Errorf("%s is empty", source)
+		}
+		ok = false
+		if err == errReferenceFailed {
+			return match
+		}
+		in.fail(path, "${%s}: %v", name, err)
+		return match
+	})
+	if !ok {
+		in.failed[path] = true
+		return "", false
+	}
+
+	if result.Value != n.Value {
+		// Let the expanded value decode as a number or bool where the field needs one
+		n.Value, n.Tag, n.Style = result.Value, "", 0
+	}
+	in.expanded[path] = result
+	return result.Value, true
+}
+
+// resolve returns the value of one reference and a description of its source.
+func (in *interpolator) resolve(name string) (string, string, error) {
+	switch {
+	case name == "":
+		return "", "", fmt.Errorf("empty reference")
+	case strings.HasPrefix(name, BuiltinPrefix):
+		builtin, ok := builtinValues[strings.TrimPrefix(name, BuiltinPrefix)]
+		if !ok {
+			names := make([]string, 0, len(builtinValues))
+			for k := range builtinValues {
+				names = append(names, BuiltinPrefix+k)
+			}
+			sort.Strings(names)
+			return "", "", fmt.Errorf("unknown built-in value, expected one of: %s", strings.Join(names, ", "))
+		}
+		value, err := builtin(in.file)
+		return value, "built-in " + name, err
+	case strings.ContainsAny(name, ".["):
+		if _, ok := in.scalars[name]; ok {
+			value, ok := in.expand(name)
+			if !ok {
+				return "", "", errReferenceFailed
+			}
+			return value, "config " + name, nil
+		}
+		if value, ok := in.lookup(name); ok {
+			return value, "config " + name, nil
+		}
+		return "", "", fmt.Errorf("no config setting %s", name)
+	}
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", "env " + name, fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, "env " + name, nil
+}
+
+func (in *interpolator) fail(path string, format string, args ...interface{}) {
+	n := in.scalars[path]
+	in.errs = append(in.errs, ValidationError{
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+		File:    in.file,
+		Line:    n.Line,
+		Column:  n.Column,
+	})
+}
+
+// provenanceLookup finds scalar settings loaded so far, for references to
+// settings in earlier files.
+func provenanceLookup(provenance map[string]ConfigSource) func(path string) (string, bool) {
+	return func(path string) (string, bool) {
+		source, ok := provenance[path]
+		if !ok || source.Value == nil {
+			return "", false
+		}
+		switch reflect.ValueOf(source.Value).Kind() {
+		case reflect.String, reflect.Int, reflect.Bool, reflect.Float64:
+			return fmt.Sprint(source.Value), true
+		}
+		return "", false
+	}
+}
+
+// Interpolations returns the interpolated values still in effect, i.e. set by
+// the file that provides the setting, sorted by path.
+func (c *Config) Interpolations() []Interpolation {
+	// Later loads of a file (e.g. its profile section) win
+	effective := make(map[string]Interpolation)
+	for _, fp := range c.sources {
+		for path, in := range fp.interpolations {
+			if source, ok := c.sourceOf(path); ok && source.File == fp.file {
+				effective[path] = in
+			}
+		}
+	}
+	result := make([]Interpolation, 0, len(effective))
+	for _, in := range effective {
+		result = append(result, in)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+	return result
+}
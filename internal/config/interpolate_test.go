@@ -0,0 +1,149 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLoadConfigInterpolation verifies env vars, defaults, config keys,
+// built-ins and the $$ escape expand before values decode
+func TestLoadConfigInterpolation(t *testing.T) {
+	globalDir := t.TempDir()
+	t.Setenv("ROODA_CONFIG_HOME", globalDir)
+	t.Setenv("AGENT_HOME", "/opt/agent")
+	t.Setenv("ITERS", "4")
+	t.Setenv("EMPTY", "")
+	os.WriteFile(filepath.Join(globalDir, "rooda-config.yml"), []byte(`loop:
+  ai_cmd_alias: "global-agent"
+`), 0644)
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "rooda-config.yml")
+	os.WriteFile(configPath, []byte(`loop:
+  ai_cmd: "${AGENT_HOME}/bin/agent --model ${ROODA_TEST_MODEL:-sonnet} --tag ${EMPTY:-none}"
+  default_max_iterations: ${ITERS}
+  show_ai_output: "${ROODA_TEST_SHOW:-true}"
+procedures:
+  demo:
+    summary: "${loop.ai_cmd_alias} on ${rooda.date}, cost $$5"
+    description: "runs ${procedures.demo.summary}"
+    observe:
+      - content: "dir ${rooda.config_dir}"
+`), 0644)
+
+	config, err := LoadConfig(CLIFlags{ConfigPath: configPath})
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if config.Loop.AICmd != "/opt/agent/bin/agent --model sonnet --tag none" {
+		t.Errorf("Expected ai_cmd expanded, got %q", config.Loop.AICmd)
+	}
+	if config.Loop.DefaultMaxIterations == nil || *config.Loop.DefaultMaxIterations != 4 {
+		t.Errorf("Expected default_max_iterations 4, got %v", config.Loop.DefaultMaxIterations)
+	}
+	if !config.Loop.ShowAIOutput {
+		t.Error("Expected show_ai_output true from default")
+	}
+	demo := config.Procedures["demo"]
+	wantSummary := "global-agent on " + time.Now().Format("2006-01-02") + ", cost $5"
+	if demo.Summary != wantSummary {
+		t.Errorf("Expected summary %q, got %q", wantSummary, demo.Summary)
+	}
+	if demo.Description != "runs "+wantSummary {
+		t.Errorf("Expected description to reference summary, got %q", demo.Description)
+	}
+	absDir, _ := filepath.Abs(tmpDir)
+	if len(demo.Observe) != 1 || demo.Observe[0].Content != "dir "+absDir {
+		t.Errorf("Expected observe content with config dir, got %+v", demo.Observe)
+	}
+
+	interpolations := make(map[string]Interpolation)
+	for _, in := range config.Interpolations() {
+		interpolations[in.Path] = in
+	}
+	aiCmd, ok := interpolations["loop.ai_cmd"]
+	if !ok {
+		t.Fatalf("Expected loop.ai_cmd in interpolations, got %v", config.Interpolations())
+	}
+	if aiCmd.File != configPath || !strings.HasPrefix(aiCmd.Template, "${AGENT_HOME}") {
+		t.Errorf("Unexpected interpolation record %+v", aiCmd)
+	}
+	wantRefs := []string{"env AGENT_HOME", "default for ROODA_TEST_MODEL", "default for EMPTY"}
+	if !reflect.DeepEqual(aiCmd.References, wantRefs) {
+		t.Errorf("Expected references %v, got %v", wantRefs, aiCmd.References)
+	}
+	if refs := interpolations["procedures.demo.summary"].References; !reflect.DeepEqual(refs, []string{"config loop.ai_cmd_alias", "built-in rooda.date"}) {
+		t.Errorf("Unexpected summary references %v", refs)
+	}
+	if _, ok := interpolations["loop.ai_cmd_alias"]; ok {
+		t.Error("Values without references should not be recorded")
+	}
+}
+
+// TestLoadConfigInterpolationOverridden verifies interpolations from a file
+// whose value was later overridden are not reported
+func TestLoadConfigInterpolationOverridden(t *testing.T) {
+	globalDir := t.TempDir()
+	t.Setenv("ROODA_CONFIG_HOME", globalDir)
+	os.WriteFile(filepath.Join(globalDir, "rooda-config.yml"), []byte(`loop:
+  ai_cmd: "${ROODA_TEST_CMD:-global}"
+`), 0644)
+	configPath := filepath.Join(t.TempDir(), "rooda-config.yml")
+	os.WriteFile(configPath, []byte(`loop:
+  ai_cmd: "workspace"
+`), 0644)
+
+	config, err := LoadConfig(CLIFlags{ConfigPath: configPath})
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if got := config.Interpolations(); len(got) != 0 {
+		t.Errorf("Expected no interpolations in effect, got %+v", got)
+	}
+}
+
+// TestLoadConfigInterpolationErrors verifies every unresolvable reference is
+// reported with its position
+func TestLoadConfigInterpolationErrors(t *testing.T) {
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+	os.Unsetenv("ROODA_TEST_UNSET")
+	configPath := filepath.Join(t.TempDir(), "rooda-config.yml")
+	os.WriteFile(configPath, []byte(`loop:
+  ai_cmd: "${ROODA_TEST_UNSET}"
+  ai_cmd_alias: "${loop.nope}"
+  log_level: "${rooda.nope}"
+procedures:
+  a:
+    summary: "${procedures.a.description}"
+    description: "${procedures.a.summary}"
+`), 0644)
+
+	_, err := LoadConfig(CLIFlags{ConfigPath: configPath})
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("Expected ValidationErrors, got %v", err)
+	}
+	want := []struct {
+		line    int
+		message string
+	}{
+		{2, "environment variable ROODA_TEST_UNSET is not set"},
+		{3, "no config setting loop.nope"},
+		{4, "unknown built-in value, expected one of: rooda.config_dir, rooda.date, rooda.git_branch, rooda.workspace_root"},
+		{8, "interpolation cycle through procedures.a.description"},
+	}
+	if len(verrs) != len(want) {
+		t.Fatalf("Expected %d errors, got %v", len(want), verrs)
+	}
+	for i, w := range want {
+		if verrs[i].File != configPath || verrs[i].Line != w.line || !strings.Contains(verrs[i].Message, w.message) {
+			t.Errorf("Error %d: expected line %d %q, got %v", i, w.line, w.message, verrs[i])
+		}
+	}
+}
@@ -1,11 +1,13 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
-	"strconv"
+	"sort"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -27,53 +29,70 @@ type CLIFlags struct {
 	Quiet            bool
 	LogLevel         string
 	ConfigPath       string
+	NoDiscover       bool   // Load only ./rooda-config.yml, not those in parent directories
+	Profile          string // Profile to apply ("" = ROODA_PROFILE, if set)
 	ObserveFragments []string
 	OrientFragments  []string
 	DecideFragments  []string
 	ActFragments     []string
+	PhaseMode        PhaseMode // How phase fragment flags combine with the procedure ("" = replace)
 	ShowHelp         bool
 	ShowVersion      bool
 	ListProcedures   bool
+
+	pending map[string][]byte // Unsaved file contents to load in place of the files, by absolute path
 }
 
 // LoadConfig loads and merges configuration from all tiers
 func LoadConfig(cliFlags CLIFlags) (*Config, error) {
 	// 1. Start with built-in defaults
 	config := builtInDefaults()
+	config.pending = cliFlags.pending
 	provenance := initProvenance(config)
 
-	// 2. Resolve global config directory and load config
+	// 2. Resolve global config directory and load installed packs, config, then drop-in procedure files
 	globalDir := resolveGlobalConfigDir()
-	globalPath := filepath.Join(globalDir, "rooda-config.yml")
-	if fileExists(globalPath) {
-		globalConfig, err := parseYAML(globalPath)
-		if err != nil {
-			return nil, fmt.Errorf("global config %s: %w", globalPath, err)
+	globalPath := filepath.Join(globalDir, ConfigFileName)
+	globalFiles := packFiles(filepath.Join(globalDir, PacksDir))
+	if config.fileExists(globalPath) {
+		globalFiles = append(globalFiles, globalPath)
+	}
+	globalFiles = append(globalFiles, dropInFiles(filepath.Join(globalDir, DropInDir))...)
+	for _, path := range globalFiles {
+		if err := loadConfigFile(config, provenance, TierGlobal, path, nil); err != nil {
+			return nil, err
 		}
-		mergeConfig(config, globalConfig, provenance, TierGlobal, globalPath, globalDir)
 	}
 
-	// 3. Load workspace config
-	workspacePath := "./rooda-config.yml"
-	if cliFlags.ConfigPath != "" {
-		workspacePath = cliFlags.ConfigPath
+	// 3. Load workspace packs and config files, outermost first, each followed
+	// by the drop-in procedure files next to it, then those in the workspace
+	workspacePaths := []string{cliFlags.ConfigPath}
+	if cliFlags.ConfigPath == "" {
+		workspacePaths = discoverWorkspaceFiles(!cliFlags.NoDiscover)
 	}
-	if fileExists(workspacePath) {
-		workspaceConfig, err := parseYAML(workspacePath)
-		if err != nil {
-			return nil, fmt.Errorf("workspace config %s: %w", workspacePath, err)
+	workspaceFiles := packFiles(filepath.Join(WorkspaceDir, PacksDir))
+	for _, path := range workspacePaths {
+		if config.fileExists(path) {
+			workspaceFiles = append(workspaceFiles, path)
+			workspaceFiles = append(workspaceFiles, dropInFiles(filepath.Join(filepath.Dir(path), DropInDir))...)
 		}
-		workspaceDir := filepath.Dir(workspacePath)
-		if workspaceDir == "" {
-			workspaceDir = "."
+	}
+	workspaceFiles = append(workspaceFiles, dropInFiles(WorkspaceProceduresDir)...)
+	for _, path := range workspaceFiles {
+		if err := loadConfigFile(config, provenance, TierWorkspace, path, nil); err != nil {
+			return nil, err
 		}
-		mergeConfig(config, workspaceConfig, provenance, TierWorkspace, workspacePath, workspaceDir)
 	}
 
-	// 4. Apply environment variables
+	// 4. Apply the selected profile over the config files
+	if err := applyProfile(config, provenance, cliFlags.Profile); err != nil {
+		return nil, err
+	}
+
+	// 5. Apply environment variables
 	applyEnvVars(config, provenance)
 
-	// 5. Apply CLI flags
+	// 6. Apply CLI flags
 	if cliFlags.MaxIterations != nil {
 		config.Loop.DefaultMaxIterations = cliFlags.MaxIterations
 		provenance["loop.default_max_iterations"] = ConfigSource{TierCLIFlag, "", *cliFlags.MaxIterations}
@@ -86,13 +105,253 @@ func LoadConfig(cliFlags CLIFlags) (*Config, error) {
 		config.Loop.AICmdAlias = cliFlags.AICmdAlias
 		provenance["loop.ai_cmd_alias"] = ConfigSource{TierCLIFlag, "", cliFlags.AICmdAlias}
 	}
+	applyPhaseFlags(config, provenance, cliFlags)
 
 	// Assign provenance to config
 	config.Provenance = provenance
 
+	// 7. Validate the merged result, reporting every problem at once
+	if err := validateSettings(config); err != nil {
+		return nil, err
+	}
+
 	return config, nil
 }
 
+// loadConfigFile merges the config file at path into config, after the files
+// it includes so its own settings win. including is the chain of files that
+// led here, to catch include cycles.
+func loadConfigFile(config *Config, provenance map[string]ConfigSource, tier ConfigTier, path string, including []string) error {
+	// Read the include list first: the file's own values are interpolated
+	// after its includes merge, so they can reference included settings
+	data, err := config.readFile(path)
+	if err != nil {
+		return fmt.Errorf("%s config %s: %w", tier, path, err)
+	}
+	includes, err := includeList(data)
+	if err != nil {
+		return fmt.Errorf("%s config %s: %w", tier, path, err)
+	}
+	dir := filepath.Dir(path)
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("%s config %s: %w", tier, path, err)
+	}
+	including = append(including, absPath)
+
+	for _, pattern := range includes {
+		files, err := includeFiles(dir, pattern)
+		if err != nil {
+			return fmt.Errorf("%s config %s: include %q: %w", tier, path, pattern, err)
+		}
+		for _, file := range files {
+			absFile, err := filepath.Abs(file)
+			if err != nil {
+				return fmt.Errorf("%s config %s: include %q: %w", tier, path, pattern, err)
+			}
+			for _, p := range including {
+				if p == absFile {
+					return fmt.Errorf("%s config %s: include cycle through %s", tier, path, file)
+				}
+			}
+			if err := loadConfigFile(config, provenance, tier, file, including); err != nil {
+				return err
+			}
+		}
+	}
+
+	cf, err := parseYAML(path, data, provenanceLookup(provenance))
+	var verrs ValidationErrors
+	if errors.As(err, &verrs) {
+		return verrs
+	}
+	if err != nil {
+		return fmt.Errorf("%s config %s: %w", tier, path, err)
+	}
+	mergeConfig(config, cf, provenance, tier, path, dir)
+	return nil
+}
+
+// ProfileEnvVar selects a profile when --profile is not given.
+const ProfileEnvVar = "ROODA_PROFILE"
+
+// applyProfile merges every definition of the named profile, in the order
+// their files loaded, as the profile tier. name "" falls back to
+// ROODA_PROFILE; no profile is applied when both are empty.
+func applyProfile(config *Config, provenance map[string]ConfigSource, name string) error {
+	if name == "" {
+		name = os.Getenv(ProfileEnvVar)
+	}
+	if name == "" {
+		return nil
+	}
+
+	found := false
+	for _, def := range config.profiles {
+		if def.name != name {
+			continue
+		}
+		found = true
+		interpolations, err := interpolate(def.node, def.file, provenanceLookup(provenance))
+		if err != nil {
+			return err
+		}
+		type profileFields profileYAML // Without the deferring UnmarshalYAML
+		var profile profileFields
+		if err := def.node.Decode(&profile); err != nil {
+			return fmt.Errorf("%s config %s: profile %q: %w", TierProfile, def.file, name, err)
+		}
+		mergeConfig(config, &configFile{
+			Loop:           profile.Loop,
+			AICmdAliases:   profile.AICmdAliases,
+			Procedures:     profile.Procedures,
+			positions:      nodePositions(def.node),
+			interpolations: interpolations,
+		}, provenance, TierProfile, def.file, filepath.Dir(def.file))
+	}
+	if !found {
+		return fmt.Errorf("unknown profile %q%s", name, availableProfiles(config.profiles))
+	}
+	config.Profile = name
+	return nil
+}
+
+// availableProfiles lists the defined profile names for error messages.
+func availableProfiles(profiles []profileSource) string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, def := range profiles {
+		if !seen[def.name] {
+			seen[def.name] = true
+			names = append(names, def.name)
+		}
+	}
+	if len(names) == 0 {
+		return ", no profiles are defined"
+	}
+	sort.Strings(names)
+	return ", available: " + strings.Join(names, ", ")
+}
+
+// includeList reads just the include entries of a config file.
+func includeList(data []byte) ([]string, error) {
+	var cf struct {
+		Include []string `yaml:"include"`
+	}
+	if err := yaml.Unmarshal(data, &cf); err != nil {
+		return nil, err
+	}
+	return cf.Include, nil
+}
+
+// ConfigFileName is the name of the global and workspace config files.
+const ConfigFileName = "rooda-config.yml"
+
+// discoverWorkspaceFiles returns the workspace config file candidates,
+// outermost first. With discover, that is rooda-config.yml in each directory
+// from the enclosing git repository's root down to the current directory,
+// named relative to it; otherwise, or outside a git repository, just
+// ./rooda-config.yml.
+func discoverWorkspaceFiles(discover bool) []string {
+	local := "./" + ConfigFileName
+	cwd, err := os.Getwd()
+	if !discover || err != nil {
+		return []string{local}
+	}
+
+	root, ok := gitRoot(cwd)
+	if !ok {
+		return []string{local}
+	}
+
+	var paths []string
+	for dir := cwd; dir != root; {
+		dir = filepath.Dir(dir)
+		rel, err := filepath.Rel(cwd, filepath.Join(dir, ConfigFileName))
+		if err != nil {
+			continue
+		}
+		paths = append([]string{rel}, paths...)
+	}
+	return append(paths, local)
+}
+
+// WorkspaceRoot returns the directory workspace state such as iteration stats
+// is kept under, relative to the current directory. With discover, that is the
+// enclosing git repository's root, so runs from any subdirectory share it;
+// otherwise, or outside a git repository, the current directory.
+func WorkspaceRoot(discover bool) string {
+	cwd, err := os.Getwd()
+	if !discover || err != nil {
+		return "."
+	}
+	root, ok := gitRoot(cwd)
+	if !ok {
+		return "."
+	}
+	rel, err := filepath.Rel(cwd, root)
+	if err != nil {
+		return "."
+	}
+	return rel
+}
+
+// gitRoot returns the nearest directory from dir upwards that contains .git.
+func gitRoot(dir string) (string, bool) {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// includeFiles resolves an include entry relative to the including file's
+// directory. Globs may match nothing; plain paths must exist.
+func includeFiles(dir string, pattern string) ([]string, error) {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(dir, pattern)
+	}
+	if !strings.ContainsAny(pattern, "*?[") {
+		if !fileExists(pattern) {
+			return nil, fmt.Errorf("file not found: %s", pattern)
+		}
+		return []string{pattern}, nil
+	}
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// dropInFiles returns the *.yml files in dir in name order, or none when dir
+// doesn't exist.
+func dropInFiles(dir string) []string {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.yml"))
+	sort.Strings(files)
+	return files
+}
+
+// packFiles returns the procedure files of the packs installed in dir, by
+// pack name, then file name.
+func packFiles(dir string) []string {
+	files, _ := filepath.Glob(filepath.Join(dir, "*", "procedures", "*.yml"))
+	sort.Strings(files)
+	return files
+}
+
+// GlobalConfigDir returns the directory holding the global config file.
+func GlobalConfigDir() string {
+	return resolveGlobalConfigDir()
+}
+
 // resolveGlobalConfigDir resolves the global config directory
 func resolveGlobalConfigDir() string {
 	// 1. ROODA_CONFIG_HOME env var
@@ -117,12 +376,12 @@ func resolveGlobalConfigDir() string {
 func builtInDefaults() *Config {
 	maxIter := DefaultMaxIterations
 	procedures := make(map[string]Procedure)
-	
+
 	// Load built-in procedures if function is registered
 	if BuiltInProceduresFunc != nil {
 		procedures = BuiltInProceduresFunc()
 	}
-	
+
 	return &Config{
 		Loop: LoopConfig{
 			IterationMode:        DefaultIterationMode,
@@ -132,13 +391,29 @@ func builtInDefaults() *Config {
 			LogLevel:             DefaultLogLevel,
 			LogTimestampFormat:   DefaultTimestampFormat,
 			ShowAIOutput:         DefaultShowAIOutput,
+			IdleTimeoutFailure:   DefaultIdleTimeoutFailure,
+			AdaptiveTimeout: AdaptiveTimeoutConfig{
+				K:          DefaultAdaptiveTimeoutK,
+				Floor:      DefaultAdaptiveTimeoutFloor,
+				Ceiling:    DefaultAdaptiveTimeoutCeiling,
+				MinSamples: DefaultAdaptiveTimeoutMinSamples,
+			},
+			RetryBackoff:    DefaultRetryBackoff,
+			RetryBackoffMax: DefaultRetryBackoffMax,
+			MaxRetries:      DefaultMaxRetries,
 		},
 		Procedures:   procedures,
 		AICmdAliases: builtInAliases(),
+		AliasOptions: make(map[string]AliasOptions),
 		Provenance:   make(map[string]ConfigSource),
 	}
 }
 
+// BuiltInAliases returns the built-in AI command aliases, by name.
+func BuiltInAliases() map[string]string {
+	return builtInAliases()
+}
+
 // builtInAliases returns the built-in AI command aliases
 func builtInAliases() map[string]string {
 	return map[string]string{
@@ -159,47 +434,104 @@ func initProvenance(config *Config) map[string]ConfigSource {
 	p["loop.log_level"] = ConfigSource{TierBuiltIn, "", config.Loop.LogLevel}
 	p["loop.log_timestamp_format"] = ConfigSource{TierBuiltIn, "", config.Loop.LogTimestampFormat}
 	p["loop.show_ai_output"] = ConfigSource{TierBuiltIn, "", config.Loop.ShowAIOutput}
+	p["loop.idle_timeout_failure"] = ConfigSource{TierBuiltIn, "", config.Loop.IdleTimeoutFailure}
+	p["loop.adaptive_timeout"] = ConfigSource{TierBuiltIn, "", config.Loop.AdaptiveTimeout}
+	p["loop.retry_backoff"] = ConfigSource{TierBuiltIn, "", config.Loop.RetryBackoff}
+	p["loop.retry_backoff_max"] = ConfigSource{TierBuiltIn, "", config.Loop.RetryBackoffMax}
+	p["loop.max_retries"] = ConfigSource{TierBuiltIn, "", config.Loop.MaxRetries}
 	for name, cmd := range config.AICmdAliases {
 		p["ai_cmd_aliases."+name] = ConfigSource{TierBuiltIn, "", cmd}
 	}
+	for name, proc := range config.Procedures {
+		p["procedures."+name] = ConfigSource{TierBuiltIn, "", proc}
+	}
 	return p
 }
 
 // configFile represents the YAML config file structure
 type configFile struct {
-	Loop struct {
-		IterationMode        string `yaml:"iteration_mode"`
-		DefaultMaxIterations *int   `yaml:"default_max_iterations"`
-		IterationTimeout     *int   `yaml:"iteration_timeout"`
-		MaxOutputBuffer      int    `yaml:"max_output_buffer"`
-		FailureThreshold     int    `yaml:"failure_threshold"`
-		LogLevel             string `yaml:"log_level"`
-		LogTimestampFormat   string `yaml:"log_timestamp_format"`
-		ShowAIOutput         bool   `yaml:"show_ai_output"`
-		AICmd                string `yaml:"ai_cmd"`
-		AICmdAlias           string `yaml:"ai_cmd_alias"`
-	} `yaml:"loop"`
-	AICmdAliases map[string]string            `yaml:"ai_cmd_aliases"`
-	Procedures   map[string]procedureYAML     `yaml:"procedures"`
+	Version      int                      `yaml:"version"` // Schema version, see ConfigVersion
+	Loop         loopYAML                 `yaml:"loop"`
+	AICmdAliases map[string]aliasYAML     `yaml:"ai_cmd_aliases"`
+	Procedures   map[string]procedureYAML `yaml:"procedures"`
+	Include      []string                 `yaml:"include"`  // Files or globs merged before this file, relative to it
+	Profiles     map[string]profileYAML   `yaml:"profiles"` // Overlays applied when selected with --profile or ROODA_PROFILE
+
+	positions      map[string]position      // Setting path -> position in the file
+	unknownKeys    ValidationErrors         // Keys the loader ignores, likely typos
+	deprecations   ValidationErrors         // Values in forms older than ConfigVersion
+	interpolations map[string]Interpolation // Setting path -> values that contained ${...}
+}
+
+type loopYAML struct {
+	IterationMode        string              `yaml:"iteration_mode"`
+	DefaultMaxIterations *int                `yaml:"default_max_iterations"`
+	IterationTimeout     *timeoutValue       `yaml:"iteration_timeout"`
+	AdaptiveTimeout      adaptiveTimeoutYAML `yaml:"adaptive_timeout"`
+	IdleTimeout          *int                `yaml:"idle_timeout"`
+	IdleTimeoutFailure   *bool               `yaml:"idle_timeout_failure"`
+	ResourceLimits       resourceLimitsYAML  `yaml:"resource_limits"`
+	MaxOutputBuffer      *int                `yaml:"max_output_buffer"`
+	FailureThreshold     *int                `yaml:"failure_threshold"`
+	LogLevel             string              `yaml:"log_level"`
+	LogTimestampFormat   string              `yaml:"log_timestamp_format"`
+	ShowAIOutput         *bool               `yaml:"show_ai_output"`
+	AICmd                string              `yaml:"ai_cmd"`
+	AICmdAlias           string              `yaml:"ai_cmd_alias"`
+	RetryBackoff         *int                `yaml:"retry_backoff"`
+	RetryBackoffMax      *int                `yaml:"retry_backoff_max"`
+	MaxRetries           *int                `yaml:"max_retries"`
+}
+
+// profileYAML is a named overlay of loop, alias and procedure settings. It
+// keeps its YAML node and decodes only when selected, so a profile's
+// ${...} references need only resolve where the profile is used.
+type profileYAML struct {
+	Loop         loopYAML                 `yaml:"loop"`
+	AICmdAliases map[string]aliasYAML     `yaml:"ai_cmd_aliases"`
+	Procedures   map[string]procedureYAML `yaml:"procedures"`
+
+	node *yaml.Node
+}
+
+func (p *profileYAML) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: profile must be a mapping", value.Line)
+	}
+	p.node = value
+	return nil
+}
+
+// profileSource is one definition of a profile, in the file that defines it.
+type profileSource struct {
+	name string
+	file string
+	node *yaml.Node
 }
 
 type procedureYAML struct {
-	Display              string                   `yaml:"display"`
-	Summary              string                   `yaml:"summary"`
-	Description          string                   `yaml:"description"`
-	Observe              phaseFragments           `yaml:"observe"`
-	Orient               phaseFragments           `yaml:"orient"`
-	Decide               phaseFragments           `yaml:"decide"`
-	Act                  phaseFragments           `yaml:"act"`
-	IterationMode        string                   `yaml:"iteration_mode"`
-	DefaultMaxIterations *int                     `yaml:"default_max_iterations"`
-	IterationTimeout     *int                     `yaml:"iteration_timeout"`
-	MaxOutputBuffer      *int                     `yaml:"max_output_buffer"`
-	AICmd                string                   `yaml:"ai_cmd"`
-	AICmdAlias           string                   `yaml:"ai_cmd_alias"`
-}
-
-// phaseFragments handles both v0.1.0 string format and v2 array format
+	Display              string             `yaml:"display"`
+	Summary              string             `yaml:"summary"`
+	Description          string             `yaml:"description"`
+	Extends              string             `yaml:"extends"`
+	Observe              phaseYAML          `yaml:"observe"`
+	Orient               phaseYAML          `yaml:"orient"`
+	Decide               phaseYAML          `yaml:"decide"`
+	Act                  phaseYAML          `yaml:"act"`
+	IterationMode        string             `yaml:"iteration_mode"`
+	DefaultMaxIterations *int               `yaml:"default_max_iterations"`
+	IterationTimeout     *timeoutValue      `yaml:"iteration_timeout"`
+	IdleTimeout          *int               `yaml:"idle_timeout"`
+	ResourceLimits       resourceLimitsYAML `yaml:"resource_limits"`
+	MaxOutputBuffer      *int               `yaml:"max_output_buffer"`
+	AICmd                string             `yaml:"ai_cmd"`
+	AICmdAlias           string             `yaml:"ai_cmd_alias"`
+	Context              string             `yaml:"context"`
+}
+
+// phaseFragments handles both v0.1.0 string format and v2 array format. The
+// string format is deprecated: it loads with a warning (see legacyForms) and
+// rooda config migrate rewrites it.
 type phaseFragments []fragmentActionYAML
 
 func (p *phaseFragments) UnmarshalYAML(unmarshal func(interface{}) error) error {
@@ -221,28 +553,375 @@ func (p *phaseFragments) UnmarshalYAML(unmarshal func(interface{}) error) error
 	return nil
 }
 
+// phaseYAML handles a phase given as fragments, which replace the phase, or
+// as a mapping of operators that edit the phase inherited from an earlier
+// config file or from the procedure named by extends
+type phaseYAML struct {
+	Replace phaseFragments `yaml:"replace"`
+	Remove  []string       `yaml:"remove"`
+	Prepend phaseFragments `yaml:"prepend"`
+	Append  phaseFragments `yaml:"append"`
+}
+
+func (p *phaseYAML) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// Try plain fragments first; an empty list leaves the phase unchanged
+	var fragments phaseFragments
+	if err := unmarshal(&fragments); err == nil {
+		if len(fragments) > 0 {
+			*p = phaseYAML{Replace: fragments}
+		}
+		return nil
+	}
+
+	// Fall back to operator mapping (plain struct type avoids recursing into this method)
+	type phaseOperators phaseYAML
+	var ops phaseOperators
+	if err := unmarshal(&ops); err != nil {
+		return err
+	}
+	*p = phaseYAML(ops)
+	return nil
+}
+
+// isSet reports whether the phase was given at all.
+func (p phaseYAML) isSet() bool {
+	return p.Replace != nil || p.Remove != nil || p.Prepend != nil || p.Append != nil
+}
+
+// apply edits current in order: replace, remove, prepend, append. Returns the
+// indexes of remove entries that matched no fragment.
+func (p phaseYAML) apply(current []FragmentAction, configDir string) ([]FragmentAction, []int) {
+	result := current
+	if p.Replace != nil {
+		result = resolveFragmentPaths(configDir, p.Replace)
+	}
+
+	var missing []int
+	for i, path := range p.Remove {
+		resolved := resolveFragmentPaths(configDir, []fragmentActionYAML{{Path: path}})[0].Path
+		kept := make([]FragmentAction, 0, len(result))
+		for _, f := range result {
+			if f.Path != path && f.Path != resolved {
+				kept = append(kept, f)
+			}
+		}
+		if len(kept) == len(result) {
+			missing = append(missing, i)
+		}
+		result = kept
+	}
+
+	prepended := resolveFragmentPaths(configDir, p.Prepend)
+	appended := resolveFragmentPaths(configDir, p.Append)
+	edited := make([]FragmentAction, 0, len(prepended)+len(result)+len(appended))
+	edited = append(edited, prepended...)
+	edited = append(edited, result...)
+	edited = append(edited, appended...)
+	return edited, missing
+}
+
+// aliasYAML handles both the plain command string form and the mapping form
+// that adds execution options
+type aliasYAML struct {
+	Command     string                 `yaml:"command"`
+	Classifiers []outputClassifierYAML `yaml:"classifiers"`
+	TTY         bool                   `yaml:"tty"`
+	Session     *sessionAdapterYAML    `yaml:"session"`
+	Backend     string                 `yaml:"backend"`
+	BaseURL     string                 `yaml:"base_url"`
+	Model       string                 `yaml:"model"`
+	APIKeyEnv   string                 `yaml:"api_key_env"`
+}
+
+type sessionAdapterYAML struct {
+	IDPattern     string `yaml:"id_pattern"`
+	ResumeCommand string `yaml:"resume_command"`
+}
+
+func (a *aliasYAML) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// Try plain command string first
+	var command string
+	if err := unmarshal(&command); err == nil {
+		*a = aliasYAML{Command: command}
+		return nil
+	}
+
+	// Fall back to mapping form (plain struct type avoids recursing into this method)
+	type aliasMapping aliasYAML
+	var mapping aliasMapping
+	if err := unmarshal(&mapping); err != nil {
+		return err
+	}
+	*a = aliasYAML(mapping)
+	return nil
+}
+
+// options converts the mapping-form settings to AliasOptions.
+// Returns false for the plain string form.
+func (a aliasYAML) options() (AliasOptions, bool) {
+	if len(a.Classifiers) == 0 && !a.TTY && a.Session == nil && a.Backend == "" {
+		return AliasOptions{}, false
+	}
+	opts := AliasOptions{TTY: a.TTY, Backend: Backend(a.Backend)}
+	if opts.Backend == BackendHTTP {
+		opts.HTTP = &HTTPBackend{
+			BaseURL:   a.BaseURL,
+			Model:     a.Model,
+			APIKeyEnv: a.APIKeyEnv,
+		}
+	}
+	if a.Session != nil {
+		opts.Session = &SessionAdapter{
+			IDPattern:     a.Session.IDPattern,
+			ResumeCommand: a.Session.ResumeCommand,
+		}
+	}
+	for _, c := range a.Classifiers {
+		opts.Classifiers = append(opts.Classifiers, OutputClassifier{
+			Class:     ErrorClass(c.Class),
+			Pattern:   c.Pattern,
+			ExitCodes: c.ExitCodes,
+		})
+	}
+	return opts, true
+}
+
+type outputClassifierYAML struct {
+	Class     string `yaml:"class"`
+	Pattern   string `yaml:"pattern"`
+	ExitCodes []int  `yaml:"exit_codes"`
+}
+
+type resourceLimitsYAML struct {
+	MemoryMB     *int `yaml:"memory_mb"`
+	CPUSeconds   *int `yaml:"cpu_seconds"`
+	MaxProcesses *int `yaml:"max_processes"`
+	MaxOpenFiles *int `yaml:"max_open_files"`
+}
+
+// mergeInto overrides the limits set in r and reports whether any were set.
+func (r resourceLimitsYAML) mergeInto(limits *ResourceLimits) bool {
+	set := false
+	for _, f := range []struct {
+		src *int
+		dst **int
+	}{
+		{r.MemoryMB, &limits.MemoryMB},
+		{r.CPUSeconds, &limits.CPUSeconds},
+		{r.MaxProcesses, &limits.MaxProcesses},
+		{r.MaxOpenFiles, &limits.MaxOpenFiles},
+	} {
+		if f.src != nil {
+			*f.dst = f.src
+			set = true
+		}
+	}
+	return set
+}
+
+type adaptiveTimeoutYAML struct {
+	K          *float64 `yaml:"k"`
+	Floor      *int     `yaml:"floor"`
+	Ceiling    *int     `yaml:"ceiling"`
+	MinSamples *int     `yaml:"min_samples"`
+}
+
+// timeoutValue handles iteration_timeout as either a number of seconds or "auto"
+type timeoutValue struct {
+	Seconds *int
+	Auto    bool
+}
+
+func (t *timeoutValue) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var seconds int
+	if err := unmarshal(&seconds); err == nil {
+		t.Seconds = &seconds
+		return nil
+	}
+
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	if s != IterationTimeoutAuto {
+		return fmt.Errorf("invalid iteration_timeout %q, must be a number of seconds or %q", s, IterationTimeoutAuto)
+	}
+	t.Auto = true
+	return nil
+}
+
+// provenanceValue returns the value recorded in provenance for this timeout
+func (t *timeoutValue) provenanceValue() any {
+	if t.Auto {
+		return IterationTimeoutAuto
+	}
+	return *t.Seconds
+}
+
 type fragmentActionYAML struct {
 	Content    string                 `yaml:"content"`
 	Path       string                 `yaml:"path"`
 	Parameters map[string]interface{} `yaml:"parameters"`
 }
 
-// parseYAML parses a YAML config file
-func parseYAML(path string) (*configFile, error) {
-	data, err := os.ReadFile(path)
+// parseYAML parses the YAML config file at path, expanding ${...} references
+// first. lookup finds settings from files loaded earlier.
+func parseYAML(path string, data []byte, lookup func(path string) (string, bool)) (*configFile, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	interpolations, err := interpolate(&doc, path, lookup)
+	if err != nil {
+		return nil, err
+	}
+	deprecated, err := deprecations(&doc, path)
 	if err != nil {
 		return nil, err
 	}
-
 	var cf configFile
-	if err := yaml.Unmarshal(data, &cf); err != nil {
+	if err := doc.Decode(&cf); err != nil {
 		return nil, err
 	}
+	cf.positions = nodePositions(&doc)
+	cf.unknownKeys = unknownKeys(&doc, path)
+	cf.deprecations = deprecated
+	cf.interpolations = interpolations
 	return &cf, nil
 }
 
+// position is where a setting appears in a config file.
+type position struct {
+	line   int
+	column int
+}
+
+// filePositions records where each setting appears in one config file.
+type filePositions struct {
+	file           string
+	positions      map[string]position
+	interpolations map[string]Interpolation
+}
+
+// nodePositions maps setting paths (e.g. loop.failure_threshold,
+// ai_cmd_aliases.claude.classifiers[0]) to their position in the document.
+// Scalar values point at the value, mappings and sequences at their key.
+func nodePositions(doc *yaml.Node) map[string]position {
+	positions := make(map[string]position)
+	var walk func(n *yaml.Node, path string)
+	walk = func(n *yaml.Node, path string) {
+		switch n.Kind {
+		case yaml.DocumentNode:
+			for _, c := range n.Content {
+				walk(c, path)
+			}
+		case yaml.AliasNode:
+			walk(n.Alias, path)
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				key, value := n.Content[i], n.Content[i+1]
+				p := key.Value
+				if path != "" {
+					p = path + "." + key.Value
+				}
+				if value.Kind == yaml.ScalarNode {
+					positions[p] = position{value.Line, value.Column}
+				} else {
+					positions[p] = position{key.Line, key.Column}
+				}
+				walk(value, p)
+			}
+		case yaml.SequenceNode:
+			for i, c := range n.Content {
+				p := fmt.Sprintf("%s[%d]", path, i)
+				positions[p] = position{c.Line, c.Column}
+				walk(c, p)
+			}
+		}
+	}
+	walk(doc, "")
+	return positions
+}
+
+// locate fills in the file, line and column that set the value behind err.
+// The file comes from provenance of the setting or its nearest parent; values
+// from built-in defaults, env vars and CLI flags have no location.
+func (c *Config) locate(err *ValidationError) {
+	source, found := c.sourceOf(err.Path)
+	if !found || source.File == "" {
+		return
+	}
+	err.File = source.File
+
+	if pos, ok := c.positionIn(source.File, err.Path); ok {
+		err.Line, err.Column = pos.line, pos.column
+		return
+	}
+	// Procedures merge field by field, so the setting may come from an earlier file
+	for i := len(c.sources) - 1; i >= 0; i-- {
+		if pos, ok := c.sources[i].positions[err.Path]; ok {
+			err.File, err.Line, err.Column = c.sources[i].file, pos.line, pos.column
+			return
+		}
+	}
+	for path := parentPath(err.Path); path != ""; path = parentPath(path) {
+		if pos, ok := c.positionIn(source.File, path); ok {
+			err.Line, err.Column = pos.line, pos.column
+			return
+		}
+	}
+}
+
+// sourceOf returns the provenance of the setting at path or its nearest parent.
+func (c *Config) sourceOf(path string) (ConfigSource, bool) {
+	for ; path != ""; path = parentPath(path) {
+		if source, ok := c.Provenance[path]; ok {
+			return source, true
+		}
+	}
+	return ConfigSource{}, false
+}
+
+// Files returns the config files that were loaded, in load order.
+func (c *Config) Files() []string {
+	files := make([]string, len(c.sources))
+	for i, fp := range c.sources {
+		files[i] = fp.file
+	}
+	return files
+}
+
+// positionIn returns where path appears in the most recently loaded copy of file.
+func (c *Config) positionIn(file string, path string) (position, bool) {
+	for i := len(c.sources) - 1; i >= 0; i-- {
+		if c.sources[i].file == file {
+			pos, ok := c.sources[i].positions[path]
+			return pos, ok
+		}
+	}
+	return position{}, false
+}
+
+// parentPath drops the last element of a setting path: a.b[0] -> a.b -> a -> "".
+func parentPath(path string) string {
+	i := strings.LastIndexAny(path, ".[")
+	if i < 0 {
+		return ""
+	}
+	return path[:i]
+}
+
 // mergeConfig merges overlay config into base config
 func mergeConfig(base *Config, overlay *configFile, provenance map[string]ConfigSource, tier ConfigTier, filePath string, configDir string) {
+	if filePath != "" {
+		base.sources = append(base.sources, filePositions{filePath, overlay.positions, overlay.interpolations})
+	}
+	base.Warnings = append(base.Warnings, overlay.unknownKeys...)
+	base.Warnings = append(base.Warnings, overlay.deprecations...)
+	for name, profile := range overlay.Profiles {
+		base.profiles = append(base.profiles, profileSource{name, filePath, profile.node})
+	}
+
 	// Merge loop settings
 	if overlay.Loop.IterationMode != "" {
 		base.Loop.IterationMode = IterationMode(overlay.Loop.IterationMode)
@@ -253,16 +932,43 @@ func mergeConfig(base *Config, overlay *configFile, provenance map[string]Config
 		provenance["loop.default_max_iterations"] = ConfigSource{tier, filePath, *overlay.Loop.DefaultMaxIterations}
 	}
 	if overlay.Loop.IterationTimeout != nil {
-		base.Loop.IterationTimeout = overlay.Loop.IterationTimeout
-		provenance["loop.iteration_timeout"] = ConfigSource{tier, filePath, *overlay.Loop.IterationTimeout}
+		base.Loop.IterationTimeout = overlay.Loop.IterationTimeout.Seconds
+		base.Loop.IterationTimeoutAuto = overlay.Loop.IterationTimeout.Auto
+		provenance["loop.iteration_timeout"] = ConfigSource{tier, filePath, overlay.Loop.IterationTimeout.provenanceValue()}
+	}
+	if adaptive := overlay.Loop.AdaptiveTimeout; adaptive.K != nil || adaptive.Floor != nil || adaptive.Ceiling != nil || adaptive.MinSamples != nil {
+		if adaptive.K != nil {
+			base.Loop.AdaptiveTimeout.K = *adaptive.K
+		}
+		if adaptive.Floor != nil {
+			base.Loop.AdaptiveTimeout.Floor = *adaptive.Floor
+		}
+		if adaptive.Ceiling != nil {
+			base.Loop.AdaptiveTimeout.Ceiling = *adaptive.Ceiling
+		}
+		if adaptive.MinSamples != nil {
+			base.Loop.AdaptiveTimeout.MinSamples = *adaptive.MinSamples
+		}
+		provenance["loop.adaptive_timeout"] = ConfigSource{tier, filePath, base.Loop.AdaptiveTimeout}
+	}
+	if overlay.Loop.IdleTimeout != nil {
+		base.Loop.IdleTimeout = overlay.Loop.IdleTimeout
+		provenance["loop.idle_timeout"] = ConfigSource{tier, filePath, *overlay.Loop.IdleTimeout}
+	}
+	if overlay.Loop.IdleTimeoutFailure != nil {
+		base.Loop.IdleTimeoutFailure = *overlay.Loop.IdleTimeoutFailure
+		provenance["loop.idle_timeout_failure"] = ConfigSource{tier, filePath, *overlay.Loop.IdleTimeoutFailure}
 	}
-	if overlay.Loop.MaxOutputBuffer != 0 {
-		base.Loop.MaxOutputBuffer = overlay.Loop.MaxOutputBuffer
-		provenance["loop.max_output_buffer"] = ConfigSource{tier, filePath, overlay.Loop.MaxOutputBuffer}
+	if overlay.Loop.ResourceLimits.mergeInto(&base.Loop.ResourceLimits) {
+		provenance["loop.resource_limits"] = ConfigSource{tier, filePath, base.Loop.ResourceLimits}
 	}
-	if overlay.Loop.FailureThreshold != 0 {
-		base.Loop.FailureThreshold = overlay.Loop.FailureThreshold
-		provenance["loop.failure_threshold"] = ConfigSource{tier, filePath, overlay.Loop.FailureThreshold}
+	if overlay.Loop.MaxOutputBuffer != nil {
+		base.Loop.MaxOutputBuffer = *overlay.Loop.MaxOutputBuffer
+		provenance["loop.max_output_buffer"] = ConfigSource{tier, filePath, *overlay.Loop.MaxOutputBuffer}
+	}
+	if overlay.Loop.FailureThreshold != nil {
+		base.Loop.FailureThreshold = *overlay.Loop.FailureThreshold
+		provenance["loop.failure_threshold"] = ConfigSource{tier, filePath, *overlay.Loop.FailureThreshold}
 	}
 	if overlay.Loop.LogLevel != "" {
 		base.Loop.LogLevel = LogLevel(overlay.Loop.LogLevel)
@@ -272,9 +978,9 @@ func mergeConfig(base *Config, overlay *configFile, provenance map[string]Config
 		base.Loop.LogTimestampFormat = TimestampFormat(overlay.Loop.LogTimestampFormat)
 		provenance["loop.log_timestamp_format"] = ConfigSource{tier, filePath, overlay.Loop.LogTimestampFormat}
 	}
-	if overlay.Loop.ShowAIOutput {
-		base.Loop.ShowAIOutput = overlay.Loop.ShowAIOutput
-		provenance["loop.show_ai_output"] = ConfigSource{tier, filePath, overlay.Loop.ShowAIOutput}
+	if overlay.Loop.ShowAIOutput != nil {
+		base.Loop.ShowAIOutput = *overlay.Loop.ShowAIOutput
+		provenance["loop.show_ai_output"] = ConfigSource{tier, filePath, *overlay.Loop.ShowAIOutput}
 	}
 	if overlay.Loop.AICmd != "" {
 		base.Loop.AICmd = overlay.Loop.AICmd
@@ -284,59 +990,148 @@ func mergeConfig(base *Config, overlay *configFile, provenance map[string]Config
 		base.Loop.AICmdAlias = overlay.Loop.AICmdAlias
 		provenance["loop.ai_cmd_alias"] = ConfigSource{tier, filePath, overlay.Loop.AICmdAlias}
 	}
+	if overlay.Loop.RetryBackoff != nil {
+		base.Loop.RetryBackoff = *overlay.Loop.RetryBackoff
+		provenance["loop.retry_backoff"] = ConfigSource{tier, filePath, *overlay.Loop.RetryBackoff}
+	}
+	if overlay.Loop.RetryBackoffMax != nil {
+		base.Loop.RetryBackoffMax = *overlay.Loop.RetryBackoffMax
+		provenance["loop.retry_backoff_max"] = ConfigSource{tier, filePath, *overlay.Loop.RetryBackoffMax}
+	}
+	if overlay.Loop.MaxRetries != nil {
+		base.Loop.MaxRetries = *overlay.Loop.MaxRetries
+		provenance["loop.max_retries"] = ConfigSource{tier, filePath, *overlay.Loop.MaxRetries}
+	}
 
-	// Merge AI command aliases
-	for name, command := range overlay.AICmdAliases {
-		base.AICmdAliases[name] = command
-		provenance["ai_cmd_aliases."+name] = ConfigSource{tier, filePath, command}
+	// Merge AI command aliases (a redefined alias replaces its options too)
+	for name, alias := range overlay.AICmdAliases {
+		base.AICmdAliases[name] = alias.Command
+		provenance["ai_cmd_aliases."+name] = ConfigSource{tier, filePath, alias.Command}
+		if opts, ok := alias.options(); ok {
+			if base.AliasOptions == nil {
+				base.AliasOptions = make(map[string]AliasOptions)
+			}
+			base.AliasOptions[name] = opts
+		} else {
+			delete(base.AliasOptions, name)
+		}
 	}
 
-	// Merge procedures
-	for name, proc := range overlay.Procedures {
+	// Merge procedures, parents before the procedures in this file that extend them
+	order, cycles := procedureMergeOrder(overlay.Procedures)
+	for _, name := range order {
+		proc := overlay.Procedures[name]
 		baseProcedure, exists := base.Procedures[name]
 		if !exists {
 			baseProcedure = Procedure{}
 		}
+		// Fields merge individually, so each one set here records its own source
+		setBy := func(field string, value any) {
+			provenance["procedures."+name+"."+field] = ConfigSource{tier, filePath, value}
+		}
+		mergeError := func(path string, format string, args ...interface{}) {
+			base.mergeErrors = append(base.mergeErrors, ValidationError{
+				Path:    "procedures." + name + "." + path,
+				Message: fmt.Sprintf("procedure %q: ", name) + fmt.Sprintf(format, args...),
+			})
+		}
+
+		if proc.Extends != "" {
+			parent, ok := base.Procedures[proc.Extends]
+			switch {
+			case proc.Extends == name:
+				mergeError("extends", "cannot extend itself")
+			case cycles[name]:
+				mergeError("extends", "extends cycle through %q", proc.Extends)
+			case !ok:
+				mergeError("extends", "extends unknown procedure %q", proc.Extends)
+			default:
+				baseProcedure = inheritProcedure(provenance, name, proc.Extends, parent)
+				baseProcedure.Extends = proc.Extends
+			}
+			setBy("extends", proc.Extends)
+		}
 
 		// Merge fields
 		if proc.Display != "" {
 			baseProcedure.Display = proc.Display
+			setBy("display", proc.Display)
 		}
 		if proc.Summary != "" {
 			baseProcedure.Summary = proc.Summary
+			setBy("summary", proc.Summary)
 		}
 		if proc.Description != "" {
 			baseProcedure.Description = proc.Description
+			setBy("description", proc.Description)
 		}
-		if len(proc.Observe) > 0 {
-			baseProcedure.Observe = resolveFragmentPaths(configDir, proc.Observe)
+		if proc.Observe.isSet() {
+			var missing []int
+			baseProcedure.Observe, missing = proc.Observe.apply(baseProcedure.Observe, configDir)
+			setBy("observe", baseProcedure.Observe)
+			for _, i := range missing {
+				mergeError(fmt.Sprintf("observe.remove[%d]", i), "observe.remove: no fragment with path %q", proc.Observe.Remove[i])
+			}
 		}
-		if len(proc.Orient) > 0 {
-			baseProcedure.Orient = resolveFragmentPaths(configDir, proc.Orient)
+		if proc.Orient.isSet() {
+			var missing []int
+			baseProcedure.Orient, missing = proc.Orient.apply(baseProcedure.Orient, configDir)
+			setBy("orient", baseProcedure.Orient)
+			for _, i := range missing {
+				mergeError(fmt.Sprintf("orient.remove[%d]", i), "orient.remove: no fragment with path %q", proc.Orient.Remove[i])
+			}
 		}
-		if len(proc.Decide) > 0 {
-			baseProcedure.Decide = resolveFragmentPaths(configDir, proc.Decide)
+		if proc.Decide.isSet() {
+			var missing []int
+			baseProcedure.Decide, missing = proc.Decide.apply(baseProcedure.Decide, configDir)
+			setBy("decide", baseProcedure.Decide)
+			for _, i := range missing {
+				mergeError(fmt.Sprintf("decide.remove[%d]", i), "decide.remove: no fragment with path %q", proc.Decide.Remove[i])
+			}
 		}
-		if len(proc.Act) > 0 {
-			baseProcedure.Act = resolveFragmentPaths(configDir, proc.Act)
+		if proc.Act.isSet() {
+			var missing []int
+			baseProcedure.Act, missing = proc.Act.apply(baseProcedure.Act, configDir)
+			setBy("act", baseProcedure.Act)
+			for _, i := range missing {
+				mergeError(fmt.Sprintf("act.remove[%d]", i), "act.remove: no fragment with path %q", proc.Act.Remove[i])
+			}
 		}
 		if proc.IterationMode != "" {
 			baseProcedure.IterationMode = IterationMode(proc.IterationMode)
+			setBy("iteration_mode", proc.IterationMode)
+		}
+		if proc.Context != "" {
+			baseProcedure.Context = ContextMode(proc.Context)
+			setBy("context", proc.Context)
 		}
 		if proc.DefaultMaxIterations != nil {
 			baseProcedure.DefaultMaxIterations = proc.DefaultMaxIterations
+			setBy("default_max_iterations", *proc.DefaultMaxIterations)
 		}
 		if proc.IterationTimeout != nil {
-			baseProcedure.IterationTimeout = proc.IterationTimeout
+			baseProcedure.IterationTimeout = proc.IterationTimeout.Seconds
+			baseProcedure.IterationTimeoutAuto = proc.IterationTimeout.Auto
+			setBy("iteration_timeout", proc.IterationTimeout.provenanceValue())
+		}
+		if proc.IdleTimeout != nil {
+			baseProcedure.IdleTimeout = proc.IdleTimeout
+			setBy("idle_timeout", *proc.IdleTimeout)
+		}
+		if proc.ResourceLimits.mergeInto(&baseProcedure.ResourceLimits) {
+			setBy("resource_limits", baseProcedure.ResourceLimits)
 		}
 		if proc.MaxOutputBuffer != nil {
 			baseProcedure.MaxOutputBuffer = proc.MaxOutputBuffer
+			setBy("max_output_buffer", *proc.MaxOutputBuffer)
 		}
 		if proc.AICmd != "" {
 			baseProcedure.AICmd = proc.AICmd
+			setBy("ai_cmd", proc.AICmd)
 		}
 		if proc.AICmdAlias != "" {
 			baseProcedure.AICmdAlias = proc.AICmdAlias
+			setBy("ai_cmd_alias", proc.AICmdAlias)
 		}
 
 		base.Procedures[name] = baseProcedure
@@ -344,69 +1139,141 @@ func mergeConfig(base *Config, overlay *configFile, provenance map[string]Config
 	}
 }
 
-// resolveFragmentPaths resolves fragment paths relative to config directory
-func resolveFragmentPaths(configDir string, fragments []fragmentActionYAML) []FragmentAction {
-	resolved := make([]FragmentAction, len(fragments))
-	for i, frag := range fragments {
-		resolved[i] = FragmentAction{
-			Content:    frag.Content,
-			Path:       frag.Path,
-			Parameters: frag.Parameters,
+// applyPhaseFlags applies --observe/--orient/--decide/--act fragments to the
+// selected procedure. Unknown procedures are left for the caller to report.
+func applyPhaseFlags(config *Config, provenance map[string]ConfigSource, cliFlags CLIFlags) {
+	proc, ok := config.Procedures[cliFlags.ProcedureName]
+	if !ok {
+		return
+	}
+	for _, phase := range []struct {
+		name      string
+		values    []string
+		fragments *[]FragmentAction
+	}{
+		{"observe", cliFlags.ObserveFragments, &proc.Observe},
+		{"orient", cliFlags.OrientFragments, &proc.Orient},
+		{"decide", cliFlags.DecideFragments, &proc.Decide},
+		{"act", cliFlags.ActFragments, &proc.Act},
+	} {
+		if len(phase.values) == 0 {
+			continue
 		}
-		// Only resolve path if not builtin: and path is specified
-		if frag.Path != "" && !filepath.IsAbs(frag.Path) && frag.Path[:8] != "builtin:" {
-			resolved[i].Path = filepath.Join(configDir, frag.Path)
+		var fragments []FragmentAction
+		if cliFlags.PhaseMode == PhaseModeAppend {
+			fragments = append(fragments, *phase.fragments...)
 		}
+		for _, value := range phase.values {
+			fragments = append(fragments, flagFragment(value))
+		}
+		*phase.fragments = fragments
+		provenance["procedures."+cliFlags.ProcedureName+"."+phase.name] = ConfigSource{TierCLIFlag, "", fragments}
 	}
-	return resolved
+	config.Procedures[cliFlags.ProcedureName] = proc
 }
 
-// applyEnvVars applies environment variable overrides
-func applyEnvVars(config *Config, provenance map[string]ConfigSource) {
-	if v := os.Getenv("ROODA_LOOP_AI_CMD"); v != "" {
-		config.Loop.AICmd = v
-		provenance["loop.ai_cmd"] = ConfigSource{TierEnvVar, "", v}
+// flagFragment treats a phase flag value like --context: an existing file
+// (or builtin: fragment) is loaded by path, anything else is inline content.
+func flagFragment(value string) FragmentAction {
+	if strings.HasPrefix(value, "builtin:") || fileExists(value) {
+		return FragmentAction{Path: value}
 	}
-	if v := os.Getenv("ROODA_LOOP_AI_CMD_ALIAS"); v != "" {
-		config.Loop.AICmdAlias = v
-		provenance["loop.ai_cmd_alias"] = ConfigSource{TierEnvVar, "", v}
-	}
-	if v := os.Getenv("ROODA_LOOP_ITERATION_MODE"); v != "" {
-		config.Loop.IterationMode = IterationMode(v)
-		provenance["loop.iteration_mode"] = ConfigSource{TierEnvVar, "", v}
+	return FragmentAction{Content: value}
+}
+
+// procedureMergeOrder sorts a file's procedures so that each comes after the
+// procedure it extends when both are defined in the same file. Procedures in
+// an extends cycle are reported in cycles.
+func procedureMergeOrder(procedures map[string]procedureYAML) (order []string, cycles map[string]bool) {
+	names := make([]string, 0, len(procedures))
+	for name := range procedures {
+		names = append(names, name)
 	}
-	if v := os.Getenv("ROODA_LOOP_DEFAULT_MAX_ITERATIONS"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil {
-			config.Loop.DefaultMaxIterations = &n
-			provenance["loop.default_max_iterations"] = ConfigSource{TierEnvVar, "", n}
+	sort.Strings(names)
+
+	// A procedure is in a cycle when following extends leads back to it
+	cycles = make(map[string]bool)
+	for _, name := range names {
+		seen := make(map[string]bool)
+		for parent := procedures[name].Extends; !seen[parent]; parent = procedures[parent].Extends {
+			if parent == name {
+				cycles[name] = true
+				break
+			}
+			if _, ok := procedures[parent]; !ok {
+				break
+			}
+			seen[parent] = true
 		}
 	}
-	if v := os.Getenv("ROODA_LOOP_ITERATION_TIMEOUT"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil {
-			config.Loop.IterationTimeout = &n
-			provenance["loop.iteration_timeout"] = ConfigSource{TierEnvVar, "", n}
+
+	done := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		if done[name] {
+			return
 		}
+		done[name] = true
+		if parent := procedures[name].Extends; !cycles[name] {
+			if _, ok := procedures[parent]; ok {
+				visit(parent)
+			}
+		}
+		order = append(order, name)
+	}
+	for _, name := range names {
+		visit(name)
 	}
-	if v := os.Getenv("ROODA_LOOP_FAILURE_THRESHOLD"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil {
-			config.Loop.FailureThreshold = n
-			provenance["loop.failure_threshold"] = ConfigSource{TierEnvVar, "", n}
+	return order, cycles
+}
+
+// inheritProcedure returns a copy of parent to merge name's own fields onto,
+// moving the parent's per-field provenance to name. Display stays with the
+// parent, since it names that procedure.
+func inheritProcedure(provenance map[string]ConfigSource, name string, parentName string, parent Procedure) Procedure {
+	prefix := "procedures." + name + "."
+	parentPrefix := "procedures." + parentName + "."
+	inheritedSources := make(map[string]ConfigSource)
+	for path, source := range provenance {
+		if strings.HasPrefix(path, prefix) {
+			delete(provenance, path)
+		}
+		if strings.HasPrefix(path, parentPrefix) && path != parentPrefix+"display" {
+			inheritedSources[prefix+strings.TrimPrefix(path, parentPrefix)] = source
 		}
 	}
-	if v := os.Getenv("ROODA_LOOP_LOG_LEVEL"); v != "" {
-		config.Loop.LogLevel = LogLevel(v)
-		provenance["loop.log_level"] = ConfigSource{TierEnvVar, "", v}
+	// Phases set with the parent as a whole (e.g. built-ins) take its source
+	for _, phase := range []string{"observe", "orient", "decide", "act"} {
+		if _, ok := inheritedSources[prefix+phase]; !ok {
+			if source, ok := provenance["procedures."+parentName]; ok {
+				inheritedSources[prefix+phase] = source
+			}
+		}
 	}
-	if v := os.Getenv("ROODA_LOOP_LOG_TIMESTAMP_FORMAT"); v != "" {
-		config.Loop.LogTimestampFormat = TimestampFormat(v)
-		provenance["loop.log_timestamp_format"] = ConfigSource{TierEnvVar, "", v}
+	for path, source := range inheritedSources {
+		provenance[path] = source
 	}
-	if v := os.Getenv("ROODA_LOOP_SHOW_AI_OUTPUT"); v != "" {
-		if b, err := strconv.ParseBool(v); err == nil {
-			config.Loop.ShowAIOutput = b
-			provenance["loop.show_ai_output"] = ConfigSource{TierEnvVar, "", b}
+
+	inherited := parent
+	inherited.Display = ""
+	return inherited
+}
+
+// resolveFragmentPaths resolves fragment paths relative to config directory
+func resolveFragmentPaths(configDir string, fragments []fragmentActionYAML) []FragmentAction {
+	resolved := make([]FragmentAction, len(fragments))
+	for i, frag := range fragments {
+		resolved[i] = FragmentAction{
+			Content:    frag.Content,
+			Path:       frag.Path,
+			Parameters: frag.Parameters,
+		}
+		// Only resolve path if not builtin: and path is specified
+		if frag.Path != "" && !filepath.IsAbs(frag.Path) && !strings.HasPrefix(frag.Path, "builtin:") {
+			resolved[i].Path = filepath.Join(configDir, frag.Path)
 		}
 	}
+	return resolved
 }
 
 // fileExists checks if a file exists
@@ -414,3 +1281,32 @@ func fileExists(path string) bool {
 	_, err := os.Stat(path)
 	return err == nil
 }
+
+// fileExists checks if the config file at path exists or has contents pending.
+func (c *Config) fileExists(path string) bool {
+	if _, ok := c.pendingFile(path); ok {
+		return true
+	}
+	return fileExists(path)
+}
+
+// readFile reads the config file at path, or the contents pending for it, so
+// an edit can be checked before the file is written.
+func (c *Config) readFile(path string) ([]byte, error) {
+	if data, ok := c.pendingFile(path); ok {
+		return data, nil
+	}
+	return os.ReadFile(path)
+}
+
+func (c *Config) pendingFile(path string) ([]byte, bool) {
+	if len(c.pending) == 0 {
+		return nil, false
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, false
+	}
+	data, ok := c.pending[abs]
+	return data, ok
+}
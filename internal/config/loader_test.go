@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -149,6 +150,144 @@ func TestLoadConfigEnvVars(t *testing.T) {
 	}
 }
 
+// TestLoadConfigIdleTimeout verifies idle_timeout settings from file and env
+func TestLoadConfigIdleTimeout(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	defer os.Chdir(origDir)
+	os.Chdir(tmpDir)
+
+	configYAML := `loop:
+  idle_timeout: 120
+  idle_timeout_failure: false
+procedures:
+  build:
+    idle_timeout: 300
+`
+	os.WriteFile("rooda-config.yml", []byte(configYAML), 0644)
+
+	config, err := LoadConfig(CLIFlags{})
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if config.Loop.IdleTimeout == nil || *config.Loop.IdleTimeout != 120 {
+		t.Errorf("expected idle_timeout 120, got %v", config.Loop.IdleTimeout)
+	}
+	if config.Loop.IdleTimeoutFailure {
+		t.Error("expected idle_timeout_failure false from workspace config")
+	}
+	if p := config.Procedures["build"].IdleTimeout; p == nil || *p != 300 {
+		t.Errorf("expected procedure idle_timeout 300, got %v", p)
+	}
+
+	t.Setenv("ROODA_LOOP_IDLE_TIMEOUT", "45")
+	t.Setenv("ROODA_LOOP_IDLE_TIMEOUT_FAILURE", "true")
+	config, err = LoadConfig(CLIFlags{})
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if *config.Loop.IdleTimeout != 45 {
+		t.Errorf("expected idle_timeout 45 from env, got %d", *config.Loop.IdleTimeout)
+	}
+	if !config.Loop.IdleTimeoutFailure {
+		t.Error("expected idle_timeout_failure true from env")
+	}
+	if config.Provenance["loop.idle_timeout"].Tier != TierEnvVar {
+		t.Errorf("expected env provenance, got %s", config.Provenance["loop.idle_timeout"].Tier)
+	}
+}
+
+// TestLoadConfigIterationTimeoutAuto verifies iteration_timeout: auto and adaptive tuning
+func TestLoadConfigResourceLimits(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	defer os.Chdir(origDir)
+	os.Chdir(tmpDir)
+
+	configYAML := `loop:
+  resource_limits:
+    memory_mb: 4096
+    max_processes: 256
+procedures:
+  build:
+    resource_limits:
+      memory_mb: 8192
+      cpu_seconds: 600
+`
+	os.WriteFile("rooda-config.yml", []byte(configYAML), 0644)
+
+	config, err := LoadConfig(CLIFlags{})
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if got := config.Loop.ResourceLimits.String(); got != "memory_mb=4096 max_processes=256" {
+		t.Errorf("unexpected loop limits: %s", got)
+	}
+	if config.Provenance["loop.resource_limits"].Tier != TierWorkspace {
+		t.Errorf("expected workspace provenance, got %s", config.Provenance["loop.resource_limits"].Tier)
+	}
+
+	limits := ResolveResourceLimits(*config, "build")
+	if got := limits.String(); got != "memory_mb=8192 cpu_seconds=600 max_processes=256" {
+		t.Errorf("expected procedure limits to override per field, got %s", got)
+	}
+	if ResolveResourceLimits(*config, "missing").String() != "memory_mb=4096 max_processes=256" {
+		t.Error("expected loop limits for unknown procedure")
+	}
+}
+
+func TestLoadConfigIterationTimeoutAuto(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	defer os.Chdir(origDir)
+	os.Chdir(tmpDir)
+
+	configYAML := `loop:
+  iteration_timeout: auto
+  adaptive_timeout:
+    k: 2.5
+    ceiling: 1200
+procedures:
+  build:
+    iteration_timeout: 900
+  plan:
+    iteration_timeout: auto
+`
+	os.WriteFile("rooda-config.yml", []byte(configYAML), 0644)
+
+	config, err := LoadConfig(CLIFlags{})
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if !config.Loop.IterationTimeoutAuto || config.Loop.IterationTimeout != nil {
+		t.Errorf("expected loop iteration_timeout auto, got auto=%v timeout=%v", config.Loop.IterationTimeoutAuto, config.Loop.IterationTimeout)
+	}
+	adaptive := config.Loop.AdaptiveTimeout
+	if adaptive.K != 2.5 || adaptive.Ceiling != 1200 {
+		t.Errorf("expected k=2.5 ceiling=1200, got %+v", adaptive)
+	}
+	if adaptive.Floor != DefaultAdaptiveTimeoutFloor || adaptive.MinSamples != DefaultAdaptiveTimeoutMinSamples {
+		t.Errorf("expected unset adaptive fields to keep defaults, got %+v", adaptive)
+	}
+	if build := config.Procedures["build"]; build.IterationTimeoutAuto || build.IterationTimeout == nil || *build.IterationTimeout != 900 {
+		t.Errorf("expected build iteration_timeout 900, got %+v", build)
+	}
+	if !config.Procedures["plan"].IterationTimeoutAuto {
+		t.Error("expected plan iteration_timeout auto")
+	}
+	if config.Provenance["loop.iteration_timeout"].Value != IterationTimeoutAuto {
+		t.Errorf("expected provenance value auto, got %v", config.Provenance["loop.iteration_timeout"].Value)
+	}
+
+	os.WriteFile("rooda-config.yml", []byte("loop:\n  iteration_timeout: sometimes\n"), 0644)
+	if _, err := LoadConfig(CLIFlags{}); err == nil {
+		t.Error("expected error for invalid iteration_timeout string")
+	}
+}
+
 // TestLoadConfigCLIFlags verifies CLI flag overrides (highest precedence)
 func TestLoadConfigCLIFlags(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -189,6 +328,66 @@ func TestLoadConfigCLIFlags(t *testing.T) {
 	}
 }
 
+// TestLoadConfigPhaseFlags verifies --observe/--orient/--decide/--act overrides
+func TestLoadConfigPhaseFlags(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	defer os.Chdir(origDir)
+	os.Chdir(tmpDir)
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+
+	configYAML := `procedures:
+  custom:
+    observe:
+      - content: "config observe"
+    act:
+      - content: "config act"
+`
+	os.WriteFile("rooda-config.yml", []byte(configYAML), 0644)
+	os.WriteFile("observe.md", []byte("file observe"), 0644)
+
+	tests := []struct {
+		name        string
+		mode        PhaseMode
+		wantObserve []FragmentAction
+	}{
+		{"replace by default", "", []FragmentAction{{Path: "observe.md"}, {Content: "inline observe"}}},
+		{"append", PhaseModeAppend, []FragmentAction{{Content: "config observe"}, {Path: "observe.md"}, {Content: "inline observe"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := LoadConfig(CLIFlags{
+				ProcedureName:    "custom",
+				ObserveFragments: []string{"observe.md", "inline observe"},
+				PhaseMode:        tt.mode,
+			})
+			if err != nil {
+				t.Fatalf("LoadConfig failed: %v", err)
+			}
+			proc := config.Procedures["custom"]
+			if !reflect.DeepEqual(proc.Observe, tt.wantObserve) {
+				t.Errorf("observe = %+v, want %+v", proc.Observe, tt.wantObserve)
+			}
+			if len(proc.Act) != 1 || proc.Act[0].Content != "config act" {
+				t.Errorf("act should be unchanged, got %+v", proc.Act)
+			}
+			source := config.Provenance["procedures.custom.observe"]
+			if got := source.Describe("procedures.custom.observe"); got != "cli --observe" {
+				t.Errorf("Expected observe provenance cli --observe, got %q", got)
+			}
+		})
+	}
+
+	// Other procedures and commands without a procedure are unaffected
+	config, err := LoadConfig(CLIFlags{ObserveFragments: []string{"inline observe"}})
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if got := config.Procedures["custom"].Observe; len(got) != 1 || got[0].Content != "config observe" {
+		t.Errorf("Expected config observe without a procedure name, got %+v", got)
+	}
+}
+
 // TestPrecedenceOrder verifies full precedence chain
 func TestPrecedenceOrder(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -302,6 +501,524 @@ func TestMergeProcedures(t *testing.T) {
 	}
 }
 
+// TestMergeProceduresExtends verifies extends and per-phase operators
+func TestMergeProceduresExtends(t *testing.T) {
+	globalDir := t.TempDir()
+	t.Setenv("ROODA_CONFIG_HOME", globalDir)
+	globalYAML := `procedures:
+  base:
+    display: "Base"
+    summary: "Base procedure"
+    observe:
+      - path: "builtin:fragments/observe/a.md"
+      - path: "builtin:fragments/observe/b.md"
+    act:
+      - path: "builtin:fragments/act/a.md"
+    default_max_iterations: 4
+`
+	if err := os.WriteFile(filepath.Join(globalDir, "rooda-config.yml"), []byte(globalYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	workspaceDir := t.TempDir()
+	workspacePath := filepath.Join(workspaceDir, "rooda-config.yml")
+	workspaceYAML := `procedures:
+  zz-team:
+    extends: base
+    observe:
+      remove: ["builtin:fragments/observe/b.md"]
+      prepend:
+        - content: "Read TEAM.md"
+      append:
+        - path: "extra.md"
+    act:
+      replace:
+        - path: "builtin:fragments/act/b.md"
+  aa-team-strict:
+    extends: zz-team
+    default_max_iterations: 2
+    observe:
+      remove: ["extra.md"]
+`
+	if err := os.WriteFile(workspacePath, []byte(workspaceYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfig(CLIFlags{ConfigPath: workspacePath})
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	paths := func(fragments []FragmentAction) []string {
+		var out []string
+		for _, f := range fragments {
+			if f.Path != "" {
+				out = append(out, f.Path)
+			} else {
+				out = append(out, "content:"+f.Content)
+			}
+		}
+		return out
+	}
+
+	team := config.Procedures["zz-team"]
+	wantObserve := []string{"content:Read TEAM.md", "builtin:fragments/observe/a.md", filepath.Join(workspaceDir, "extra.md")}
+	if got := paths(team.Observe); strings.Join(got, ",") != strings.Join(wantObserve, ",") {
+		t.Errorf("zz-team observe = %v, want %v", got, wantObserve)
+	}
+	if got := paths(team.Act); len(got) != 1 || got[0] != "builtin:fragments/act/b.md" {
+		t.Errorf("zz-team act = %v, want the replacement", got)
+	}
+	if team.Extends != "base" || team.Display != "" || team.Summary != "Base procedure" || *team.DefaultMaxIterations != 4 {
+		t.Errorf("zz-team did not inherit base settings: %+v", team)
+	}
+	if src := config.Provenance["procedures.zz-team.default_max_iterations"]; src.Tier != TierGlobal {
+		t.Errorf("Expected inherited default_max_iterations from global config, got %+v", src)
+	}
+
+	// Extends a procedure defined later in the same file
+	strict := config.Procedures["aa-team-strict"]
+	wantObserve = wantObserve[:2]
+	if got := paths(strict.Observe); strings.Join(got, ",") != strings.Join(wantObserve, ",") {
+		t.Errorf("aa-team-strict observe = %v, want %v", got, wantObserve)
+	}
+	if *strict.DefaultMaxIterations != 2 {
+		t.Errorf("Expected aa-team-strict default_max_iterations 2, got %d", *strict.DefaultMaxIterations)
+	}
+
+	// The parent is unchanged
+	if got := paths(config.Procedures["base"].Observe); len(got) != 2 {
+		t.Errorf("base observe changed: %v", got)
+	}
+}
+
+func TestMergeProceduresExtendsErrors(t *testing.T) {
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+	workspacePath := filepath.Join(t.TempDir(), "rooda-config.yml")
+	workspaceYAML := `procedures:
+  a:
+    extends: b
+  b:
+    extends: a
+  c:
+    extends: missing
+  d:
+    observe:
+      remove: ["nope.md"]
+`
+	if err := os.WriteFile(workspacePath, []byte(workspaceYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadConfig(CLIFlags{ConfigPath: workspacePath})
+	if err == nil {
+		t.Fatal("Expected errors for bad extends and remove")
+	}
+	for _, want := range []string{
+		workspacePath + `:3:14: procedure "a": extends cycle through "b"`,
+		workspacePath + `:5:14: procedure "b": extends cycle through "a"`,
+		workspacePath + `:7:14: procedure "c": extends unknown procedure "missing"`,
+		workspacePath + `:10:16: procedure "d": observe.remove: no fragment with path "nope.md"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error %q, got: %v", want, err)
+		}
+	}
+}
+
+// TestLoadConfigIncludes verifies include lists merge before the including file
+func TestLoadConfigIncludes(t *testing.T) {
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+	tmpDir := t.TempDir()
+	write := func(rel string, content string) string {
+		t.Helper()
+		path := filepath.Join(tmpDir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	sharedA := write("shared/a.yml", `procedures:
+  shared-a:
+    summary: "from a"
+    act:
+      - path: "fragments/act.md"
+`)
+	write("shared/b.yml", `include: ["../common/base.yml"]
+procedures:
+  shared-b:
+    summary: "from b"
+`)
+	base := write("common/base.yml", `loop:
+  failure_threshold: 7
+procedures:
+  shared-a:
+    summary: "from base"
+`)
+	workspacePath := write("rooda-config.yml", `include:
+  - "shared/*.yml"
+procedures:
+  shared-b:
+    summary: "from workspace"
+`)
+
+	config, err := LoadConfig(CLIFlags{ConfigPath: workspacePath})
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	// base.yml is included by b.yml, which the glob loads after a.yml
+	if got := config.Procedures["shared-a"]; got.Summary != "from base" || got.Act[0].Path != filepath.Join(tmpDir, "shared", "fragments", "act.md") {
+		t.Errorf("Expected shared-a summary from base.yml and act relative to shared/a.yml, got %+v", got)
+	}
+	if got := config.Procedures["shared-b"].Summary; got != "from workspace" {
+		t.Errorf("Expected including file to win, got %q", got)
+	}
+	if config.Loop.FailureThreshold != 7 {
+		t.Errorf("Expected nested include to apply, got failure_threshold %d", config.Loop.FailureThreshold)
+	}
+	if src := config.Provenance["procedures.shared-a.act"]; src.File != sharedA || src.Tier != TierWorkspace {
+		t.Errorf("Expected shared-a act from %s, got %+v", sharedA, src)
+	}
+	if src := config.Provenance["loop.failure_threshold"]; src.File != base {
+		t.Errorf("Expected failure_threshold from %s, got %+v", base, src)
+	}
+
+	// Load order: includes before the file that includes them
+	wantFiles := []string{sharedA, base, filepath.Join(tmpDir, "shared", "b.yml"), workspacePath}
+	if got := config.Files(); strings.Join(got, ",") != strings.Join(wantFiles, ",") {
+		t.Errorf("Files() = %v, want %v", got, wantFiles)
+	}
+}
+
+func TestLoadConfigIncludeErrors(t *testing.T) {
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+	tmpDir := t.TempDir()
+	cyclePath := filepath.Join(tmpDir, "cycle.yml")
+	os.WriteFile(cyclePath, []byte("include: [other.yml]\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "other.yml"), []byte("include: [cycle.yml]\n"), 0644)
+	missingPath := filepath.Join(tmpDir, "missing.yml")
+	os.WriteFile(missingPath, []byte("include: [nope.yml, \"none/*.yml\"]\n"), 0644)
+
+	_, err := LoadConfig(CLIFlags{ConfigPath: cyclePath})
+	if err == nil || !strings.Contains(err.Error(), "include cycle through") {
+		t.Errorf("Expected include cycle error, got %v", err)
+	}
+	_, err = LoadConfig(CLIFlags{ConfigPath: missingPath})
+	if err == nil || !strings.Contains(err.Error(), `include "nope.yml": file not found`) {
+		t.Errorf("Expected missing include error, got %v", err)
+	}
+}
+
+// TestLoadConfigDropIns verifies procedures.d and .rooda/procedures files
+func TestLoadConfigDropIns(t *testing.T) {
+	globalDir := t.TempDir()
+	t.Setenv("ROODA_CONFIG_HOME", globalDir)
+	workspaceDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	defer os.Chdir(origDir)
+	os.Chdir(workspaceDir)
+
+	os.MkdirAll(filepath.Join(globalDir, "procedures.d"), 0755)
+	os.WriteFile(filepath.Join(globalDir, "procedures.d", "org.yml"), []byte("procedures:\n  org:\n    summary: \"global drop-in\"\n"), 0644)
+	os.WriteFile("rooda-config.yml", []byte("procedures:\n  org:\n    summary: \"workspace\"\n  local:\n    summary: \"workspace\"\n"), 0644)
+	os.MkdirAll("procedures.d", 0755)
+	os.WriteFile(filepath.Join("procedures.d", "a.yml"), []byte("procedures:\n  local:\n    summary: \"procedures.d\"\n"), 0644)
+	os.WriteFile(filepath.Join("procedures.d", "notes.txt"), []byte("ignored"), 0644)
+	os.MkdirAll(".rooda/procedures", 0755)
+	os.WriteFile(filepath.Join(".rooda", "procedures", "z.yml"), []byte("procedures:\n  team:\n    summary: \".rooda\"\n"), 0644)
+
+	config, err := LoadConfig(CLIFlags{})
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	for name, want := range map[string]string{"org": "workspace", "local": "procedures.d", "team": ".rooda"} {
+		if got := config.Procedures[name].Summary; got != want {
+			t.Errorf("procedure %s summary = %q, want %q", name, got, want)
+		}
+	}
+	if src := config.Provenance["procedures.team.summary"]; src.File != filepath.Join(".rooda", "procedures", "z.yml") || src.Tier != TierWorkspace {
+		t.Errorf("Unexpected provenance for team: %+v", src)
+	}
+	if got := len(config.Files()); got != 4 {
+		t.Errorf("Expected 4 files loaded, got %v", config.Files())
+	}
+}
+
+// TestLoadConfigPacks verifies installed pack procedures load before config files
+func TestLoadConfigPacks(t *testing.T) {
+	globalDir := t.TempDir()
+	t.Setenv("ROODA_CONFIG_HOME", globalDir)
+	workspaceDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	defer os.Chdir(origDir)
+	os.Chdir(workspaceDir)
+
+	packProcedures := filepath.Join(globalDir, "packs", "team", "procedures")
+	os.MkdirAll(packProcedures, 0755)
+	os.WriteFile(filepath.Join(packProcedures, "review.yml"), []byte(`procedures:
+  review:
+    summary: "from pack"
+    ai_cmd_alias: claude
+    act:
+      - path: ../fragments/review.md
+`), 0644)
+	os.WriteFile(filepath.Join(globalDir, "rooda-config.yml"), []byte("procedures:\n  review:\n    summary: \"from global config\"\n"), 0644)
+
+	config, err := LoadConfig(CLIFlags{})
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	review := config.Procedures["review"]
+	if review.Summary != "from global config" || review.AICmdAlias != "claude" {
+		t.Errorf("Expected config file to override pack, got %+v", review)
+	}
+	if want := filepath.Join(globalDir, "packs", "team", "fragments", "review.md"); review.Act[0].Path != want {
+		t.Errorf("Expected fragment path %s, got %s", want, review.Act[0].Path)
+	}
+	if src := config.Provenance["procedures.review.ai_cmd_alias"]; src.File != filepath.Join(packProcedures, "review.yml") {
+		t.Errorf("Expected ai_cmd_alias from pack file, got %+v", src)
+	}
+}
+
+// TestLoadConfigDiscovery verifies rooda-config.yml files from the git root
+// down to the current directory merge outermost first
+func TestLoadConfigDiscovery(t *testing.T) {
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+	repoDir := t.TempDir()
+	serviceDir := filepath.Join(repoDir, "services", "api")
+	os.MkdirAll(filepath.Join(repoDir, ".git"), 0755)
+	os.MkdirAll(filepath.Join(serviceDir, "procedures.d"), 0755)
+	os.WriteFile(filepath.Join(repoDir, "rooda-config.yml"), []byte(`loop:
+  ai_cmd_alias: "claude"
+  default_max_iterations: 5
+procedures:
+  org:
+    summary: "repo root"
+    observe:
+      - path: fragments/org.md
+`), 0644)
+	os.WriteFile(filepath.Join(serviceDir, "rooda-config.yml"), []byte("loop:\n  default_max_iterations: 2\n"), 0644)
+	os.WriteFile(filepath.Join(serviceDir, "procedures.d", "api.yml"), []byte("procedures:\n  api:\n    summary: \"service\"\n"), 0644)
+
+	origDir, _ := os.Getwd()
+	defer os.Chdir(origDir)
+	os.Chdir(serviceDir)
+
+	config, err := LoadConfig(CLIFlags{})
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if config.Loop.DefaultMaxIterations == nil || *config.Loop.DefaultMaxIterations != 2 {
+		t.Errorf("Expected the innermost file to override, got %v", config.Loop.DefaultMaxIterations)
+	}
+	if config.Loop.AICmdAlias != "claude" {
+		t.Errorf("Expected ai_cmd_alias from the repo root file, got %q", config.Loop.AICmdAlias)
+	}
+	outer := filepath.Join("..", "..", "rooda-config.yml")
+	if src := config.Provenance["loop.ai_cmd_alias"]; src.Tier != TierWorkspace || src.File != outer {
+		t.Errorf("Expected provenance %s, got %+v", outer, src)
+	}
+	if got, want := config.Procedures["org"].Observe[0].Path, filepath.Join("..", "..", "fragments", "org.md"); got != want {
+		t.Errorf("Expected fragment path relative to the repo root file %s, got %s", want, got)
+	}
+	if config.Procedures["api"].Summary != "service" {
+		t.Error("Expected the service's procedures.d to load")
+	}
+	if root := WorkspaceRoot(true); root != filepath.Join("..", "..") {
+		t.Errorf("Expected the workspace root at the repo root, got %s", root)
+	}
+	if root := WorkspaceRoot(false); root != "." {
+		t.Errorf("Expected the current directory as the workspace root with NoDiscover, got %s", root)
+	}
+
+	config, err = LoadConfig(CLIFlags{NoDiscover: true})
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if config.Loop.AICmdAlias == "claude" || len(config.Files()) != 2 {
+		t.Errorf("Expected only the local files with NoDiscover, got %v", config.Files())
+	}
+}
+
+// TestLoadConfigProfiles verifies a selected profile overlays the config
+// files, merges definitions from every tier, and yields to env vars
+func TestLoadConfigProfiles(t *testing.T) {
+	globalDir := t.TempDir()
+	t.Setenv("ROODA_CONFIG_HOME", globalDir)
+	t.Setenv("ROODA_PROFILE", "")
+	globalPath := filepath.Join(globalDir, "rooda-config.yml")
+	os.WriteFile(globalPath, []byte(`profiles:
+  ci:
+    loop:
+      log_timestamp_format: iso
+      failure_threshold: 9
+`), 0644)
+	workspacePath := filepath.Join(t.TempDir(), "rooda-config.yml")
+	os.WriteFile(workspacePath, []byte(`loop:
+  default_max_iterations: 5
+  failure_threshold: 2
+procedures:
+  build:
+    summary: "Build"
+    act:
+      - content: "Build it"
+profiles:
+  ci:
+    loop:
+      iteration_mode: unlimited
+      iteration_timeout: 600
+      ai_cmd_alias: "${ROODA_TEST_CI_ALIAS}"
+    procedures:
+      build:
+        summary: "CI build"
+  local:
+    loop:
+      ai_cmd: "${ROODA_TEST_UNSET_IN_LOCAL}"
+`), 0644)
+
+	// Without a profile, profile settings are ignored and their references unresolved
+	config, err := LoadConfig(CLIFlags{ConfigPath: workspacePath})
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if config.Profile != "" || config.Loop.IterationMode != ModeMaxIterations || config.Loop.FailureThreshold != 2 {
+		t.Errorf("Expected no profile applied, got profile %q, %+v", config.Profile, config.Loop)
+	}
+
+	t.Setenv("ROODA_TEST_CI_ALIAS", "claude")
+	t.Setenv("ROODA_LOOP_LOG_TIMESTAMP_FORMAT", "none")
+	config, err = LoadConfig(CLIFlags{ConfigPath: workspacePath, Profile: "ci"})
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if config.Profile != "ci" {
+		t.Errorf("Expected profile ci, got %q", config.Profile)
+	}
+	if config.Loop.IterationMode != ModeUnlimited || config.Loop.IterationTimeout == nil || *config.Loop.IterationTimeout != 600 {
+		t.Errorf("Expected workspace profile loop settings, got %+v", config.Loop)
+	}
+	if config.Loop.AICmdAlias != "claude" || config.Loop.FailureThreshold != 9 {
+		t.Errorf("Expected global and workspace profile settings, got %+v", config.Loop)
+	}
+	if config.Loop.LogTimestampFormat != TimestampNone {
+		t.Errorf("Expected env var to override profile, got %q", config.Loop.LogTimestampFormat)
+	}
+	if config.Procedures["build"].Summary != "CI build" || len(config.Procedures["build"].Act) == 0 {
+		t.Errorf("Expected profile to merge into build procedure, got %+v", config.Procedures["build"])
+	}
+	for path, want := range map[string]ConfigSource{
+		"loop.iteration_mode":         {TierProfile, workspacePath, "unlimited"},
+		"loop.failure_threshold":      {TierProfile, globalPath, 9},
+		"loop.default_max_iterations": {TierWorkspace, workspacePath, 5},
+	} {
+		if got := config.Provenance[path]; !reflect.DeepEqual(got, want) {
+			t.Errorf("Provenance[%s] = %+v, want %+v", path, got, want)
+		}
+	}
+
+	// ROODA_PROFILE selects a profile when the flag is not given
+	t.Setenv("ROODA_PROFILE", "ci")
+	config, err = LoadConfig(CLIFlags{ConfigPath: workspacePath})
+	if err != nil || config.Profile != "ci" {
+		t.Errorf("Expected ROODA_PROFILE to select ci, got %v, %v", config, err)
+	}
+
+	_, err = LoadConfig(CLIFlags{ConfigPath: workspacePath, Profile: "nope"})
+	if err == nil || err.Error() != `unknown profile "nope", available: ci, local` {
+		t.Errorf("Expected unknown profile error, got %v", err)
+	}
+	_, err = LoadConfig(CLIFlags{ConfigPath: workspacePath, Profile: "local"})
+	if err == nil || !strings.Contains(err.Error(), "ROODA_TEST_UNSET_IN_LOCAL is not set") {
+		t.Errorf("Expected selected profile's references to resolve, got %v", err)
+	}
+}
+
+// TestMergeAliasMappingForm verifies mapping-form aliases with classifiers
+func TestMergeAliasMappingForm(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	defer os.Chdir(origDir)
+	os.Chdir(tmpDir)
+
+	configYAML := `ai_cmd_aliases:
+  plain: "plain-ai --flag"
+  claude:
+    command: "claude -p"
+    classifiers:
+      - class: rate_limited
+        pattern: "(?i)rate limit"
+      - class: auth_error
+        exit_codes: [77]
+  term:
+    command: "term-ai"
+    tty: true
+  resumable:
+    command: "resumable-ai --json"
+    session:
+      id_pattern: '"session_id":"([^"]+)"'
+      resume_command: "resumable-ai --json --resume {session_id}"
+  local:
+    backend: http
+    base_url: http://localhost:8080/v1
+    model: qwen2.5-coder
+    api_key_env: LOCAL_API_KEY
+procedures:
+  refactor:
+    context: continue-until-failure
+loop:
+  retry_backoff: 2
+  max_retries: 4
+`
+	os.WriteFile("rooda-config.yml", []byte(configYAML), 0644)
+
+	config, err := LoadConfig(CLIFlags{})
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if config.AICmdAliases["claude"] != "claude -p" {
+		t.Errorf("expected mapping-form command, got %q", config.AICmdAliases["claude"])
+	}
+	if config.AICmdAliases["plain"] != "plain-ai --flag" {
+		t.Errorf("expected string-form command, got %q", config.AICmdAliases["plain"])
+	}
+	opts := config.AliasOptions["claude"]
+	if len(opts.Classifiers) != 2 || opts.Classifiers[0].Class != ClassRateLimited || opts.Classifiers[1].ExitCodes[0] != 77 {
+		t.Errorf("unexpected classifiers: %+v", opts.Classifiers)
+	}
+	if !config.AliasOptions["term"].TTY || opts.TTY {
+		t.Errorf("expected tty only on term alias, got term=%+v claude=%+v", config.AliasOptions["term"], opts)
+	}
+	if s := config.AliasOptions["resumable"].Session; s == nil || s.ResumeCommand != "resumable-ai --json --resume {session_id}" {
+		t.Errorf("unexpected session adapter: %+v", s)
+	}
+	if local := config.AliasOptions["local"]; local.Backend != BackendHTTP || local.HTTP == nil || local.HTTP.Model != "qwen2.5-coder" || local.HTTP.APIKeyEnv != "LOCAL_API_KEY" {
+		t.Errorf("unexpected http backend options: %+v", local)
+	}
+	if config.Procedures["refactor"].Context != ContextContinueUntilFailure {
+		t.Errorf("expected procedure context, got %q", config.Procedures["refactor"].Context)
+	}
+	if _, ok := config.AliasOptions["plain"]; ok {
+		t.Error("expected no options for string-form alias")
+	}
+	if config.Loop.RetryBackoff != 2 || config.Loop.MaxRetries != 4 || config.Loop.RetryBackoffMax != DefaultRetryBackoffMax {
+		t.Errorf("unexpected retry settings: backoff=%d max=%d retries=%d", config.Loop.RetryBackoff, config.Loop.RetryBackoffMax, config.Loop.MaxRetries)
+	}
+
+	cmd, err := ResolveAICommand(*config, "build", CLIFlags{AICmdAlias: "claude"})
+	if err != nil {
+		t.Fatalf("ResolveAICommand failed: %v", err)
+	}
+	if len(cmd.Options.Classifiers) != 2 {
+		t.Errorf("expected resolved command to carry classifiers, got %+v", cmd.Options)
+	}
+}
+
 // TestResolveGlobalConfigDir verifies global config directory resolution
 func TestResolveGlobalConfigDir(t *testing.T) {
 	// Test ROODA_CONFIG_HOME
@@ -0,0 +1,318 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigVersion is the config file schema version this rooda reads and
+// writes, set with the top-level version key. Version 1 is the v0.1.0
+// format, which gave a phase as a single fragment path; version 2 gives
+// phases as lists of fragments. Files without a version are read as the
+// current version.
+const ConfigVersion = 2
+
+// phaseKeys are the procedure keys holding phase fragments.
+var phaseKeys = []string{"observe", "orient", "decide", "act"}
+
+// legacyForm is a value written in a form older than ConfigVersion.
+type legacyForm struct {
+	path    string     // Setting path, e.g. procedures.build.observe
+	keys    []string   // Keys of path in the file
+	node    *yaml.Node // The value
+	message string
+}
+
+// legacyForms finds values in doc written in a deprecated form: an old
+// version number, and phases (or phase operators) given as a single path.
+func legacyForms(doc *yaml.Node) []legacyForm {
+	root := doc
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+	var forms []legacyForm
+	if v := mappingValue(root, "version"); v != nil {
+		if n, err := strconv.Atoi(v.Value); err == nil && n < ConfigVersion {
+			forms = append(forms, legacyForm{"version", []string{"version"}, v, fmt.Sprintf("config version %d is deprecated, the current version is %d", n, ConfigVersion)})
+		}
+	}
+
+	procedures := func(n *yaml.Node, keys []string) {
+		if n == nil || n.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			proc := n.Content[i+1]
+			procKeys := append(keys[:len(keys):len(keys)], n.Content[i].Value)
+			for _, phase := range phaseKeys {
+				v := mappingValue(proc, phase)
+				if v == nil {
+					continue
+				}
+				phaseKeys := append(procKeys[:len(procKeys):len(procKeys)], phase)
+				if v.Kind == yaml.ScalarNode && v.Tag != "!!null" {
+					forms = append(forms, legacyPhase(phaseKeys, v))
+				}
+				for _, op := range []string{"replace", "prepend", "append"} {
+					if opValue := mappingValue(v, op); opValue != nil && opValue.Kind == yaml.ScalarNode && opValue.Tag != "!!null" {
+						forms = append(forms, legacyPhase(append(phaseKeys[:len(phaseKeys):len(phaseKeys)], op), opValue))
+					}
+				}
+			}
+		}
+	}
+	procedures(mappingValue(root, "procedures"), []string{"procedures"})
+	if profiles := mappingValue(root, "profiles"); profiles != nil && profiles.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(profiles.Content); i += 2 {
+			procedures(mappingValue(profiles.Content[i+1], "procedures"), []string{"profiles", profiles.Content[i].Value, "procedures"})
+		}
+	}
+	return forms
+}
+
+func legacyPhase(keys []string, n *yaml.Node) legacyForm {
+	path := strings.Join(keys, ".")
+	return legacyForm{path, keys, n, fmt.Sprintf("%s is a single fragment path, which is deprecated; use a list of fragments, e.g. [{path: %s}]", path, n.Value)}
+}
+
+// deprecations reports the legacy forms in doc as warnings, and a version
+// newer than this rooda understands as an error.
+func deprecations(doc *yaml.Node, file string) (ValidationErrors, error) {
+	root := doc
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+	if v := mappingValue(root, "version"); v != nil {
+		if n, err := strconv.Atoi(v.Value); err != nil || n < 1 || n > ConfigVersion {
+			msg := fmt.Sprintf("version must be a number from 1 to %d, got %q", ConfigVersion, v.Value)
+			if err == nil && n > ConfigVersion {
+				msg = fmt.Sprintf("config version %d is newer than this rooda supports (%d), upgrade rooda", n, ConfigVersion)
+			}
+			return nil, ValidationErrors{{Path: "version", Message: msg, File: file, Line: v.Line, Column: v.Column}}
+		}
+	}
+
+	var warnings ValidationErrors
+	for _, form := range legacyForms(doc) {
+		warnings = append(warnings, ValidationError{
+			Path:    form.path,
+			Message: form.message + ` (run "rooda config migrate")`,
+			File:    file,
+			Line:    form.node.Line,
+			Column:  form.node.Column,
+		})
+	}
+	return warnings, nil
+}
+
+// Migration is a config file rewritten to the current schema version.
+type Migration struct {
+	File    string
+	Before  []byte
+	After   []byte
+	Changes []string // One line per rewritten value
+}
+
+// Changed reports whether the migration rewrote anything.
+func (m *Migration) Changed() bool {
+	return len(m.Changes) > 0
+}
+
+// Diff returns the migration as a unified diff.
+func (m *Migration) Diff() string {
+	return unifiedDiff(m.File, string(m.Before), string(m.After))
+}
+
+// MigrateFile rewrites the config file at path to ConfigVersion without
+// writing it: single-path phases become fragment lists and the version key is
+// set. Only the lines of those values change, so comments, formatting and
+// ${...} templates are kept.
+func MigrateFile(path string) (*Migration, error) {
+	before, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := parseSource(path, before)
+	if err != nil {
+		return nil, err
+	}
+	root := s.root
+
+	m := &Migration{File: path, Before: before, After: before}
+	var edits []textEdit
+	for _, form := range legacyForms(&s.doc) {
+		if form.path == "version" {
+			continue // Set below
+		}
+		fragment := &yaml.Node{Kind: yaml.ScalarNode, Tag: form.node.Tag, Value: form.node.Value, Style: form.node.Style, LineComment: form.node.LineComment}
+		*form.node = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", HeadComment: form.node.HeadComment, Content: []*yaml.Node{{
+			Kind:    yaml.MappingNode,
+			Tag:     "!!map",
+			Content: []*yaml.Node{{Kind: yaml.ScalarNode, Tag: "!!str", Value: "path"}, fragment},
+		}}}
+		edits = append(edits, s.rewrite(form.keys))
+		m.Changes = append(m.Changes, fmt.Sprintf("%s: single path -> fragment list", form.path))
+	}
+
+	version := strconv.Itoa(ConfigVersion)
+	switch v := mappingValue(root, "version"); {
+	case v == nil:
+		// At the top, below any comment heading the file
+		key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"}
+		value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: version}
+		at := s.contentEnd()
+		if len(root.Content) > 0 {
+			at = root.Content[0].Line - 1
+		}
+		root.Content = append([]*yaml.Node{key, value}, root.Content...)
+		edits = append(edits, textEdit{start: at, end: at, key: key, value: value})
+		m.Changes = append(m.Changes, fmt.Sprintf("version: set to %s", version))
+	case v.Value != version:
+		m.Changes = append(m.Changes, fmt.Sprintf("version: %s -> %s", v.Value, version))
+		v.Kind, v.Tag, v.Value, v.Style = yaml.ScalarNode, "!!int", version, 0
+		edits = append(edits, s.rewrite([]string{"version"}))
+	}
+
+	if !m.Changed() {
+		return m, nil
+	}
+	after, err := s.apply(edits)
+	if err != nil {
+		return nil, err
+	}
+	m.After = after
+	return m, nil
+}
+
+// Write replaces the file with the migrated one, once it is known to load:
+// as part of the configuration flags selects, or on its own when it is not
+// one of those files.
+func (m *Migration) Write(flags CLIFlags) error {
+	abs, err := filepath.Abs(m.File)
+	if err != nil {
+		return err
+	}
+	flags.pending = map[string][]byte{abs: m.After}
+	config, err := LoadConfig(flags)
+	if err != nil {
+		return fmt.Errorf("migrated %s does not load: %w", m.File, err)
+	}
+	loaded := false
+	for _, file := range config.Files() {
+		if f, err := filepath.Abs(file); err == nil && f == abs {
+			loaded = true
+		}
+	}
+	if !loaded {
+		standalone := builtInDefaults()
+		standalone.pending = flags.pending
+		err := loadConfigFile(standalone, initProvenance(standalone), TierWorkspace, m.File, nil)
+		if err == nil {
+			err = validateSettings(standalone)
+		}
+		if err != nil {
+			return fmt.Errorf("migrated %s does not load: %w", m.File, err)
+		}
+	}
+	return writeFileAtomic(m.File, m.After)
+}
+
+// unifiedDiff returns the line differences between a and b as a unified diff
+// with three lines of context.
+func unifiedDiff(file string, a, b string) string {
+	x, y := diffLines(a), diffLines(b)
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type edit struct {
+		op   byte // ' ', '-' or '+'
+		line string
+		i, j int // Lines of a and b before this edit
+	}
+	var edits []edit
+	for i, j := 0, 0; i < len(x) || j < len(y); {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			edits = append(edits, edit{' ', x[i], i, j})
+			i, j = i+1, j+1
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, edit{'-', x[i], i, j})
+			i++
+		default:
+			edits = append(edits, edit{'+', y[j], i, j})
+			j++
+		}
+	}
+
+	const context = 3
+	var out strings.Builder
+	for start := 0; start < len(edits); {
+		// Find the next change, then extend the hunk while changes are close
+		first := start
+		for first < len(edits) && edits[first].op == ' ' {
+			first++
+		}
+		if first == len(edits) {
+			break
+		}
+		last := first
+		for k := first; k < len(edits) && k <= last+2*context; k++ {
+			if edits[k].op != ' ' {
+				last = k
+			}
+		}
+		from, to := max(first-context, start), min(last+context+1, len(edits))
+
+		aCount, bCount := 0, 0
+		for _, e := range edits[from:to] {
+			if e.op != '+' {
+				aCount++
+			}
+			if e.op != '-' {
+				bCount++
+			}
+		}
+		aStart, bStart := edits[from].i+1, edits[from].j+1
+		if aCount == 0 {
+			aStart--
+		}
+		if bCount == 0 {
+			bStart--
+		}
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", file, file)
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+		for _, e := range edits[from:to] {
+			fmt.Fprintf(&out, "%c%s\n", e.op, e.line)
+		}
+		start = to
+	}
+	return out.String()
+}
+
+func diffLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
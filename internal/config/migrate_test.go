@@ -0,0 +1,213 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const legacyConfig = `# Team config
+loop:
+  log_level: info
+procedures:
+  bootstrap:
+    observe: prompts/observe.md # main
+    orient:
+      - path: prompts/orient.md
+    decide:
+      append: prompts/decide.md
+    act: prompts/act.md
+`
+
+// TestLoadConfigDeprecations verifies legacy forms load with a located
+// warning each
+func TestLoadConfigDeprecations(t *testing.T) {
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+	configPath := filepath.Join(t.TempDir(), "rooda-config.yml")
+	os.WriteFile(configPath, []byte("version: 1\n"+legacyConfig), 0644)
+
+	config, err := LoadConfig(CLIFlags{ConfigPath: configPath})
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if got := config.Procedures["bootstrap"].Act; len(got) != 1 || !strings.HasSuffix(got[0].Path, "prompts/act.md") {
+		t.Errorf("Expected single path phase to load, got %+v", got)
+	}
+
+	var got []string
+	for _, w := range config.Warnings {
+		got = append(got, w.Error())
+	}
+	want := []string{
+		configPath + `:1:10: config version 1 is deprecated, the current version is 2 (run "rooda config migrate")`,
+		configPath + `:7:14: procedures.bootstrap.observe is a single fragment path, which is deprecated; use a list of fragments, e.g. [{path: prompts/observe.md}] (run "rooda config migrate")`,
+		configPath + `:11:15: procedures.bootstrap.decide.append is a single fragment path, which is deprecated; use a list of fragments, e.g. [{path: prompts/decide.md}] (run "rooda config migrate")`,
+		configPath + `:12:10: procedures.bootstrap.act is a single fragment path, which is deprecated; use a list of fragments, e.g. [{path: prompts/act.md}] (run "rooda config migrate")`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected warnings:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// TestLoadConfigVersionInvalid verifies versions this rooda cannot read are errors
+func TestLoadConfigVersionInvalid(t *testing.T) {
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+	configPath := filepath.Join(t.TempDir(), "rooda-config.yml")
+
+	for version, want := range map[string]string{
+		"3":   "config version 3 is newer than this rooda supports (2), upgrade rooda",
+		"0":   `version must be a number from 1 to 2, got "0"`,
+		"two": `version must be a number from 1 to 2, got "two"`,
+	} {
+		os.WriteFile(configPath, []byte("version: "+version+"\n"), 0644)
+		_, err := LoadConfig(CLIFlags{ConfigPath: configPath})
+		var verrs ValidationErrors
+		if !errors.As(err, &verrs) || len(verrs) != 1 || verrs[0].Line != 1 || verrs[0].Message != want {
+			t.Errorf("version %s: expected error %q, got %v", version, want, err)
+		}
+	}
+}
+
+// TestMigrateFile verifies legacy forms are rewritten with comments kept, and
+// that migrating again changes nothing
+func TestMigrateFile(t *testing.T) {
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+	configPath := filepath.Join(t.TempDir(), "rooda-config.yml")
+	os.WriteFile(configPath, []byte(legacyConfig), 0644)
+
+	m, err := MigrateFile(configPath)
+	if err != nil {
+		t.Fatalf("MigrateFile failed: %v", err)
+	}
+	want := `# Team config
+version: 2
+loop:
+  log_level: info
+procedures:
+  bootstrap:
+    observe:
+      - path: prompts/observe.md # main
+    orient:
+      - path: prompts/orient.md
+    decide:
+      append:
+        - path: prompts/decide.md
+    act:
+      - path: prompts/act.md
+`
+	if string(m.After) != want {
+		t.Errorf("Unexpected migrated file:\n%s\nwant:\n%s", m.After, want)
+	}
+	if len(m.Changes) != 4 || m.Changes[0] != "procedures.bootstrap.observe: single path -> fragment list" || m.Changes[3] != "version: set to 2" {
+		t.Errorf("Unexpected changes %v", m.Changes)
+	}
+	if data, _ := os.ReadFile(configPath); string(data) != legacyConfig {
+		t.Error("Expected MigrateFile not to write the file")
+	}
+
+	os.WriteFile(configPath, m.After, 0644)
+	config, err := LoadConfig(CLIFlags{ConfigPath: configPath})
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if len(config.Warnings) != 0 {
+		t.Errorf("Expected no deprecation warnings after migrating, got %v", config.Warnings)
+	}
+	if m, err = MigrateFile(configPath); err != nil || m.Changed() {
+		t.Errorf("Expected no changes on a migrated file, got %v %v", m.Changes, err)
+	}
+}
+
+// TestMigrateFileKeepsFormatting verifies the diff shows only the migrated
+// values, in the file's own indentation
+func TestMigrateFileKeepsFormatting(t *testing.T) {
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+	configPath := filepath.Join(t.TempDir(), "rooda-config.yml")
+	os.WriteFile(configPath, []byte(`loop:
+    default_max_iterations: 3   # keep low
+
+procedures:
+    build:
+        summary: "Build it"
+        observe: prompts/observe.md
+        orient:
+            - path: prompts/orient.md
+`), 0644)
+
+	m, err := MigrateFile(configPath)
+	if err != nil {
+		t.Fatalf("MigrateFile failed: %v", err)
+	}
+	var changed []string
+	for _, line := range strings.Split(m.Diff(), "\n") {
+		if (strings.HasPrefix(line, "-") || strings.HasPrefix(line, "+")) && !strings.HasPrefix(line, "---") && !strings.HasPrefix(line, "+++") {
+			changed = append(changed, line)
+		}
+	}
+	want := []string{
+		"+version: 2",
+		"-        observe: prompts/observe.md",
+		"+        observe:",
+		"+            - path: prompts/observe.md",
+	}
+	if strings.Join(changed, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected changed lines:\n%s\nwant:\n%s", strings.Join(changed, "\n"), strings.Join(want, "\n"))
+	}
+
+	if err := m.Write(CLIFlags{ConfigPath: configPath}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if data, _ := os.ReadFile(configPath); string(data) != string(m.After) {
+		t.Errorf("Expected the migrated file written, got:\n%s", data)
+	}
+}
+
+// TestMigrationWriteInvalid verifies a migrated file that does not load is
+// not written
+func TestMigrationWriteInvalid(t *testing.T) {
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+	configPath := filepath.Join(t.TempDir(), "other.yml")
+	original := "loop:\n  failure_threshold: 0\nprocedures:\n  build:\n    act: act.md\n"
+	os.WriteFile(configPath, []byte(original), 0644)
+
+	m, err := MigrateFile(configPath)
+	if err != nil {
+		t.Fatalf("MigrateFile failed: %v", err)
+	}
+	// Not part of the configuration the flags select, so it loads on its own
+	err = m.Write(CLIFlags{ConfigPath: filepath.Join(t.TempDir(), "missing.yml")})
+	if err == nil || !strings.Contains(err.Error(), "migrated "+configPath+" does not load") {
+		t.Errorf("Expected a load error, got %v", err)
+	}
+	if data, _ := os.ReadFile(configPath); string(data) != original {
+		t.Errorf("Expected the file unchanged, got:\n%s", data)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	b := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"
+	want := `--- f.yml
++++ f.yml
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -8,3 +8,4 @@
+ h
+ i
+ j
++k
+`
+	if got := unifiedDiff("f.yml", a, b); got != want {
+		t.Errorf("Unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+	if got := unifiedDiff("f.yml", a, a); got != "" {
+		t.Errorf("Expected no diff for equal input, got:\n%s", got)
+	}
+}
@@ -70,6 +70,11 @@ Set one via:
 Available aliases: %s`, strings.Join(aliases, ", "))
 }
 
+// ResolveAlias resolves a single AI command alias by name, independent of any procedure.
+func ResolveAlias(config Config, aliasName string) (AICommand, error) {
+	return resolveAlias(config, aliasName, "alias")
+}
+
 // resolveAlias resolves an alias name to a command string.
 func resolveAlias(config Config, aliasName string, source string) (AICommand, error) {
 	command, exists := config.AICmdAliases[aliasName]
@@ -87,5 +92,29 @@ func resolveAlias(config Config, aliasName string, source string) (AICommand, er
 	return AICommand{
 		Command: command,
 		Source:  fmt.Sprintf("%s=%s", source, aliasName),
+		Options: config.AliasOptions[aliasName],
 	}, nil
 }
+
+// ResolveResourceLimits returns the loop resource limits with any limits set
+// on the procedure taking precedence, field by field.
+func ResolveResourceLimits(config Config, procedureName string) ResourceLimits {
+	limits := config.Loop.ResourceLimits
+	proc, exists := config.Procedures[procedureName]
+	if !exists {
+		return limits
+	}
+	if proc.ResourceLimits.MemoryMB != nil {
+		limits.MemoryMB = proc.ResourceLimits.MemoryMB
+	}
+	if proc.ResourceLimits.CPUSeconds != nil {
+		limits.CPUSeconds = proc.ResourceLimits.CPUSeconds
+	}
+	if proc.ResourceLimits.MaxProcesses != nil {
+		limits.MaxProcesses = proc.ResourceLimits.MaxProcesses
+	}
+	if proc.ResourceLimits.MaxOpenFiles != nil {
+		limits.MaxOpenFiles = proc.ResourceLimits.MaxOpenFiles
+	}
+	return limits
+}
@@ -222,3 +222,22 @@ func TestResolveAICommand_AllBuiltinAliases(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveAlias(t *testing.T) {
+	config := Config{
+		AICmdAliases: map[string]string{"claude": "claude -p"},
+		AliasOptions: map[string]AliasOptions{"claude": {TTY: true}},
+	}
+
+	cmd, err := ResolveAlias(config, "claude")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if cmd.Command != "claude -p" || !cmd.Options.TTY || cmd.Source != "alias=claude" {
+		t.Errorf("unexpected command: %+v", cmd)
+	}
+
+	if _, err := ResolveAlias(config, "missing"); err == nil || !strings.Contains(err.Error(), "Available: claude") {
+		t.Errorf("expected unknown alias error listing aliases, got: %v", err)
+	}
+}
@@ -0,0 +1,267 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaID identifies the JSON Schema written by JSONSchema.
+const SchemaID = "https://github.com/jomadu/rooda/rooda-config.schema.json"
+
+// schemaEnums lists the allowed values of enum settings, by key.
+var schemaEnums = map[string][]string{
+	"iteration_mode":       {string(ModeMaxIterations), string(ModeUnlimited)},
+	"log_level":            {string(LogLevelDebug), string(LogLevelInfo), string(LogLevelWarn), string(LogLevelError)},
+	"log_timestamp_format": {string(TimestampTime), string(TimestampTimeMs), string(TimestampRelative), string(TimestampISO), string(TimestampNone)},
+	"context":              {string(ContextFresh), string(ContextContinue), string(ContextContinueUntilFailure)},
+	"backend":              {string(BackendSubprocess), string(BackendHTTP)},
+	"class":                {string(ClassRateLimited), string(ClassTransient), string(ClassAuthError)},
+}
+
+// JSONSchema returns a JSON Schema (draft-07) for rooda-config.yml, generated
+// from the structures the loader decodes, so editors can autocomplete and
+// check config files.
+func JSONSchema() ([]byte, error) {
+	schema := schemaFor(reflect.TypeOf(configFile{}), "")
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["$id"] = SchemaID
+	schema["title"] = "rooda configuration"
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// schemaFor describes values of type t found under key.
+func schemaFor(t reflect.Type, key string) map[string]interface{} {
+	switch t {
+	case reflect.TypeOf(phaseFragments{}):
+		return map[string]interface{}{"oneOf": []interface{}{
+			map[string]interface{}{"type": "string", "description": "Path to a single fragment file (deprecated, use a list)"},
+			map[string]interface{}{"type": "array", "items": schemaFor(reflect.TypeOf(fragmentActionYAML{}), "")},
+		}}
+	case reflect.TypeOf(phaseYAML{}):
+		fragments := schemaFor(reflect.TypeOf(phaseFragments{}), "")
+		operators := objectSchema(t)
+		operators["description"] = "Edits to the inherited phase, applied as replace, remove, prepend, append"
+		return map[string]interface{}{"oneOf": append(fragments["oneOf"].([]interface{}), operators)}
+	case reflect.TypeOf(aliasYAML{}):
+		return map[string]interface{}{"oneOf": []interface{}{
+			map[string]interface{}{"type": "string", "description": "Command string"},
+			objectSchema(t),
+		}}
+	case reflect.TypeOf(timeoutValue{}):
+		return map[string]interface{}{"oneOf": []interface{}{
+			map[string]interface{}{"type": "integer", "minimum": 1, "description": "Seconds"},
+			map[string]interface{}{"const": IterationTimeoutAuto},
+			interpolatedSchema(),
+		}}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaFor(t.Elem(), key)
+	case reflect.Struct:
+		return objectSchema(t)
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaFor(t.Elem(), "")}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": schemaFor(t.Elem(), "")}
+	case reflect.String:
+		s := map[string]interface{}{"type": "string"}
+		if values, ok := schemaEnums[key]; ok {
+			s["enum"] = values
+		}
+		return s
+	case reflect.Int:
+		if key == "version" {
+			return interpolable(map[string]interface{}{"type": "integer", "minimum": 1, "maximum": ConfigVersion, "description": "Config schema version"})
+		}
+		return interpolable(map[string]interface{}{"type": "integer"})
+	case reflect.Float64:
+		return interpolable(map[string]interface{}{"type": "number"})
+	case reflect.Bool:
+		return interpolable(map[string]interface{}{"type": "boolean"})
+	}
+	return map[string]interface{}{} // interface{}: any value
+}
+
+// interpolable also accepts a ${...} string for a number or bool setting, since
+// references are expanded before the value is decoded.
+func interpolable(s map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"oneOf": []interface{}{s, interpolatedSchema()}}
+}
+
+// interpolatedSchema matches a string with a ${...} reference.
+func interpolatedSchema() map[string]interface{} {
+	return map[string]interface{}{"type": "string", "pattern": `\$\{`, "description": "Expanded from ${...} references"}
+}
+
+func objectSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	for _, f := range yamlFields(t) {
+		properties[f.key] = schemaFor(f.typ, f.key)
+	}
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+type yamlField struct {
+	key string
+	typ reflect.Type
+}
+
+// yamlFields lists the keys a struct decodes, in declaration order.
+func yamlFields(t reflect.Type) []yamlField {
+	var fields []yamlField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		key := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if key == "-" {
+			continue
+		}
+		if key == "" {
+			key = strings.ToLower(f.Name)
+		}
+		fields = append(fields, yamlField{key, f.Type})
+	}
+	return fields
+}
+
+// unknownKeys reports mapping keys in doc that the loader would ignore,
+// suggesting the closest known key when one is near.
+func unknownKeys(doc *yaml.Node, file string) ValidationErrors {
+	var found ValidationErrors
+	var walk func(n *yaml.Node, t reflect.Type, path string)
+	walk = func(n *yaml.Node, t reflect.Type, path string) {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if n.Kind == yaml.DocumentNode {
+			for _, c := range n.Content {
+				walk(c, t, path)
+			}
+			return
+		}
+		if n.Kind == yaml.AliasNode {
+			walk(n.Alias, t, path)
+			return
+		}
+		if t == reflect.TypeOf(phaseYAML{}) && n.Kind != yaml.MappingNode {
+			t = reflect.TypeOf(phaseFragments{})
+		}
+
+		switch {
+		case t.Kind() == reflect.Struct && n.Kind == yaml.MappingNode:
+			fields := yamlFields(t)
+			known := make([]string, len(fields))
+			for i, f := range fields {
+				known[i] = f.key
+			}
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				key, value := n.Content[i], n.Content[i+1]
+				p := joinPath(path, key.Value)
+				var field *yamlField
+				for j := range fields {
+					if fields[j].key == key.Value {
+						field = &fields[j]
+						break
+					}
+				}
+				if field == nil {
+					found = append(found, unknownKeyError(file, key, p, path, known))
+					continue
+				}
+				walk(value, field.typ, p)
+			}
+		case t.Kind() == reflect.Map && n.Kind == yaml.MappingNode:
+			if t.Elem().Kind() == reflect.Interface {
+				return // free-form, e.g. fragment parameters
+			}
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				walk(n.Content[i+1], t.Elem(), joinPath(path, n.Content[i].Value))
+			}
+		case t.Kind() == reflect.Slice && n.Kind == yaml.SequenceNode:
+			for i, c := range n.Content {
+				walk(c, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	}
+	walk(doc, reflect.TypeOf(configFile{}), "")
+	return found
+}
+
+func unknownKeyError(file string, key *yaml.Node, path string, parent string, known []string) ValidationError {
+	msg := fmt.Sprintf("unknown key %q in %s", key.Value, parent)
+	if parent == "" {
+		msg = fmt.Sprintf("unknown top-level key %q", key.Value)
+	}
+	if suggestion := closestKey(key.Value, known); suggestion != "" {
+		msg += fmt.Sprintf(" (did you mean %q?)", suggestion)
+	} else {
+		sorted := append([]string(nil), known...)
+		sort.Strings(sorted)
+		msg += fmt.Sprintf(", expected one of: %s", strings.Join(sorted, ", "))
+	}
+	return ValidationError{Path: path, Message: msg, File: file, Line: key.Line, Column: key.Column}
+}
+
+func joinPath(parent string, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// closestKey returns the known key nearest to key by edit distance, or ""
+// when none is close enough to be a likely typo.
+func closestKey(key string, known []string) string {
+	best, bestDist := "", -1
+	for _, k := range known {
+		d := editDistance(strings.ToLower(key), k)
+		if bestDist < 0 || d < bestDist {
+			best, bestDist = k, d
+		}
+	}
+	limit := len(key) / 3
+	if limit < 2 {
+		limit = 2
+	}
+	if bestDist < 0 || bestDist > limit {
+		return ""
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig_UnknownKeys(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+	workspacePath := filepath.Join(tmpDir, "rooda-config.yml")
+	workspaceYAML := `loop:
+  iteraton_timeout: 30
+  log_level: info
+procedurs: {}
+procedures:
+  build:
+    act:
+      - content: "Do {{.x}}"
+        parameters:
+          anything_goes: true
+        paramters: {}
+ai_cmd_aliases:
+  plain: "plain-ai"
+  fast:
+    command: "fast-ai"
+    sesion: {}
+    zzzz: 1
+`
+	if err := os.WriteFile(workspacePath, []byte(workspaceYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfig(CLIFlags{ConfigPath: workspacePath})
+	if err != nil {
+		t.Fatalf("Expected unknown keys to be warnings, got error: %v", err)
+	}
+	var got []string
+	for _, w := range config.Warnings {
+		got = append(got, w.Error())
+	}
+	want := []string{
+		workspacePath + `:2:3: unknown key "iteraton_timeout" in loop (did you mean "iteration_timeout"?)`,
+		workspacePath + `:4:1: unknown top-level key "procedurs" (did you mean "procedures"?)`,
+		workspacePath + `:11:9: unknown key "paramters" in procedures.build.act[0] (did you mean "parameters"?)`,
+		workspacePath + `:16:5: unknown key "sesion" in ai_cmd_aliases.fast (did you mean "session"?)`,
+		workspacePath + `:17:5: unknown key "zzzz" in ai_cmd_aliases.fast, expected one of: api_key_env, backend, base_url, classifiers, command, model, session, tty`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected warnings:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestClosestKey(t *testing.T) {
+	known := []string{"iteration_timeout", "idle_timeout", "log_level"}
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"iteraton_timeout", "iteration_timeout"},
+		{"idle_timout", "idle_timeout"},
+		{"LOG_LEVEL", "log_level"},
+		{"loglevel", "log_level"},
+		{"color", ""},
+	}
+	for _, tt := range tests {
+		if got := closestKey(tt.key, known); got != tt.want {
+			t.Errorf("closestKey(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	data, err := JSONSchema()
+	if err != nil {
+		t.Fatalf("JSONSchema failed: %v", err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("Schema is not valid JSON: %v", err)
+	}
+	if schema["$schema"] != "http://json-schema.org/draft-07/schema#" || schema["additionalProperties"] != false {
+		t.Errorf("Unexpected schema header: %v", schema)
+	}
+
+	lookup := func(path ...string) map[string]interface{} {
+		t.Helper()
+		node := schema
+		for _, key := range path {
+			next, ok := node[key].(map[string]interface{})
+			if !ok {
+				t.Fatalf("Schema has no %s", strings.Join(path, "."))
+			}
+			node = next
+		}
+		return node
+	}
+
+	loop := lookup("properties", "loop", "properties")
+	for _, key := range []string{"iteration_timeout", "adaptive_timeout", "resource_limits", "failure_threshold", "max_retries"} {
+		if _, ok := loop[key]; !ok {
+			t.Errorf("Expected loop.%s in schema", key)
+		}
+	}
+	logLevel := lookup("properties", "loop", "properties", "log_level")
+	if enum, _ := logLevel["enum"].([]interface{}); len(enum) != 4 {
+		t.Errorf("Expected log_level enum, got %v", logLevel)
+	}
+	timeout := lookup("properties", "loop", "properties", "iteration_timeout")
+	if oneOf, _ := timeout["oneOf"].([]interface{}); len(oneOf) != 3 {
+		t.Errorf("Expected iteration_timeout to accept seconds, auto or a ${...} string, got %v", timeout)
+	}
+	for _, key := range []string{"default_max_iterations", "show_ai_output"} {
+		setting := lookup("properties", "loop", "properties", key)
+		oneOf, _ := setting["oneOf"].([]interface{})
+		if len(oneOf) != 2 || oneOf[1].(map[string]interface{})["pattern"] != `\$\{` {
+			t.Errorf("Expected loop.%s to also accept a ${...} string, got %v", key, setting)
+		}
+	}
+
+	version, _ := lookup("properties", "version")["oneOf"].([]interface{})
+	if len(version) != 2 || version[0].(map[string]interface{})["maximum"] != float64(ConfigVersion) {
+		t.Errorf("Expected version up to %d, got %v", ConfigVersion, version)
+	}
+
+	procedure := lookup("properties", "procedures", "additionalProperties", "properties")
+	for _, key := range []string{"observe", "act", "context", "ai_cmd_alias"} {
+		if _, ok := procedure[key]; !ok {
+			t.Errorf("Expected procedures.*.%s in schema", key)
+		}
+	}
+	phase := lookup("properties", "procedures", "additionalProperties", "properties", "act")
+	if oneOf, _ := phase["oneOf"].([]interface{}); len(oneOf) != 3 {
+		t.Errorf("Expected phase to accept a path, fragment list or operators, got %v", phase)
+	}
+}
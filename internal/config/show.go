@@ -0,0 +1,368 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats for MarshalConfig.
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
+// cliFlagNames maps settings that CLI flags override to the flag name.
+var cliFlagNames = map[string]string{
+	"loop.default_max_iterations": "--max-iterations",
+	"loop.ai_cmd":                 "--ai-cmd",
+	"loop.ai_cmd_alias":           "--ai-cmd-alias",
+}
+
+// Describe says where the value of the setting at path came from, e.g.
+// "workspace ./rooda-config.yml" or "env ROODA_LOOP_LOG_LEVEL".
+func (s ConfigSource) Describe(path string) string {
+	switch s.Tier {
+	case TierGlobal, TierWorkspace, TierProfile:
+		return fmt.Sprintf("%s %s", s.Tier, s.File)
+	case TierEnvVar:
+		return fmt.Sprintf("%s %s", s.Tier, EnvVarName(path))
+	case TierCLIFlag:
+		if flag, ok := cliFlagNames[path]; ok {
+			return fmt.Sprintf("%s %s", s.Tier, flag)
+		}
+		// Phase fragment flags, e.g. procedures.build.observe -> --observe
+		if strings.HasPrefix(path, "procedures.") {
+			switch phase := path[strings.LastIndex(path, ".")+1:]; phase {
+			case "observe", "orient", "decide", "act":
+				return fmt.Sprintf("%s --%s", s.Tier, phase)
+			}
+		}
+	}
+	return string(s.Tier)
+}
+
+// MarshalConfig renders the merged configuration in config file form as YAML
+// or JSON. With provenance, YAML values carry a comment naming their source
+// and any ${...} template they were expanded from, and JSON gains
+// "provenance" and "interpolations" objects keyed by setting path.
+func MarshalConfig(config *Config, format string, provenance bool) ([]byte, error) {
+	view := newConfigView(config)
+
+	switch format {
+	case FormatYAML:
+		var doc yaml.Node
+		if err := doc.Encode(view); err != nil {
+			return nil, err
+		}
+		if provenance {
+			interpolations := make(map[string]Interpolation)
+			for _, in := range config.Interpolations() {
+				interpolations[in.Path] = in
+			}
+			annotateProvenance(&doc, "", config.Provenance, interpolations)
+		}
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(&doc); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case FormatJSON:
+		var out interface{} = view
+		if provenance {
+			sources := make(map[string]sourceView, len(config.Provenance))
+			for path, src := range config.Provenance {
+				sources[path] = sourceView{Tier: src.Tier, File: src.File, Description: src.Describe(path)}
+			}
+			var interpolations map[string]interpolationView
+			for _, in := range config.Interpolations() {
+				if interpolations == nil {
+					interpolations = make(map[string]interpolationView)
+				}
+				interpolations[in.Path] = interpolationView{File: in.File, Template: in.Template, Value: in.Value, References: in.References}
+			}
+			out = struct {
+				configView
+				Provenance     map[string]sourceView        `json:"provenance"`
+				Interpolations map[string]interpolationView `json:"interpolations,omitempty"`
+			}{view, sources, interpolations}
+		}
+		data, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	}
+	return nil, fmt.Errorf("invalid format %q, must be one of: yaml, json", format)
+}
+
+// annotateProvenance adds a line comment with the source of every setting
+// path that has provenance, and the template of interpolated scalars.
+// Mapping values are annotated on their key.
+func annotateProvenance(n *yaml.Node, path string, provenance map[string]ConfigSource, interpolations map[string]Interpolation) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			annotateProvenance(c, path, provenance, interpolations)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			p := key.Value
+			if path != "" {
+				p = path + "." + key.Value
+			}
+			if src, ok := provenance[p]; ok {
+				if value.Kind == yaml.ScalarNode {
+					value.LineComment = src.Describe(p)
+				} else {
+					key.LineComment = src.Describe(p)
+				}
+			}
+			annotateInterpolation(value, p, interpolations)
+			annotateProvenance(value, p, provenance, interpolations)
+		}
+	case yaml.SequenceNode:
+		for i, c := range n.Content {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			annotateInterpolation(c, p, interpolations)
+			annotateProvenance(c, p, provenance, interpolations)
+		}
+	}
+}
+
+// annotateInterpolation appends the template a scalar was expanded from to
+// its line comment.
+func annotateInterpolation(n *yaml.Node, path string, interpolations map[string]Interpolation) {
+	in, ok := interpolations[path]
+	if !ok || n.Kind != yaml.ScalarNode {
+		return
+	}
+	note := fmt.Sprintf("from %q", in.Template)
+	if len(in.References) > 0 {
+		note += fmt.Sprintf(" (%s)", strings.Join(in.References, ", "))
+	}
+	if n.LineComment != "" {
+		note = n.LineComment + ", " + note
+	}
+	n.LineComment = note
+}
+
+type sourceView struct {
+	Tier        ConfigTier `json:"tier"`
+	File        string     `json:"file,omitempty"`
+	Description string     `json:"description"`
+}
+
+type interpolationView struct {
+	File       string   `json:"file"`
+	Template   string   `json:"template"`
+	Value      string   `json:"value"`
+	References []string `json:"references,omitempty"`
+}
+
+// configView mirrors the config file structure for output.
+type configView struct {
+	Loop         loopView                 `yaml:"loop" json:"loop"`
+	AICmdAliases map[string]interface{}   `yaml:"ai_cmd_aliases,omitempty" json:"ai_cmd_aliases,omitempty"`
+	Procedures   map[string]procedureView `yaml:"procedures,omitempty" json:"procedures,omitempty"`
+}
+
+type loopView struct {
+	IterationMode        IterationMode       `yaml:"iteration_mode" json:"iteration_mode"`
+	DefaultMaxIterations *int                `yaml:"default_max_iterations,omitempty" json:"default_max_iterations,omitempty"`
+	IterationTimeout     interface{}         `yaml:"iteration_timeout,omitempty" json:"iteration_timeout,omitempty"`
+	AdaptiveTimeout      adaptiveTimeoutView `yaml:"adaptive_timeout" json:"adaptive_timeout"`
+	IdleTimeout          *int                `yaml:"idle_timeout,omitempty" json:"idle_timeout,omitempty"`
+	IdleTimeoutFailure   bool                `yaml:"idle_timeout_failure" json:"idle_timeout_failure"`
+	ResourceLimits       *resourceLimitsView `yaml:"resource_limits,omitempty" json:"resource_limits,omitempty"`
+	MaxOutputBuffer      int                 `yaml:"max_output_buffer" json:"max_output_buffer"`
+	FailureThreshold     int                 `yaml:"failure_threshold" json:"failure_threshold"`
+	LogLevel             LogLevel            `yaml:"log_level" json:"log_level"`
+	LogTimestampFormat   TimestampFormat     `yaml:"log_timestamp_format" json:"log_timestamp_format"`
+	ShowAIOutput         bool                `yaml:"show_ai_output" json:"show_ai_output"`
+	AICmd                string              `yaml:"ai_cmd,omitempty" json:"ai_cmd,omitempty"`
+	AICmdAlias           string              `yaml:"ai_cmd_alias,omitempty" json:"ai_cmd_alias,omitempty"`
+	RetryBackoff         int                 `yaml:"retry_backoff" json:"retry_backoff"`
+	RetryBackoffMax      int                 `yaml:"retry_backoff_max" json:"retry_backoff_max"`
+	MaxRetries           int                 `yaml:"max_retries" json:"max_retries"`
+}
+
+type adaptiveTimeoutView struct {
+	K          float64 `yaml:"k" json:"k"`
+	Floor      int     `yaml:"floor" json:"floor"`
+	Ceiling    int     `yaml:"ceiling" json:"ceiling"`
+	MinSamples int     `yaml:"min_samples" json:"min_samples"`
+}
+
+type resourceLimitsView struct {
+	MemoryMB     *int `yaml:"memory_mb,omitempty" json:"memory_mb,omitempty"`
+	CPUSeconds   *int `yaml:"cpu_seconds,omitempty" json:"cpu_seconds,omitempty"`
+	MaxProcesses *int `yaml:"max_processes,omitempty" json:"max_processes,omitempty"`
+	MaxOpenFiles *int `yaml:"max_open_files,omitempty" json:"max_open_files,omitempty"`
+}
+
+type aliasView struct {
+	Command     string              `yaml:"command" json:"command"`
+	Classifiers []classifierView    `yaml:"classifiers,omitempty" json:"classifiers,omitempty"`
+	TTY         bool                `yaml:"tty,omitempty" json:"tty,omitempty"`
+	Session     *sessionAdapterView `yaml:"session,omitempty" json:"session,omitempty"`
+	Backend     Backend             `yaml:"backend,omitempty" json:"backend,omitempty"`
+	BaseURL     string              `yaml:"base_url,omitempty" json:"base_url,omitempty"`
+	Model       string              `yaml:"model,omitempty" json:"model,omitempty"`
+	APIKeyEnv   string              `yaml:"api_key_env,omitempty" json:"api_key_env,omitempty"`
+}
+
+type classifierView struct {
+	Class     ErrorClass `yaml:"class" json:"class"`
+	Pattern   string     `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+	ExitCodes []int      `yaml:"exit_codes,omitempty,flow" json:"exit_codes,omitempty"`
+}
+
+type sessionAdapterView struct {
+	IDPattern     string `yaml:"id_pattern" json:"id_pattern"`
+	ResumeCommand string `yaml:"resume_command" json:"resume_command"`
+}
+
+type procedureView struct {
+	Display              string              `yaml:"display,omitempty" json:"display,omitempty"`
+	Summary              string              `yaml:"summary,omitempty" json:"summary,omitempty"`
+	Description          string              `yaml:"description,omitempty" json:"description,omitempty"`
+	Extends              string              `yaml:"extends,omitempty" json:"extends,omitempty"`
+	Observe              []fragmentView      `yaml:"observe,omitempty" json:"observe,omitempty"`
+	Orient               []fragmentView      `yaml:"orient,omitempty" json:"orient,omitempty"`
+	Decide               []fragmentView      `yaml:"decide,omitempty" json:"decide,omitempty"`
+	Act                  []fragmentView      `yaml:"act,omitempty" json:"act,omitempty"`
+	IterationMode        IterationMode       `yaml:"iteration_mode,omitempty" json:"iteration_mode,omitempty"`
+	DefaultMaxIterations *int                `yaml:"default_max_iterations,omitempty" json:"default_max_iterations,omitempty"`
+	IterationTimeout     interface{}         `yaml:"iteration_timeout,omitempty" json:"iteration_timeout,omitempty"`
+	IdleTimeout          *int                `yaml:"idle_timeout,omitempty" json:"idle_timeout,omitempty"`
+	ResourceLimits       *resourceLimitsView `yaml:"resource_limits,omitempty" json:"resource_limits,omitempty"`
+	MaxOutputBuffer      *int                `yaml:"max_output_buffer,omitempty" json:"max_output_buffer,omitempty"`
+	AICmd                string              `yaml:"ai_cmd,omitempty" json:"ai_cmd,omitempty"`
+	AICmdAlias           string              `yaml:"ai_cmd_alias,omitempty" json:"ai_cmd_alias,omitempty"`
+	Context              ContextMode         `yaml:"context,omitempty" json:"context,omitempty"`
+}
+
+type fragmentView struct {
+	Content    string                 `yaml:"content,omitempty" json:"content,omitempty"`
+	Path       string                 `yaml:"path,omitempty" json:"path,omitempty"`
+	Parameters map[string]interface{} `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+}
+
+func newConfigView(config *Config) configView {
+	loop := config.Loop
+	view := configView{
+		Loop: loopView{
+			IterationMode:        loop.IterationMode,
+			DefaultMaxIterations: loop.DefaultMaxIterations,
+			IterationTimeout:     timeoutView(loop.IterationTimeout, loop.IterationTimeoutAuto),
+			AdaptiveTimeout: adaptiveTimeoutView{
+				K:          loop.AdaptiveTimeout.K,
+				Floor:      loop.AdaptiveTimeout.Floor,
+				Ceiling:    loop.AdaptiveTimeout.Ceiling,
+				MinSamples: loop.AdaptiveTimeout.MinSamples,
+			},
+			IdleTimeout:        loop.IdleTimeout,
+			IdleTimeoutFailure: loop.IdleTimeoutFailure,
+			ResourceLimits:     newResourceLimitsView(loop.ResourceLimits),
+			MaxOutputBuffer:    loop.MaxOutputBuffer,
+			FailureThreshold:   loop.FailureThreshold,
+			LogLevel:           loop.LogLevel,
+			LogTimestampFormat: loop.LogTimestampFormat,
+			ShowAIOutput:       loop.ShowAIOutput,
+			AICmd:              loop.AICmd,
+			AICmdAlias:         loop.AICmdAlias,
+			RetryBackoff:       loop.RetryBackoff,
+			RetryBackoffMax:    loop.RetryBackoffMax,
+			MaxRetries:         loop.MaxRetries,
+		},
+	}
+
+	if len(config.AICmdAliases) > 0 {
+		view.AICmdAliases = make(map[string]interface{}, len(config.AICmdAliases))
+	}
+	for name, command := range config.AICmdAliases {
+		opts, ok := config.AliasOptions[name]
+		if !ok {
+			view.AICmdAliases[name] = command
+			continue
+		}
+		alias := aliasView{Command: command, TTY: opts.TTY, Backend: opts.Backend}
+		for _, c := range opts.Classifiers {
+			alias.Classifiers = append(alias.Classifiers, classifierView{Class: c.Class, Pattern: c.Pattern, ExitCodes: c.ExitCodes})
+		}
+		if opts.Session != nil {
+			alias.Session = &sessionAdapterView{IDPattern: opts.Session.IDPattern, ResumeCommand: opts.Session.ResumeCommand}
+		}
+		if opts.HTTP != nil {
+			alias.BaseURL = opts.HTTP.BaseURL
+			alias.Model = opts.HTTP.Model
+			alias.APIKeyEnv = opts.HTTP.APIKeyEnv
+		}
+		view.AICmdAliases[name] = alias
+	}
+
+	if len(config.Procedures) > 0 {
+		view.Procedures = make(map[string]procedureView, len(config.Procedures))
+	}
+	for name, proc := range config.Procedures {
+		view.Procedures[name] = procedureView{
+			Display:              proc.Display,
+			Summary:              proc.Summary,
+			Description:          proc.Description,
+			Extends:              proc.Extends,
+			Observe:              newFragmentViews(proc.Observe),
+			Orient:               newFragmentViews(proc.Orient),
+			Decide:               newFragmentViews(proc.Decide),
+			Act:                  newFragmentViews(proc.Act),
+			IterationMode:        proc.IterationMode,
+			DefaultMaxIterations: proc.DefaultMaxIterations,
+			IterationTimeout:     timeoutView(proc.IterationTimeout, proc.IterationTimeoutAuto),
+			IdleTimeout:          proc.IdleTimeout,
+			ResourceLimits:       newResourceLimitsView(proc.ResourceLimits),
+			MaxOutputBuffer:      proc.MaxOutputBuffer,
+			AICmd:                proc.AICmd,
+			AICmdAlias:           proc.AICmdAlias,
+			Context:              proc.Context,
+		}
+	}
+
+	return view
+}
+
+// timeoutView returns seconds, "auto", or nil when no timeout is set.
+func timeoutView(seconds *int, auto bool) interface{} {
+	if auto {
+		return IterationTimeoutAuto
+	}
+	if seconds != nil {
+		return *seconds
+	}
+	return nil
+}
+
+func newResourceLimitsView(limits ResourceLimits) *resourceLimitsView {
+	if limits.IsZero() {
+		return nil
+	}
+	return &resourceLimitsView{
+		MemoryMB:     limits.MemoryMB,
+		CPUSeconds:   limits.CPUSeconds,
+		MaxProcesses: limits.MaxProcesses,
+		MaxOpenFiles: limits.MaxOpenFiles,
+	}
+}
+
+func newFragmentViews(fragments []FragmentAction) []fragmentView {
+	views := make([]fragmentView, len(fragments))
+	for i, f := range fragments {
+		views[i] = fragmentView{Content: f.Content, Path: f.Path, Parameters: f.Parameters}
+	}
+	return views
+}
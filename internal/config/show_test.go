@@ -0,0 +1,174 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func loadShowConfig(t *testing.T) (*Config, string) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	t.Setenv("ROODA_CONFIG_HOME", tmpDir)
+	workspacePath := filepath.Join(tmpDir, "workspace.yml")
+	workspaceYAML := `loop:
+  iteration_timeout: 30
+ai_cmd_aliases:
+  fast:
+    command: "fast-ai"
+    tty: true
+procedures:
+  build:
+    summary: "Build it"
+    idle_timeout: 120
+    act:
+      - content: "Do {{.what}}"
+        parameters:
+          what: work
+`
+	if err := os.WriteFile(workspacePath, []byte(workspaceYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ROODA_LOOP_LOG_LEVEL", "debug")
+	maxIter := 7
+	config, err := LoadConfig(CLIFlags{ConfigPath: workspacePath, MaxIterations: &maxIter})
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	return config, workspacePath
+}
+
+func TestMarshalConfig_YAMLProvenance(t *testing.T) {
+	config, workspacePath := loadShowConfig(t)
+
+	out, err := MarshalConfig(config, FormatYAML, true)
+	if err != nil {
+		t.Fatalf("MarshalConfig failed: %v", err)
+	}
+	for _, want := range []string{
+		"  iteration_mode: max-iterations # built-in\n",
+		"  default_max_iterations: 7 # cli --max-iterations\n",
+		"  iteration_timeout: 30 # workspace " + workspacePath + "\n",
+		"  log_level: debug # env ROODA_LOOP_LOG_LEVEL\n",
+		"  fast: # workspace " + workspacePath + "\n    command: fast-ai\n    tty: true\n",
+		"  build: # workspace " + workspacePath + "\n",
+		"    summary: Build it # workspace " + workspacePath + "\n",
+		"    idle_timeout: 120 # workspace " + workspacePath + "\n",
+		"      - content: Do {{.what}}\n        parameters:\n          what: work\n",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+
+	// Output without provenance is a valid config file that loads to the same values
+	plain, err := MarshalConfig(config, FormatYAML, false)
+	if err != nil {
+		t.Fatalf("MarshalConfig failed: %v", err)
+	}
+	if strings.Contains(string(plain), "#") {
+		t.Errorf("Expected no provenance comments, got:\n%s", plain)
+	}
+	var cf configFile
+	if err := yaml.Unmarshal(plain, &cf); err != nil {
+		t.Fatalf("Output is not a valid config file: %v", err)
+	}
+	if cf.Loop.IterationTimeout == nil || *cf.Loop.IterationTimeout.Seconds != 30 || cf.Procedures["build"].Summary != "Build it" {
+		t.Errorf("Unexpected round-tripped config: %+v", cf)
+	}
+}
+
+func TestMarshalConfig_JSONProvenance(t *testing.T) {
+	config, workspacePath := loadShowConfig(t)
+
+	out, err := MarshalConfig(config, FormatJSON, true)
+	if err != nil {
+		t.Fatalf("MarshalConfig failed: %v", err)
+	}
+	var decoded struct {
+		Loop struct {
+			IterationTimeout int    `json:"iteration_timeout"`
+			LogLevel         string `json:"log_level"`
+		} `json:"loop"`
+		AICmdAliases map[string]interface{} `json:"ai_cmd_aliases"`
+		Provenance   map[string]sourceView  `json:"provenance"`
+	}
+	if err := json.Unmarshal(out, &decoded); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, out)
+	}
+	if decoded.Loop.IterationTimeout != 30 || decoded.Loop.LogLevel != "debug" {
+		t.Errorf("Unexpected loop settings: %+v", decoded.Loop)
+	}
+	if decoded.AICmdAliases["claude"] != "claude -p --dangerously-skip-permissions" {
+		t.Errorf("Expected plain aliases as strings, got %v", decoded.AICmdAliases["claude"])
+	}
+	want := sourceView{Tier: TierWorkspace, File: workspacePath, Description: "workspace " + workspacePath}
+	if got := decoded.Provenance["procedures.build.idle_timeout"]; got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+	if got := decoded.Provenance["loop.log_level"].Description; got != "env ROODA_LOOP_LOG_LEVEL" {
+		t.Errorf("Expected env provenance, got %q", got)
+	}
+
+	plain, err := MarshalConfig(config, FormatJSON, false)
+	if err != nil {
+		t.Fatalf("MarshalConfig failed: %v", err)
+	}
+	if strings.Contains(string(plain), `"provenance"`) {
+		t.Errorf("Expected no provenance without the flag, got:\n%s", plain)
+	}
+}
+
+func TestMarshalConfig_Interpolations(t *testing.T) {
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+	t.Setenv("ROODA_TEST_AGENT", "/opt/agent")
+	workspacePath := filepath.Join(t.TempDir(), "workspace.yml")
+	os.WriteFile(workspacePath, []byte(`loop:
+  ai_cmd: "${ROODA_TEST_AGENT}/run --model ${ROODA_TEST_MODEL:-sonnet}"
+procedures:
+  build:
+    act:
+      - content: "Check ${loop.ai_cmd}"
+`), 0644)
+	config, err := LoadConfig(CLIFlags{ConfigPath: workspacePath})
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	out, err := MarshalConfig(config, FormatYAML, true)
+	if err != nil {
+		t.Fatalf("MarshalConfig failed: %v", err)
+	}
+	for _, want := range []string{
+		`  ai_cmd: /opt/agent/run --model sonnet # workspace ` + workspacePath + `, from "${ROODA_TEST_AGENT}/run --model ${ROODA_TEST_MODEL:-sonnet}" (env ROODA_TEST_AGENT, default for ROODA_TEST_MODEL)` + "\n",
+		`      - content: Check /opt/agent/run --model sonnet # from "Check ${loop.ai_cmd}" (config loop.ai_cmd)` + "\n",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+
+	out, err = MarshalConfig(config, FormatJSON, true)
+	if err != nil {
+		t.Fatalf("MarshalConfig failed: %v", err)
+	}
+	var parsed struct {
+		Interpolations map[string]interpolationView `json:"interpolations"`
+	}
+	if err := json.Unmarshal(out, &parsed); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	if in := parsed.Interpolations["loop.ai_cmd"]; in.Value != "/opt/agent/run --model sonnet" || in.File != workspacePath {
+		t.Errorf("Unexpected loop.ai_cmd interpolation %+v", in)
+	}
+}
+
+func TestMarshalConfig_InvalidFormat(t *testing.T) {
+	if _, err := MarshalConfig(builtInDefaults(), "toml", false); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
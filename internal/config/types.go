@@ -1,5 +1,10 @@
 package config
 
+import (
+	"fmt"
+	"strings"
+)
+
 // LogLevel defines the verbosity of loop logging.
 type LogLevel string
 
@@ -29,6 +34,44 @@ const (
 	ModeUnlimited     IterationMode = "unlimited"      // Run until SUCCESS signal, failure threshold, or Ctrl+C
 )
 
+// ContextMode controls whether iterations share an AI CLI session.
+type ContextMode string
+
+const (
+	ContextFresh                ContextMode = "fresh"                  // Every iteration starts a new session
+	ContextContinue             ContextMode = "continue"               // Resume the previous iteration's session
+	ContextContinueUntilFailure ContextMode = "continue-until-failure" // Resume until an iteration fails, then start fresh
+)
+
+// PhaseMode controls how --observe/--orient/--decide/--act fragments combine
+// with the procedure's phase.
+type PhaseMode string
+
+const (
+	PhaseModeReplace PhaseMode = "replace" // Flag fragments replace the phase (default)
+	PhaseModeAppend  PhaseMode = "append"  // Flag fragments are added after the phase
+)
+
+// SessionPlaceholder is replaced with the captured session ID in SessionAdapter.ResumeCommand.
+const SessionPlaceholder = "{session_id}"
+
+// DropInDir is the directory next to a config file whose *.yml files are
+// merged after it, so procedures can be added one file at a time.
+const DropInDir = "procedures.d"
+
+// WorkspaceDir holds workspace-local rooda files: drop-in procedures, packs,
+// recorded runs and iteration stats.
+const WorkspaceDir = ".rooda"
+
+// WorkspaceProceduresDir holds drop-in procedure files for the workspace,
+// merged after the workspace config.
+const WorkspaceProceduresDir = WorkspaceDir + "/procedures"
+
+// PacksDir is where rooda pack installs packs, in the global config directory
+// or WorkspaceDir. Each pack's procedures/*.yml files merge before that
+// tier's config file, so the config file can override them.
+const PacksDir = "packs"
+
 // ConfigTier identifies which configuration source provided a value.
 type ConfigTier string
 
@@ -36,22 +79,36 @@ const (
 	TierBuiltIn   ConfigTier = "built-in"
 	TierGlobal    ConfigTier = "global"    // <config_dir>/rooda-config.yml
 	TierWorkspace ConfigTier = "workspace" // ./rooda-config.yml
+	TierProfile   ConfigTier = "profile"   // profiles.<name> selected with --profile or ROODA_PROFILE
 	TierEnvVar    ConfigTier = "env"       // ROODA_* environment variables
 	TierCLIFlag   ConfigTier = "cli"       // --flag values
 )
 
 // Built-in defaults
 const (
-	DefaultMaxIterations  = 5
-	DefaultMaxOutputBuffer = 10485760 // 10MB
+	DefaultMaxIterations    = 5
+	DefaultMaxOutputBuffer  = 10485760 // 10MB
 	DefaultFailureThreshold = 3
+
+	DefaultAdaptiveTimeoutK          = 3.0  // Standard deviations above the mean
+	DefaultAdaptiveTimeoutFloor      = 60   // Seconds
+	DefaultAdaptiveTimeoutCeiling    = 3600 // Seconds
+	DefaultAdaptiveTimeoutMinSamples = 5
+
+	DefaultRetryBackoff    = 5   // Seconds before the first classified retry
+	DefaultRetryBackoffMax = 300 // Seconds, cap for exponential rate-limit backoff
+	DefaultMaxRetries      = 5   // Classified retries of one iteration before it counts as a failure
 )
 
+// IterationTimeoutAuto is the iteration_timeout value that enables adaptive timeouts.
+const IterationTimeoutAuto = "auto"
+
 var (
-	DefaultLogLevel          = LogLevelInfo
-	DefaultTimestampFormat   = TimestampTime
-	DefaultIterationMode     = ModeMaxIterations
-	DefaultShowAIOutput      = false
+	DefaultLogLevel           = LogLevelInfo
+	DefaultTimestampFormat    = TimestampTime
+	DefaultIterationMode      = ModeMaxIterations
+	DefaultShowAIOutput       = false
+	DefaultIdleTimeoutFailure = true
 )
 
 // FragmentAction specifies a prompt fragment with optional inline content or file path.
@@ -66,6 +123,7 @@ type Procedure struct {
 	Display              string           // Human-readable name (optional)
 	Summary              string           // One-line description (optional)
 	Description          string           // Detailed description (optional)
+	Extends              string           // Procedure this one was copied from before its own fields applied (optional)
 	Observe              []FragmentAction // Array of observe phase fragments
 	Orient               []FragmentAction // Array of orient phase fragments
 	Decide               []FragmentAction // Array of decide phase fragments
@@ -73,29 +131,131 @@ type Procedure struct {
 	IterationMode        IterationMode    // Override loop iteration mode ("" = inherit from loop)
 	DefaultMaxIterations *int             // Override loop.default_max_iterations (nil = inherit from loop). Must be >= 1 when set.
 	IterationTimeout     *int             // Override loop.iteration_timeout (nil = inherit from loop). Must be >= 1 when set. Seconds.
+	IterationTimeoutAuto bool             // iteration_timeout: auto (derive from recorded durations). Mutually exclusive with IterationTimeout.
+	IdleTimeout          *int             // Override loop.idle_timeout (nil = inherit from loop). Must be >= 1 when set. Seconds.
+	ResourceLimits       ResourceLimits   // Override individual loop.resource_limits (nil fields inherit from loop)
 	MaxOutputBuffer      *int             // Override loop.max_output_buffer (nil = inherit from loop). Must be >= 1024 when set. Bytes.
 	AICmd                string           // Override AI command for this procedure (optional)
 	AICmdAlias           string           // Override AI command alias for this procedure (optional)
+	Context              ContextMode      // Session continuity between iterations ("" = fresh). Needs an alias with a session adapter.
 }
 
 // LoopConfig defines global loop settings.
 type LoopConfig struct {
-	IterationMode        IterationMode   // Iteration mode (built-in default: ModeMaxIterations)
-	DefaultMaxIterations *int            // Global default (built-in default: 5). Must be >= 1 when set. nil = not set (inherit).
-	IterationTimeout     *int            // Per-iteration timeout in seconds (built-in default: nil). nil = no timeout.
-	MaxOutputBuffer      int             // Max AI CLI output buffer in bytes (built-in default: 10485760 = 10MB). Must be >= 1024.
-	FailureThreshold     int             // Consecutive failures before abort (built-in default: 3)
-	LogLevel             LogLevel        // Loop log level (built-in default: LogLevelInfo)
-	LogTimestampFormat   TimestampFormat // Log timestamp format (built-in default: TimestampTime)
-	ShowAIOutput         bool            // Stream AI CLI output to terminal (built-in default: false)
-	AICmd                string          // Default AI command (direct command string, optional)
-	AICmdAlias           string          // Default AI command alias name (resolved from AICmdAliases, optional)
+	IterationMode        IterationMode         // Iteration mode (built-in default: ModeMaxIterations)
+	DefaultMaxIterations *int                  // Global default (built-in default: 5). Must be >= 1 when set. nil = not set (inherit).
+	IterationTimeout     *int                  // Per-iteration timeout in seconds (built-in default: nil). nil = no timeout.
+	IterationTimeoutAuto bool                  // iteration_timeout: auto (derive from recorded durations). Mutually exclusive with IterationTimeout.
+	AdaptiveTimeout      AdaptiveTimeoutConfig // Tuning for iteration_timeout: auto
+	IdleTimeout          *int                  // Kill AI CLI after this many seconds without output (built-in default: nil). nil = no watchdog.
+	IdleTimeoutFailure   bool                  // Count idle timeouts toward failure_threshold (built-in default: true)
+	ResourceLimits       ResourceLimits        // Limits applied to the AI CLI's process group (Linux only, built-in default: none)
+	MaxOutputBuffer      int                   // Max AI CLI output buffer in bytes (built-in default: 10485760 = 10MB). Must be >= 1024.
+	FailureThreshold     int                   // Consecutive failures before abort (built-in default: 3)
+	LogLevel             LogLevel              // Loop log level (built-in default: LogLevelInfo)
+	LogTimestampFormat   TimestampFormat       // Log timestamp format (built-in default: TimestampTime)
+	ShowAIOutput         bool                  // Stream AI CLI output to terminal (built-in default: false)
+	AICmd                string                // Default AI command (direct command string, optional)
+	AICmdAlias           string                // Default AI command alias name (resolved from AICmdAliases, optional)
+	RetryBackoff         int                   // Seconds before retrying a rate-limited/transient iteration (built-in default: 5). Must be >= 0.
+	RetryBackoffMax      int                   // Cap for exponential rate-limit backoff in seconds (built-in default: 300). Must be >= RetryBackoff.
+	MaxRetries           int                   // Classified retries per iteration before counting a failure (built-in default: 5). Must be >= 0.
+}
+
+// ResourceLimits caps what the AI CLI and the processes it starts may consume.
+// Nil fields are unlimited. Each must be >= 1 when set.
+type ResourceLimits struct {
+	MemoryMB     *int // Memory in MiB (cgroup memory.max, or RLIMIT_DATA per process)
+	CPUSeconds   *int // CPU time in seconds per process (RLIMIT_CPU)
+	MaxProcesses *int // Processes (cgroup pids.max, or RLIMIT_NPROC for the user)
+	MaxOpenFiles *int // Open file descriptors per process (RLIMIT_NOFILE)
+}
+
+// IsZero reports whether no limit is set.
+func (r ResourceLimits) IsZero() bool {
+	return r.MemoryMB == nil && r.CPUSeconds == nil && r.MaxProcesses == nil && r.MaxOpenFiles == nil
+}
+
+// String formats the set limits as space-separated key=value pairs.
+func (r ResourceLimits) String() string {
+	var parts []string
+	for _, f := range []struct {
+		key   string
+		value *int
+	}{
+		{"memory_mb", r.MemoryMB},
+		{"cpu_seconds", r.CPUSeconds},
+		{"max_processes", r.MaxProcesses},
+		{"max_open_files", r.MaxOpenFiles},
+	} {
+		if f.value != nil {
+			parts = append(parts, fmt.Sprintf("%s=%d", f.key, *f.value))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// ErrorClass categorizes a failed AI CLI invocation so the loop can react to it.
+type ErrorClass string
+
+const (
+	ClassRateLimited ErrorClass = "rate_limited" // Sleep with exponential backoff and retry, no failure counted
+	ClassTransient   ErrorClass = "transient"    // Retry after retry_backoff, no failure counted
+	ClassAuthError   ErrorClass = "auth_error"   // Abort immediately
+)
+
+// OutputClassifier matches AI CLI output and/or exit code to an ErrorClass.
+// When both Pattern and ExitCodes are set, both must match.
+type OutputClassifier struct {
+	Class     ErrorClass // Classification to apply when matched
+	Pattern   string     // Regular expression matched against output (optional)
+	ExitCodes []int      // Exit codes that match (optional; empty = any non-zero exit)
+}
+
+// AliasOptions holds per-alias execution settings beyond the command string.
+type AliasOptions struct {
+	Classifiers []OutputClassifier // Checked in order; first match wins
+	TTY         bool               // Run the command under a pseudo-terminal (Linux only)
+	Session     *SessionAdapter    // How to capture and resume sessions (nil = not resumable)
+	Backend     Backend            // How the alias is executed ("" = BackendSubprocess)
+	HTTP        *HTTPBackend       // Endpoint settings when Backend is BackendHTTP
+}
+
+// Backend selects how an AI command alias is executed.
+type Backend string
+
+const (
+	BackendSubprocess Backend = "subprocess" // Run the command with the prompt on stdin
+	BackendHTTP       Backend = "http"       // Send the prompt to an OpenAI-compatible chat completions endpoint
+)
+
+// HTTPBackend configures an OpenAI-compatible chat completions endpoint.
+type HTTPBackend struct {
+	BaseURL   string // API base URL, e.g. http://localhost:8080/v1 (/chat/completions is appended)
+	Model     string // Model name sent with each request
+	APIKeyEnv string // Environment variable holding the API key (optional; no Authorization header when empty)
+}
+
+// SessionAdapter lets rooda continue an AI CLI session in the next iteration.
+type SessionAdapter struct {
+	IDPattern     string // Regular expression matched against output; first capture group (or whole match) is the session ID
+	ResumeCommand string // Command used to continue a session; must contain SessionPlaceholder
+}
+
+// AdaptiveTimeoutConfig tunes adaptive iteration timeouts.
+// The timeout is mean + K*stddev of recorded durations, clamped to [Floor, Ceiling].
+// Until MinSamples durations are recorded, Ceiling is used.
+type AdaptiveTimeoutConfig struct {
+	K          float64 // Standard deviations above the mean (built-in default: 3). Must be > 0.
+	Floor      int     // Minimum timeout in seconds (built-in default: 60). Must be >= 1.
+	Ceiling    int     // Maximum timeout in seconds (built-in default: 3600). Must be >= Floor.
+	MinSamples int     // Recorded durations required before adapting (built-in default: 5). Must be >= 2.
 }
 
 // ConfigSource tracks which tier provided a configuration value.
 type ConfigSource struct {
 	Tier  ConfigTier // Which tier provided this value
-	File  string     // File path (for workspace/global tiers) or "" for built-in/env/cli
+	File  string     // File path (for workspace/global/profile tiers) or "" for built-in/env/cli
 	Value any        // The resolved value
 }
 
@@ -104,11 +264,20 @@ type Config struct {
 	Loop         LoopConfig              // Global loop settings
 	Procedures   map[string]Procedure    // Named procedure definitions
 	AICmdAliases map[string]string       // AI command alias name -> command string
+	AliasOptions map[string]AliasOptions // AI command alias name -> execution options (only for mapping-form aliases)
 	Provenance   map[string]ConfigSource // Setting path -> source that provided it
+	Warnings     ValidationErrors        // Problems that don't stop loading, e.g. unknown keys
+	Profile      string                  // Profile applied, or "" for none
+
+	sources     []filePositions   // Where settings appear in each loaded config file, in load order
+	mergeErrors ValidationErrors  // Problems found while merging files, e.g. extending an unknown procedure
+	profiles    []profileSource   // Profile definitions from every loaded file, in load order
+	pending     map[string][]byte // Contents to load in place of files on disk, by absolute path
 }
 
 // AICommand represents a resolved AI command with provenance.
 type AICommand struct {
-	Command string // Full command string to execute
-	Source  string // Provenance: where this command came from
+	Command string       // Full command string to execute
+	Source  string       // Provenance: where this command came from
+	Options AliasOptions // Execution options when resolved from an alias
 }
@@ -2,104 +2,324 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"os/exec"
+	"regexp"
+	"sort"
 	"strings"
 )
 
-// ValidateConfig validates the merged configuration.
-// Returns error with actionable messages if validation fails.
+// ValidationError is one problem found in the configuration.
+type ValidationError struct {
+	Path    string // Setting path, e.g. loop.failure_threshold
+	Message string // Actionable description, including the setting
+	File    string // Config file that set the value ("" for built-in, env and CLI values)
+	Line    int    // 1-based line in File (0 = unknown)
+	Column  int    // 1-based column in File (0 = unknown)
+}
+
+func (e ValidationError) Error() string {
+	switch {
+	case e.File != "" && e.Line > 0:
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+	case e.File != "":
+		return fmt.Sprintf("%s: %s", e.File, e.Message)
+	}
+	return e.Message
+}
+
+// ValidationErrors is every problem found in one validation pass.
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d configuration errors:", len(e))
+	for _, err := range e {
+		b.WriteString("\n  ")
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+// ValidateConfig validates the merged configuration, including that configured
+// AI command binaries exist. Returns ValidationErrors listing every problem,
+// with the file, line and column that set each value when known.
 func ValidateConfig(config *Config) error {
-	if err := validateLoopConfig(&config.Loop); err != nil {
-		return err
+	v := &validator{checkCommands: true}
+	v.config(config)
+	return v.result(config)
+}
+
+// ValidateAICommand checks that the binary an AI command runs exists and is
+// executable, either as an absolute path or on PATH.
+func ValidateAICommand(cmd string) error {
+	return validateAICommand(cmd)
+}
+
+// validateSettings is the validation run by LoadConfig. It skips checking
+// AI command binaries, which depend on the machine rather than the config.
+func validateSettings(config *Config) error {
+	v := &validator{}
+	v.config(config)
+	return v.result(config)
+}
+
+// validator collects problems instead of stopping at the first.
+type validator struct {
+	errs          ValidationErrors
+	checkCommands bool
+}
+
+func (v *validator) addf(path string, format string, args ...interface{}) {
+	v.errs = append(v.errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) add(path string, err error) {
+	if err != nil {
+		v.errs = append(v.errs, ValidationError{Path: path, Message: err.Error()})
 	}
+}
 
-	for name, proc := range config.Procedures {
-		if err := validateProcedure(name, &proc); err != nil {
-			return err
+func (v *validator) result(config *Config) error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	for i := range v.errs {
+		config.locate(&v.errs[i])
+	}
+	sort.SliceStable(v.errs, func(i, j int) bool {
+		a, b := v.errs[i], v.errs[j]
+		if a.File != b.File {
+			return a.File < b.File
 		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return v.errs
+}
+
+func (v *validator) config(config *Config) {
+	v.errs = append(v.errs, config.mergeErrors...)
+	v.loop(&config.Loop)
+
+	names := make([]string, 0, len(config.Procedures))
+	for name := range config.Procedures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		proc := config.Procedures[name]
+		v.procedure(name, &proc)
 	}
 
-	return nil
+	names = names[:0]
+	for name := range config.AliasOptions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		v.aliasOptions(name, config.AliasOptions[name])
+	}
 }
 
-func validateLoopConfig(loop *LoopConfig) error {
+func (v *validator) loop(loop *LoopConfig) {
 	// Validate DefaultMaxIterations
 	if loop.DefaultMaxIterations != nil && *loop.DefaultMaxIterations < 1 {
-		return fmt.Errorf("loop.default_max_iterations must be >= 1, got %d", *loop.DefaultMaxIterations)
+		v.addf("loop.default_max_iterations", "loop.default_max_iterations must be >= 1, got %d", *loop.DefaultMaxIterations)
 	}
 
 	// Validate IterationTimeout
 	if loop.IterationTimeout != nil && *loop.IterationTimeout < 1 {
-		return fmt.Errorf("loop.iteration_timeout must be >= 1 second, got %d", *loop.IterationTimeout)
+		v.addf("loop.iteration_timeout", "loop.iteration_timeout must be >= 1 second, got %d", *loop.IterationTimeout)
 	}
 
+	// Validate AdaptiveTimeout when configured
+	if loop.AdaptiveTimeout != (AdaptiveTimeoutConfig{}) {
+		v.adaptiveTimeout(loop.AdaptiveTimeout)
+	}
+
+	// Validate IdleTimeout
+	if loop.IdleTimeout != nil && *loop.IdleTimeout < 1 {
+		v.addf("loop.idle_timeout", "loop.idle_timeout must be >= 1 second, got %d", *loop.IdleTimeout)
+	}
+
+	// Validate ResourceLimits
+	v.resourceLimits("loop.", "loop.", loop.ResourceLimits)
+
 	// Validate MaxOutputBuffer
 	if loop.MaxOutputBuffer < 1024 {
-		return fmt.Errorf("loop.max_output_buffer must be >= 1024 bytes, got %d", loop.MaxOutputBuffer)
+		v.addf("loop.max_output_buffer", "loop.max_output_buffer must be >= 1024 bytes, got %d", loop.MaxOutputBuffer)
 	}
 
 	// Validate FailureThreshold
 	if loop.FailureThreshold < 1 {
-		return fmt.Errorf("loop.failure_threshold must be >= 1, got %d", loop.FailureThreshold)
+		v.addf("loop.failure_threshold", "loop.failure_threshold must be >= 1, got %d", loop.FailureThreshold)
 	}
 
-	// Validate LogLevel
-	if err := validateLogLevel(loop.LogLevel); err != nil {
-		return err
+	// Validate retry settings
+	if loop.RetryBackoff < 0 {
+		v.addf("loop.retry_backoff", "loop.retry_backoff must be >= 0 seconds, got %d", loop.RetryBackoff)
+	}
+	if loop.RetryBackoffMax < loop.RetryBackoff {
+		v.addf("loop.retry_backoff_max", "loop.retry_backoff_max must be >= retry_backoff (%d), got %d", loop.RetryBackoff, loop.RetryBackoffMax)
+	}
+	if loop.MaxRetries < 0 {
+		v.addf("loop.max_retries", "loop.max_retries must be >= 0, got %d", loop.MaxRetries)
 	}
 
+	// Validate LogLevel
+	v.add("loop.log_level", validateLogLevel(loop.LogLevel))
+
 	// Validate LogTimestampFormat
-	if err := validateTimestampFormat(loop.LogTimestampFormat); err != nil {
-		return err
-	}
+	v.add("loop.log_timestamp_format", validateTimestampFormat(loop.LogTimestampFormat))
 
 	// Validate IterationMode
-	if err := validateIterationMode(loop.IterationMode); err != nil {
-		return err
-	}
+	v.add("loop.iteration_mode", validateIterationMode(loop.IterationMode))
 
 	// Validate AI command if set
-	if loop.AICmd != "" {
+	if loop.AICmd != "" && v.checkCommands {
 		if err := validateAICommand(loop.AICmd); err != nil {
-			return fmt.Errorf("loop.ai_cmd: %w", err)
+			v.addf("loop.ai_cmd", "loop.ai_cmd: %v", err)
 		}
 	}
-
-	return nil
 }
 
-func validateProcedure(name string, proc *Procedure) error {
+func (v *validator) procedure(name string, proc *Procedure) {
+	path := "procedures." + name + "."
+	prefix := fmt.Sprintf("procedure %q: ", name)
+
 	// Validate DefaultMaxIterations
 	if proc.DefaultMaxIterations != nil && *proc.DefaultMaxIterations < 1 {
-		return fmt.Errorf("procedure %q: default_max_iterations must be >= 1, got %d", name, *proc.DefaultMaxIterations)
+		v.addf(path+"default_max_iterations", "%sdefault_max_iterations must be >= 1, got %d", prefix, *proc.DefaultMaxIterations)
 	}
 
 	// Validate IterationTimeout
 	if proc.IterationTimeout != nil && *proc.IterationTimeout < 1 {
-		return fmt.Errorf("procedure %q: iteration_timeout must be >= 1 second, got %d", name, *proc.IterationTimeout)
+		v.addf(path+"iteration_timeout", "%siteration_timeout must be >= 1 second, got %d", prefix, *proc.IterationTimeout)
+	}
+
+	// Validate IdleTimeout
+	if proc.IdleTimeout != nil && *proc.IdleTimeout < 1 {
+		v.addf(path+"idle_timeout", "%sidle_timeout must be >= 1 second, got %d", prefix, *proc.IdleTimeout)
 	}
 
+	// Validate ResourceLimits
+	v.resourceLimits(path, prefix, proc.ResourceLimits)
+
 	// Validate MaxOutputBuffer
 	if proc.MaxOutputBuffer != nil && *proc.MaxOutputBuffer < 1024 {
-		return fmt.Errorf("procedure %q: max_output_buffer must be >= 1024 bytes, got %d", name, *proc.MaxOutputBuffer)
+		v.addf(path+"max_output_buffer", "%smax_output_buffer must be >= 1024 bytes, got %d", prefix, *proc.MaxOutputBuffer)
 	}
 
 	// Validate IterationMode
-	if proc.IterationMode != "" {
-		if err := validateIterationMode(proc.IterationMode); err != nil {
-			return fmt.Errorf("procedure %q: %w", name, err)
-		}
+	if err := validateIterationMode(proc.IterationMode); err != nil {
+		v.addf(path+"iteration_mode", "%s%v", prefix, err)
+	}
+
+	// Validate Context
+	if err := validateContextMode(proc.Context); err != nil {
+		v.addf(path+"context", "%s%v", prefix, err)
 	}
 
 	// Validate AI command if set
-	if proc.AICmd != "" {
+	if proc.AICmd != "" && v.checkCommands {
 		if err := validateAICommand(proc.AICmd); err != nil {
-			return fmt.Errorf("procedure %q: ai_cmd: %w", name, err)
+			v.addf(path+"ai_cmd", "%sai_cmd: %v", prefix, err)
 		}
 	}
+}
 
-	return nil
+func (v *validator) aliasOptions(name string, opts AliasOptions) {
+	path := "ai_cmd_aliases." + name
+	for i, c := range opts.Classifiers {
+		cpath := fmt.Sprintf("%s.classifiers[%d]", path, i)
+		switch c.Class {
+		case ClassRateLimited, ClassTransient, ClassAuthError:
+		default:
+			v.addf(cpath+".class", "%s: invalid class %q, must be one of: rate_limited, transient, auth_error", cpath, c.Class)
+		}
+		if c.Pattern == "" && len(c.ExitCodes) == 0 {
+			v.addf(cpath, "%s: must set pattern, exit_codes, or both", cpath)
+		}
+		if c.Pattern != "" {
+			if _, err := regexp.Compile(c.Pattern); err != nil {
+				v.addf(cpath+".pattern", "%s: invalid pattern: %v", cpath, err)
+			}
+		}
+	}
+	switch opts.Backend {
+	case "", BackendSubprocess:
+	case BackendHTTP:
+		v.httpBackend(name, opts)
+	default:
+		v.addf(path+".backend", "%s.backend: invalid backend %q, must be one of: subprocess, http", path, opts.Backend)
+	}
+	if s := opts.Session; s != nil {
+		if s.IDPattern == "" {
+			v.addf(path+".session", "%s.session.id_pattern is required", path)
+		} else if re, err := regexp.Compile(s.IDPattern); err != nil {
+			v.addf(path+".session.id_pattern", "%s.session.id_pattern: invalid pattern: %v", path, err)
+		} else if re.NumSubexp() > 1 {
+			v.addf(path+".session.id_pattern", "%s.session.id_pattern must have at most one capture group, got %d", path, re.NumSubexp())
+		}
+		if !strings.Contains(s.ResumeCommand, SessionPlaceholder) {
+			v.addf(path+".session.resume_command", "%s.session.resume_command must contain %s", path, SessionPlaceholder)
+		}
+	}
+}
+
+func (v *validator) httpBackend(name string, opts AliasOptions) {
+	path := "ai_cmd_aliases." + name
+	if opts.HTTP == nil || opts.HTTP.BaseURL == "" {
+		v.addf(path, "%s.base_url is required for backend: http", path)
+	} else if u, err := url.Parse(opts.HTTP.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.addf(path+".base_url", "%s.base_url must be an http:// or https:// URL, got %q", path, opts.HTTP.BaseURL)
+	}
+	if opts.HTTP == nil || opts.HTTP.Model == "" {
+		v.addf(path, "%s.model is required for backend: http", path)
+	}
+	if opts.TTY || opts.Session != nil {
+		v.addf(path, "%s: tty and session only apply to backend: subprocess", path)
+	}
+}
+
+// resourceLimits checks limits set under path (e.g. "loop."), prefixing messages with prefix.
+func (v *validator) resourceLimits(path string, prefix string, limits ResourceLimits) {
+	for _, f := range []struct {
+		key   string
+		value *int
+	}{
+		{"memory_mb", limits.MemoryMB},
+		{"cpu_seconds", limits.CPUSeconds},
+		{"max_processes", limits.MaxProcesses},
+		{"max_open_files", limits.MaxOpenFiles},
+	} {
+		if f.value != nil && *f.value < 1 {
+			v.addf(path+"resource_limits."+f.key, "%sresource_limits.%s must be >= 1, got %d", prefix, f.key, *f.value)
+		}
+	}
+}
+
+func (v *validator) adaptiveTimeout(adaptive AdaptiveTimeoutConfig) {
+	const path = "loop.adaptive_timeout."
+	if adaptive.K <= 0 {
+		v.addf(path+"k", "loop.adaptive_timeout.k must be > 0, got %g", adaptive.K)
+	}
+	if adaptive.Floor < 1 {
+		v.addf(path+"floor", "loop.adaptive_timeout.floor must be >= 1 second, got %d", adaptive.Floor)
+	}
+	if adaptive.Ceiling < adaptive.Floor {
+		v.addf(path+"ceiling", "loop.adaptive_timeout.ceiling must be >= floor (%d), got %d", adaptive.Floor, adaptive.Ceiling)
+	}
+	if adaptive.MinSamples < 2 {
+		v.addf(path+"min_samples", "loop.adaptive_timeout.min_samples must be >= 2, got %d", adaptive.MinSamples)
+	}
 }
 
 func validateLogLevel(level LogLevel) error {
@@ -131,6 +351,17 @@ func validateIterationMode(mode IterationMode) error {
 	}
 }
 
+func validateContextMode(mode ContextMode) error {
+	switch mode {
+	case ContextFresh, ContextContinue, ContextContinueUntilFailure:
+		return nil
+	case "":
+		return nil // Empty is valid (means fresh)
+	default:
+		return fmt.Errorf("invalid context %q, must be one of: fresh, continue, continue-until-failure", mode)
+	}
+}
+
 func validateAICommand(cmd string) error {
 	// Parse command to extract binary path
 	parts := strings.Fields(cmd)
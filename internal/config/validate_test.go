@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -64,6 +65,182 @@ func TestValidateConfig_InvalidIterationTimeout(t *testing.T) {
 	}
 }
 
+func TestValidateConfig_InvalidIdleTimeout(t *testing.T) {
+	zero := 0
+	config := &Config{
+		Loop: LoopConfig{
+			IdleTimeout:      &zero,
+			MaxOutputBuffer:  DefaultMaxOutputBuffer,
+			FailureThreshold: DefaultFailureThreshold,
+			LogLevel:         DefaultLogLevel,
+		},
+	}
+
+	err := ValidateConfig(config)
+	if err == nil || !strings.Contains(err.Error(), "idle_timeout") {
+		t.Errorf("Expected idle_timeout error, got %v", err)
+	}
+}
+
+func TestValidateConfig_InvalidSessionContext(t *testing.T) {
+	tests := []struct {
+		name    string
+		context ContextMode
+		session *SessionAdapter
+		want    string
+	}{
+		{"unknown context", "forever", nil, "invalid context"},
+		{"missing pattern", "", &SessionAdapter{ResumeCommand: "ai --resume {session_id}"}, "session.id_pattern is required"},
+		{"two capture groups", "", &SessionAdapter{IDPattern: `(a)(b)`, ResumeCommand: "ai --resume {session_id}"}, "at most one capture group"},
+		{"missing placeholder", "", &SessionAdapter{IDPattern: `id=(\S+)`, ResumeCommand: "ai --resume"}, "must contain {session_id}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				Loop: LoopConfig{
+					MaxOutputBuffer:    DefaultMaxOutputBuffer,
+					FailureThreshold:   DefaultFailureThreshold,
+					LogLevel:           DefaultLogLevel,
+					LogTimestampFormat: DefaultTimestampFormat,
+				},
+				Procedures:   map[string]Procedure{"refactor": {Context: tt.context}},
+				AliasOptions: map[string]AliasOptions{"ai": {Session: tt.session}},
+			}
+
+			err := ValidateConfig(config)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_InvalidHTTPBackend(t *testing.T) {
+	tests := []struct {
+		name string
+		opts AliasOptions
+		want string
+	}{
+		{"unknown backend", AliasOptions{Backend: "grpc"}, "invalid backend"},
+		{"missing base_url", AliasOptions{Backend: BackendHTTP, HTTP: &HTTPBackend{Model: "m"}}, "base_url is required"},
+		{"bad base_url", AliasOptions{Backend: BackendHTTP, HTTP: &HTTPBackend{BaseURL: "localhost:8080", Model: "m"}}, "http:// or https://"},
+		{"missing model", AliasOptions{Backend: BackendHTTP, HTTP: &HTTPBackend{BaseURL: "http://localhost:8080/v1"}}, "model is required"},
+		{"tty", AliasOptions{Backend: BackendHTTP, TTY: true, HTTP: &HTTPBackend{BaseURL: "http://localhost:8080/v1", Model: "m"}}, "only apply to backend: subprocess"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				Loop: LoopConfig{
+					MaxOutputBuffer:    DefaultMaxOutputBuffer,
+					FailureThreshold:   DefaultFailureThreshold,
+					LogLevel:           DefaultLogLevel,
+					LogTimestampFormat: DefaultTimestampFormat,
+				},
+				AliasOptions: map[string]AliasOptions{"local": tt.opts},
+			}
+
+			err := ValidateConfig(config)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_InvalidResourceLimits(t *testing.T) {
+	zero := 0
+	config := &Config{
+		Loop: LoopConfig{
+			MaxOutputBuffer:    DefaultMaxOutputBuffer,
+			FailureThreshold:   DefaultFailureThreshold,
+			LogLevel:           DefaultLogLevel,
+			LogTimestampFormat: DefaultTimestampFormat,
+		},
+		Procedures: map[string]Procedure{
+			"build": {ResourceLimits: ResourceLimits{MaxOpenFiles: &zero}},
+		},
+	}
+
+	err := ValidateConfig(config)
+	if err == nil || !strings.Contains(err.Error(), "resource_limits.max_open_files") || !strings.Contains(err.Error(), "build") {
+		t.Errorf("Expected procedure resource_limits error, got %v", err)
+	}
+
+	config.Procedures = nil
+	config.Loop.ResourceLimits.MemoryMB = &zero
+	err = ValidateConfig(config)
+	if err == nil || !strings.Contains(err.Error(), "loop.resource_limits.memory_mb") {
+		t.Errorf("Expected loop resource_limits error, got %v", err)
+	}
+}
+
+func TestValidateConfig_InvalidAdaptiveTimeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		adaptive AdaptiveTimeoutConfig
+		want     string
+	}{
+		{"zero k", AdaptiveTimeoutConfig{K: 0, Floor: 60, Ceiling: 600, MinSamples: 5}, "adaptive_timeout.k"},
+		{"zero floor", AdaptiveTimeoutConfig{K: 3, Floor: 0, Ceiling: 600, MinSamples: 5}, "adaptive_timeout.floor"},
+		{"ceiling below floor", AdaptiveTimeoutConfig{K: 3, Floor: 600, Ceiling: 60, MinSamples: 5}, "adaptive_timeout.ceiling"},
+		{"too few samples", AdaptiveTimeoutConfig{K: 3, Floor: 60, Ceiling: 600, MinSamples: 1}, "adaptive_timeout.min_samples"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				Loop: LoopConfig{
+					IterationTimeoutAuto: true,
+					AdaptiveTimeout:      tt.adaptive,
+					MaxOutputBuffer:      DefaultMaxOutputBuffer,
+					FailureThreshold:     DefaultFailureThreshold,
+					LogLevel:             DefaultLogLevel,
+				},
+			}
+
+			err := ValidateConfig(config)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected %s error, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_InvalidClassifiers(t *testing.T) {
+	tests := []struct {
+		name       string
+		classifier OutputClassifier
+		want       string
+	}{
+		{"unknown class", OutputClassifier{Class: "flaky", Pattern: "x"}, "invalid class"},
+		{"no matcher", OutputClassifier{Class: ClassTransient}, "must set pattern"},
+		{"bad regex", OutputClassifier{Class: ClassRateLimited, Pattern: "(unclosed"}, "invalid pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				Loop: LoopConfig{
+					MaxOutputBuffer:    DefaultMaxOutputBuffer,
+					FailureThreshold:   DefaultFailureThreshold,
+					LogLevel:           DefaultLogLevel,
+					LogTimestampFormat: DefaultTimestampFormat,
+				},
+				AliasOptions: map[string]AliasOptions{
+					"my-ai": {Classifiers: []OutputClassifier{tt.classifier}},
+				},
+			}
+
+			err := ValidateConfig(config)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected %q error, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
 func TestValidateConfig_InvalidMaxOutputBuffer(t *testing.T) {
 	config := &Config{
 		Loop: LoopConfig{
@@ -223,3 +400,101 @@ func TestValidateConfig_ProcedureInvalidIterationMode(t *testing.T) {
 		t.Error("Expected error for invalid procedure IterationMode")
 	}
 }
+
+func TestValidateConfig_CollectsAllErrors(t *testing.T) {
+	zero := 0
+	config := &Config{
+		Loop: LoopConfig{
+			MaxOutputBuffer:    DefaultMaxOutputBuffer,
+			FailureThreshold:   0,
+			LogLevel:           "verbose",
+			LogTimestampFormat: DefaultTimestampFormat,
+		},
+		Procedures: map[string]Procedure{
+			"build": {DefaultMaxIterations: &zero},
+		},
+	}
+
+	err := ValidateConfig(config)
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("Expected ValidationErrors, got %T: %v", err, err)
+	}
+	var paths []string
+	for _, e := range errs {
+		paths = append(paths, e.Path)
+	}
+	want := "loop.failure_threshold,loop.log_level,procedures.build.default_max_iterations"
+	if strings.Join(paths, ",") != want {
+		t.Errorf("Expected errors for %s, got %s", want, strings.Join(paths, ","))
+	}
+	if !strings.HasPrefix(err.Error(), "3 configuration errors:\n  ") {
+		t.Errorf("Unexpected error text: %v", err)
+	}
+}
+
+func TestLoadConfig_ReportsErrorLocations(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("ROODA_CONFIG_HOME", tmpDir)
+	globalPath := filepath.Join(tmpDir, "rooda-config.yml")
+	globalYAML := `procedures:
+  build:
+    idle_timeout: 0
+`
+	if err := os.WriteFile(globalPath, []byte(globalYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workspacePath := filepath.Join(tmpDir, "workspace.yml")
+	workspaceYAML := `loop:
+  log_level: verbose
+  failure_threshold: 0
+  retry_backoff: 2
+procedures:
+  build:
+    summary: "overrides only the summary"
+ai_cmd_aliases:
+  fast:
+    command: "fast-ai"
+    classifiers:
+      - class: flaky
+        pattern: "x"
+`
+	if err := os.WriteFile(workspacePath, []byte(workspaceYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ROODA_LOOP_RETRY_BACKOFF", "-1")
+
+	_, err := LoadConfig(CLIFlags{ConfigPath: workspacePath})
+	if err == nil {
+		t.Fatal("Expected LoadConfig to reject invalid settings")
+	}
+	for _, want := range []string{
+		globalPath + `:3:19: procedure "build": idle_timeout must be >= 1 second, got 0`,
+		workspacePath + `:2:14: invalid log_level "verbose"`,
+		workspacePath + ":3:22: loop.failure_threshold must be >= 1, got 0",
+		workspacePath + `:12:16: ai_cmd_aliases.fast.classifiers[0]: invalid class "flaky"`,
+		"\n  loop.retry_backoff must be >= 0 seconds, got -1",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoadConfig_SkipsCommandChecks(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("ROODA_CONFIG_HOME", tmpDir)
+	workspacePath := filepath.Join(tmpDir, "rooda-config.yml")
+	if err := os.WriteFile(workspacePath, []byte("loop:\n  ai_cmd: not-installed-ai --flag\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfig(CLIFlags{ConfigPath: workspacePath})
+	if err != nil {
+		t.Fatalf("Expected missing AI command binary to be accepted at load, got: %v", err)
+	}
+	err = ValidateConfig(config)
+	if err == nil || !strings.Contains(err.Error(), workspacePath+`:2:11: loop.ai_cmd: command "not-installed-ai" not found in PATH`) {
+		t.Errorf("Expected ValidateConfig to report the missing binary with its location, got: %v", err)
+	}
+}
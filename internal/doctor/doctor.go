@@ -0,0 +1,283 @@
+// Package doctor checks the environment rooda runs in for the problems that
+// make runs fail: a config that does not load, AI commands that are not
+// installed, missing fragments, an incomplete AGENTS.md and a workspace that
+// is not a git repository.
+package doctor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/jomadu/rooda/internal/config"
+	"github.com/jomadu/rooda/internal/prompt"
+	"github.com/jomadu/rooda/internal/scaffold"
+)
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	Pass Status = "pass" // Nothing to do
+	Warn Status = "warn" // Worth a look, but runs can succeed
+	Fail Status = "fail" // Runs will fail until it is fixed
+)
+
+// Check is the result of one diagnostic.
+type Check struct {
+	Name   string // What was checked, e.g. "alias claude"
+	Status Status // Outcome
+	Detail string // What was found, or how to fix it
+}
+
+// RequiredSections are the AGENTS.md sections docs/agents-md.md requires.
+var RequiredSections = []string{
+	"Issue Tracking",
+	"Work Tracking System",
+	"Build/Test/Lint Commands",
+	"Specification Definition",
+	"Implementation Definition",
+	"Quality Criteria",
+}
+
+// Run checks the configuration flags selects and the workspace in the current
+// directory. The AI command and fragment checks need the config, so they are
+// left out when it does not load.
+func Run(flags config.CLIFlags) []Check {
+	cfg, checks := checkConfig(flags)
+	if cfg != nil {
+		checks = append(checks, checkAICommands(cfg)...)
+		checks = append(checks, checkFragments(cfg)...)
+	}
+	checks = append(checks, checkAgentsMD(scaffold.AgentsFile))
+	checks = append(checks, checkGit("."))
+	return checks
+}
+
+// Failed counts the checks that failed.
+func Failed(checks []Check) int {
+	n := 0
+	for _, c := range checks {
+		if c.Status == Fail {
+			n++
+		}
+	}
+	return n
+}
+
+// checkConfig loads and validates the config, with a check per error or warning.
+func checkConfig(flags config.CLIFlags) (*config.Config, []Check) {
+	cfg, err := config.LoadConfig(flags)
+	if err != nil {
+		var errs config.ValidationErrors
+		if !errors.As(err, &errs) {
+			return nil, []Check{{Name: "config", Status: Fail, Detail: err.Error()}}
+		}
+		checks := make([]Check, len(errs))
+		for i, e := range errs {
+			checks[i] = Check{Name: "config", Status: Fail, Detail: e.Error()}
+		}
+		return nil, checks
+	}
+
+	detail := "built-in defaults only"
+	if files := cfg.Files(); len(files) > 0 {
+		detail = "loaded " + strings.Join(files, ", ")
+	}
+	checks := []Check{{Name: "config", Status: Pass, Detail: detail}}
+	for _, w := range cfg.Warnings {
+		checks = append(checks, Check{Name: "config", Status: Warn, Detail: w.Error()})
+	}
+	return cfg, checks
+}
+
+// checkAICommands checks that the binary of every alias and every direct
+// ai_cmd is installed. A broken alias fails when the loop or a procedure uses
+// it, and only warns otherwise.
+func checkAICommands(cfg *config.Config) []Check {
+	var checks []Check
+	used := make(map[string]bool)
+	if cfg.Loop.AICmd != "" {
+		checks = append(checks, commandCheck("loop.ai_cmd", cfg.Loop.AICmd))
+	}
+	if cfg.Loop.AICmdAlias != "" {
+		used[cfg.Loop.AICmdAlias] = true
+	}
+	if cfg.Loop.AICmd == "" && cfg.Loop.AICmdAlias == "" {
+		checks = append(checks, Check{Name: "ai command", Status: Warn,
+			Detail: "no default AI command: set loop.ai_cmd_alias, or pass --ai-cmd-alias to every run"})
+	}
+
+	for _, name := range sortedKeys(cfg.Procedures) {
+		proc := cfg.Procedures[name]
+		if proc.AICmd != "" {
+			checks = append(checks, commandCheck("procedures."+name+".ai_cmd", proc.AICmd))
+		}
+		if proc.AICmdAlias != "" {
+			used[proc.AICmdAlias] = true
+		}
+	}
+
+	for _, name := range sortedKeys(used) {
+		if _, ok := cfg.AICmdAliases[name]; !ok {
+			checks = append(checks, Check{Name: "alias " + name, Status: Fail,
+				Detail: fmt.Sprintf("unknown AI command alias, available: %s", strings.Join(sortedKeys(cfg.AICmdAliases), ", "))})
+		}
+	}
+	for _, name := range sortedKeys(cfg.AICmdAliases) {
+		if opts := cfg.AliasOptions[name]; opts.Backend == config.BackendHTTP {
+			checks = append(checks, Check{Name: "alias " + name, Status: Pass, Detail: "http backend, " + opts.HTTP.BaseURL})
+			continue
+		}
+		c := commandCheck("alias "+name, cfg.AICmdAliases[name])
+		if c.Status == Fail && !used[name] {
+			// Keep the finding, not the install suggestions, for an alias nothing uses
+			finding, _, _ := strings.Cut(c.Detail, "\n")
+			c.Status = Warn
+			c.Detail = strings.TrimSuffix(finding, ". Suggestions:") + ", not used by the loop or any procedure"
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
+
+// commandCheck checks that command's binary exists and is executable.
+func commandCheck(name string, command string) Check {
+	if err := config.ValidateAICommand(command); err != nil {
+		return Check{Name: name, Status: Fail, Detail: err.Error()}
+	}
+	return Check{Name: name, Status: Pass, Detail: command}
+}
+
+// checkFragments checks that every fragment path in every procedure loads,
+// with a failed check per path that does not.
+func checkFragments(cfg *config.Config) []Check {
+	var checks []Check
+	paths := 0
+	for _, name := range sortedKeys(cfg.Procedures) {
+		proc := cfg.Procedures[name]
+		for _, phase := range []struct {
+			name      string
+			fragments []config.FragmentAction
+		}{
+			{"observe", proc.Observe},
+			{"orient", proc.Orient},
+			{"decide", proc.Decide},
+			{"act", proc.Act},
+		} {
+			for i, f := range phase.fragments {
+				if f.Path == "" {
+					continue
+				}
+				paths++
+				// Paths are resolved against their config file's directory when it loads
+				if _, err := prompt.LoadFragment(f.Path, ""); err != nil {
+					checks = append(checks, Check{Name: "fragments", Status: Fail,
+						Detail: fmt.Sprintf("procedures.%s.%s[%d]: %v", name, phase.name, i, err)})
+				}
+			}
+		}
+	}
+	if len(checks) == 0 {
+		return []Check{{Name: "fragments", Status: Pass,
+			Detail: fmt.Sprintf("%d fragment path(s) in %d procedure(s) found", paths, len(cfg.Procedures))}}
+	}
+	return checks
+}
+
+// checkAgentsMD checks that path exists and has every required section. Required
+// sections that are present but empty are a warning.
+func checkAgentsMD(path string) Check {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Check{Name: path, Status: Fail, Detail: "not found: run rooda init, then rooda run agents-sync"}
+	}
+	if err != nil {
+		return Check{Name: path, Status: Fail, Detail: err.Error()}
+	}
+
+	sections := parseSections(string(data))
+	var missing, empty []string
+	for _, title := range RequiredSections {
+		hasContent, ok := sections[strings.ToLower(title)]
+		switch {
+		case !ok:
+			missing = append(missing, title)
+		case !hasContent:
+			empty = append(empty, title)
+		}
+	}
+	switch {
+	case len(missing) > 0:
+		return Check{Name: path, Status: Fail, Detail: "missing required section(s): " + strings.Join(missing, ", ")}
+	case len(empty) > 0:
+		return Check{Name: path, Status: Warn, Detail: "empty section(s): " + strings.Join(empty, ", ") + ", run rooda run agents-sync"}
+	}
+	return Check{Name: path, Status: Pass, Detail: fmt.Sprintf("all %d required sections present", len(RequiredSections))}
+}
+
+// parseSections maps the lowercased title of every Markdown heading in text
+// to whether any non-blank line follows it before the next heading. Fenced
+// code blocks are skipped, since shell comments look like headings.
+func parseSections(text string) map[string]bool {
+	sections := make(map[string]bool)
+	current := ""
+	fenced := false
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			fenced = !fenced
+		}
+		if !fenced && strings.HasPrefix(trimmed, "#") {
+			current = strings.ToLower(strings.TrimSpace(strings.TrimLeft(trimmed, "#")))
+			if _, ok := sections[current]; !ok {
+				sections[current] = false
+			}
+			continue
+		}
+		if current != "" && trimmed != "" {
+			sections[current] = true
+		}
+	}
+	return sections
+}
+
+// checkGit checks that dir is in a git repository and reports whether the
+// working tree is clean.
+func checkGit(dir string) Check {
+	if _, err := exec.LookPath("git"); err != nil {
+		return Check{Name: "git", Status: Fail, Detail: "git not found in PATH"}
+	}
+	top, err := exec.Command("git", "-C", dir, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return Check{Name: "git", Status: Fail, Detail: "not a git repository: run git init"}
+	}
+	root := strings.TrimSpace(string(top))
+
+	status, err := exec.Command("git", "-C", dir, "status", "--porcelain").Output()
+	if err != nil {
+		return Check{Name: "git", Status: Fail, Detail: fmt.Sprintf("git status failed in %s: %v", root, err)}
+	}
+	changes := 0
+	for _, line := range strings.Split(string(status), "\n") {
+		if strings.TrimSpace(line) != "" {
+			changes++
+		}
+	}
+	if changes > 0 {
+		return Check{Name: "git", Status: Warn, Detail: fmt.Sprintf("%d uncommitted change(s) in %s", changes, root)}
+	}
+	return Check{Name: "git", Status: Pass, Detail: "clean working tree in " + root}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,126 @@
+package doctor
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jomadu/rooda/internal/config"
+)
+
+func TestRun(t *testing.T) {
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	bin := t.TempDir()
+	os.WriteFile(filepath.Join(bin, "agent"), []byte("#!/bin/sh\n"), 0755)
+	os.WriteFile(filepath.Join(dir, "observe.md"), []byte("Observe"), 0644)
+	os.WriteFile(filepath.Join(dir, "rooda-config.yml"), []byte(`loop:
+  ai_cmd_alias: agent
+ai_cmd_aliases:
+  agent: `+filepath.Join(bin, "agent")+`
+  missing: not-installed-agent
+procedures:
+  build:
+    observe:
+      - path: observe.md
+      - path: builtin:fragments/observe/study_agents_md.md
+    act:
+      - path: act.md
+  review:
+    ai_cmd_alias: missing
+    observe:
+      - path: observe.md
+`), 0644)
+
+	// Leave out the built-in aliases, which depend on what this machine has installed
+	var checks []Check
+	for _, c := range Run(config.CLIFlags{}) {
+		if _, builtIn := config.BuiltInAliases()[strings.TrimPrefix(c.Name, "alias ")]; !builtIn {
+			checks = append(checks, c)
+		}
+	}
+	want := []Check{
+		{"config", Pass, "loaded ./rooda-config.yml"},
+		{"alias agent", Pass, filepath.Join(bin, "agent")},
+		{"alias missing", Fail, ""},
+		{"fragments", Fail, "procedures.build.act[0]: fragment file not found: act.md"},
+		{"AGENTS.md", Fail, "not found: run rooda init, then rooda run agents-sync"},
+		{"git", Fail, "not a git repository: run git init"},
+	}
+	if len(checks) != len(want) {
+		t.Fatalf("Expected %d checks, got %+v", len(want), checks)
+	}
+	for i, c := range checks {
+		if c.Name != want[i].Name || c.Status != want[i].Status || !strings.HasPrefix(c.Detail, want[i].Detail) {
+			t.Errorf("Check %d: expected %+v, got %+v", i, want[i], c)
+		}
+	}
+	if n := Failed(checks); n != 4 {
+		t.Errorf("Expected 4 failed checks, got %d", n)
+	}
+
+	os.WriteFile(filepath.Join(dir, "rooda-config.yml"), []byte("loop:\n  default_max_iterations: 0\n"), 0644)
+	checks = Run(config.CLIFlags{})
+	if checks[0].Name != "config" || checks[0].Status != Fail || checks[1].Name != "AGENTS.md" {
+		t.Errorf("Expected a failed config check and no config-dependent checks, got %+v", checks)
+	}
+}
+
+func TestCheckAICommandsUnused(t *testing.T) {
+	cfg := &config.Config{AICmdAliases: map[string]string{"other": "not-installed-agent --yes"}}
+	checks := checkAICommands(cfg)
+	if len(checks) != 2 || checks[0].Name != "ai command" || checks[0].Status != Warn {
+		t.Fatalf("Expected a warning for no default AI command, got %+v", checks)
+	}
+	if want := `command "not-installed-agent" not found in PATH, not used by the loop or any procedure`; checks[1].Status != Warn || checks[1].Detail != want {
+		t.Errorf("Expected unused alias to warn with %q, got %+v", want, checks[1])
+	}
+}
+
+func TestCheckAgentsMD(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "AGENTS.md")
+	full := ""
+	for _, title := range RequiredSections {
+		full += "## " + title + "\n\nFilled in.\n\n"
+	}
+
+	for _, tc := range []struct {
+		content string
+		status  Status
+		detail  string
+	}{
+		{full, Pass, "all 6 required sections present"},
+		{strings.Replace(full, "## Quality Criteria", "## Quality", 1), Fail, "missing required section(s): Quality Criteria"},
+		{strings.Replace(full, "## Quality Criteria", "```sh\n# Quality Criteria\n```", 1), Fail, "missing required section(s): Quality Criteria"},
+		{strings.Replace(full, "Definition\n\nFilled in.\n\n## Quality Criteria", "Definition\n\n## quality criteria", 1), Warn,
+			"empty section(s): Implementation Definition, run rooda run agents-sync"},
+	} {
+		os.WriteFile(path, []byte(tc.content), 0644)
+		if c := checkAgentsMD(path); c.Status != tc.status || c.Detail != tc.detail {
+			t.Errorf("Expected %s %q, got %s %q for:\n%s", tc.status, tc.detail, c.Status, c.Detail, tc.content)
+		}
+	}
+}
+
+func TestCheckGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v: %s", err, out)
+	}
+	root, _ := filepath.EvalSymlinks(dir)
+
+	if c := checkGit(dir); c.Status != Pass || c.Detail != "clean working tree in "+root {
+		t.Errorf("Expected a clean repository, got %+v", c)
+	}
+	os.WriteFile(filepath.Join(dir, "new.txt"), nil, 0644)
+	if c := checkGit(dir); c.Status != Warn || c.Detail != "1 uncommitted change(s) in "+root {
+		t.Errorf("Expected one uncommitted change, got %+v", c)
+	}
+}
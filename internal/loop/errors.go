@@ -6,9 +6,13 @@ import "strings"
 type IterationOutcome string
 
 const (
-	OutcomeSuccess IterationOutcome = "success"  // Exit 0, no signal - reset failures
-	OutcomeJobDone IterationOutcome = "job-done" // SUCCESS signal - terminate loop
-	OutcomeFailure IterationOutcome = "failure"  // FAILURE signal or non-zero exit - increment failures
+	OutcomeSuccess       IterationOutcome = "success"        // Exit 0, no signal - reset failures
+	OutcomeJobDone       IterationOutcome = "job-done"       // SUCCESS signal - terminate loop
+	OutcomeFailure       IterationOutcome = "failure"        // FAILURE signal or non-zero exit - increment failures
+	OutcomeIdleTimeout   IterationOutcome = "idle-timeout"   // No output within idle_timeout - killed, failure if configured
+	OutcomeTimeout       IterationOutcome = "timeout"        // Exceeded iteration_timeout - killed, increment failures
+	OutcomeRetried       IterationOutcome = "retried"        // Classified rate_limited/transient - retried, failures unchanged
+	OutcomeResourceLimit IterationOutcome = "resource-limit" // Killed by a resource limit - increment failures
 )
 
 // IterationResult holds the output and exit code from an AI CLI execution
@@ -2,6 +2,7 @@ package loop
 
 import (
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/jomadu/rooda/internal/ai"
@@ -33,6 +34,15 @@ func RunLoop(state *IterationState, cfg config.Config, aiCmd config.AICommand, u
 		"max_iterations":  maxIters,
 	})
 
+	// Session continuity needs an alias that can resume sessions
+	continueSessions := state.ContextMode == config.ContextContinue || state.ContextMode == config.ContextContinueUntilFailure
+	if continueSessions && aiCmd.Options.Session == nil {
+		logger.Warn(fmt.Sprintf("context: %s needs an AI command alias with a session adapter, starting every iteration fresh", state.ContextMode), map[string]interface{}{
+			"ai_cmd": aiCmd.Source,
+		})
+		continueSessions = false
+	}
+
 	for {
 		// Check termination: max iterations
 		if state.MaxIterations != nil && state.Iteration >= *state.MaxIterations {
@@ -75,8 +85,46 @@ func RunLoop(state *IterationState, cfg config.Config, aiCmd config.AICommand, u
 			break
 		}
 
-		// Execute AI CLI
-		result := ai.ExecuteAICLI(aiCmd, assembledPrompt, verbose, state.IterationTimeout, state.MaxOutputBuffer, sigChan)
+		// Determine timeout (adaptive timeouts are recomputed each iteration)
+		iterationTimeout := state.IterationTimeout
+		if state.AdaptiveTimeout != nil {
+			adaptive := state.AdaptiveTimeout.Timeout()
+			iterationTimeout = &adaptive
+			logger.Debug(fmt.Sprintf("Iteration %d: adaptive timeout", iterNum), map[string]interface{}{
+				"timeout": fmt.Sprintf("%ds", adaptive),
+				"samples": state.AdaptiveTimeout.samples(),
+			})
+		}
+
+		// Resume the previous session when continuing
+		iterCmd := aiCmd
+		if continueSessions && state.SessionID != "" {
+			iterCmd = ai.ResumeCommand(aiCmd, state.SessionID)
+			logger.Debug(fmt.Sprintf("Iteration %d: resuming session", iterNum), map[string]interface{}{
+				"session_id": state.SessionID,
+			})
+		}
+
+		// Execute AI CLI (or HTTP backend, or a replayed or scripted run)
+		executor := state.Executor
+		if executor == nil {
+			executor = ai.NewExecutor(iterCmd)
+		}
+		result := executor.Execute(assembledPrompt, ai.ExecOptions{
+			Verbose:          verbose,
+			IterationTimeout: iterationTimeout,
+			IdleTimeout:      state.IdleTimeout,
+			MaxOutputBuffer:  state.MaxOutputBuffer,
+			ResourceLimits:   state.ResourceLimits,
+		}, sigChan)
+
+		if state.Recorder != nil {
+			if err := state.Recorder.Record(assembledPrompt, result); err != nil {
+				logger.Warn("Failed to record iteration", map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+		}
 
 		// Handle interrupt
 		if result.Error == ai.ErrInterrupted {
@@ -85,12 +133,64 @@ func RunLoop(state *IterationState, cfg config.Config, aiCmd config.AICommand, u
 			break
 		}
 
+		if continueSessions {
+			if id := ai.CaptureSessionID(aiCmd.Options.Session, result.Output); id != "" {
+				state.SessionID = id
+			}
+		}
+
 		// Handle timeout
 		if result.Error == ai.ErrTimeout {
 			logger.Warn(fmt.Sprintf("Iteration %d: AI CLI exceeded timeout", iterNum), map[string]interface{}{
-				"timeout": fmt.Sprintf("%ds", *state.IterationTimeout),
+				"timeout": limitSeconds(iterationTimeout),
+			})
+			state.ConsecutiveFailures++
+			state.recordAttempt(OutcomeTimeout, "", result)
+			if state.AdaptiveTimeout != nil {
+				state.AdaptiveTimeout.recordKill(result.Duration, iterationTimeout)
+			}
+			elapsed := time.Since(iterationStart)
+			state.Stats.updateStats(elapsed)
+			state.Iteration++
+			continue
+		}
+
+		// Handle idle timeout
+		if result.Error == ai.ErrIdleTimeout {
+			if state.IdleTimeoutFailure {
+				state.ConsecutiveFailures++
+			}
+			logger.Warn(fmt.Sprintf("Iteration %d: AI CLI produced no output, killed", iterNum), map[string]interface{}{
+				"idle_timeout": limitSeconds(state.IdleTimeout),
+				"outcome":      string(OutcomeIdleTimeout),
+				"consecutive":  state.ConsecutiveFailures,
 			})
+			state.recordAttempt(OutcomeIdleTimeout, "", result)
+			if state.AdaptiveTimeout != nil {
+				state.AdaptiveTimeout.recordKill(result.Duration, nil)
+			}
+			elapsed := time.Since(iterationStart)
+			state.Stats.updateStats(elapsed)
+			state.Iteration++
+			continue
+		}
+
+		// Handle resource limit kill
+		if result.Error == ai.ErrResourceLimit {
 			state.ConsecutiveFailures++
+			limits := unsetLimit
+			if state.ResourceLimits != nil {
+				limits = state.ResourceLimits.String()
+			}
+			logger.Warn(fmt.Sprintf("Iteration %d: AI CLI killed by resource limit", iterNum), map[string]interface{}{
+				"limits":      limits,
+				"outcome":     string(OutcomeResourceLimit),
+				"consecutive": state.ConsecutiveFailures,
+			})
+			state.recordAttempt(OutcomeResourceLimit, "", result)
+			if state.AdaptiveTimeout != nil {
+				state.AdaptiveTimeout.recordKill(result.Duration, nil)
+			}
 			elapsed := time.Since(iterationStart)
 			state.Stats.updateStats(elapsed)
 			state.Iteration++
@@ -106,17 +206,56 @@ func RunLoop(state *IterationState, cfg config.Config, aiCmd config.AICommand, u
 			break
 		}
 
+		// Classify rate limits, auth errors and transient failures before the outcome matrix
+		class := ai.ClassifyResult(aiCmd.Options.Classifiers, result.Output, result.ExitCode)
+		if class == config.ClassAuthError {
+			logger.Error("Aborting: AI CLI reported an authentication error. Check the AI CLI's credentials and re-run.", map[string]interface{}{
+				"classification": string(class),
+				"exit_code":      result.ExitCode,
+				"ai_cmd":         aiCmd.Source,
+			})
+			state.recordAttempt(OutcomeFailure, class, result)
+			state.Status = StatusAborted
+			break
+		}
+		if class == config.ClassRateLimited || class == config.ClassTransient {
+			if state.Retries < state.RetryPolicy.MaxRetries {
+				delay := state.RetryPolicy.delay(class, state.Retries)
+				state.recordAttempt(OutcomeRetried, class, result)
+				state.Retries++
+				logger.Warn(fmt.Sprintf("Iteration %d: %s, retrying in %s", iterNum, class, formatDuration(delay)), map[string]interface{}{
+					"classification": string(class),
+					"exit_code":      result.ExitCode,
+					"retry":          fmt.Sprintf("%d/%d", state.Retries, state.RetryPolicy.MaxRetries),
+				})
+				if !sleepUnlessInterrupted(delay, sigChan) {
+					logger.Info("Interrupted by signal", nil)
+					state.Status = StatusInterrupted
+					break
+				}
+				continue
+			}
+			logger.Warn(fmt.Sprintf("Iteration %d: %s, retries exhausted", iterNum, class), map[string]interface{}{
+				"classification": string(class),
+				"max_retries":    state.RetryPolicy.MaxRetries,
+			})
+		}
+
 		// Determine outcome per matrix
 		outcome := DetectIterationFailure(IterationResult{
 			ExitCode: result.ExitCode,
 			Output:   result.Output,
 		})
+		state.recordAttempt(outcome, class, result)
 
 		// Scan for signals (for logging)
 		hasSuccess, hasFailure := ai.ScanOutputForSignals(result.Output)
 		_ = hasSuccess // Used for logging context
 
 		elapsed := time.Since(iterationStart)
+		if state.AdaptiveTimeout != nil {
+			state.AdaptiveTimeout.record(result.Duration)
+		}
 
 		switch outcome {
 		case OutcomeJobDone:
@@ -187,6 +326,18 @@ func RunLoop(state *IterationState, cfg config.Config, aiCmd config.AICommand, u
 	return state.Status
 }
 
+// sleepUnlessInterrupted waits for d, returning false if a signal arrives first.
+func sleepUnlessInterrupted(d time.Duration, sigChan <-chan os.Signal) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-sigChan:
+		return false
+	}
+}
+
 // logIterationStats displays iteration timing statistics
 func logIterationStats(logger *observability.Logger, stats *IterationStats) {
 	if stats.Count == 0 {
@@ -209,6 +360,18 @@ func logIterationStats(logger *observability.Logger, stats *IterationStats) {
 	logger.Info("Iteration timing:", fields)
 }
 
+// unsetLimit is logged for a kill by a limit the config does not set, as when
+// --replay returns a kill recorded under a different config.
+const unsetLimit = "recorded"
+
+// limitSeconds formats a limit in seconds for logging.
+func limitSeconds(seconds *int) string {
+	if seconds == nil {
+		return unsetLimit
+	}
+	return fmt.Sprintf("%ds", *seconds)
+}
+
 // formatDuration formats a duration in human-readable format (e.g., "1.23s", "2m 15s")
 func formatDuration(d time.Duration) string {
 	if d < time.Second {
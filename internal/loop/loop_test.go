@@ -1,9 +1,13 @@
 package loop
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"testing"
 	"time"
 
+	"github.com/jomadu/rooda/internal/ai"
 	"github.com/jomadu/rooda/internal/config"
 	"github.com/jomadu/rooda/internal/observability"
 )
@@ -239,3 +243,501 @@ func TestRunLoop_StatisticsUpdated(t *testing.T) {
 		t.Errorf("Expected non-zero max time")
 	}
 }
+
+func TestRunLoop_IdleTimeout(t *testing.T) {
+	tests := []struct {
+		name            string
+		countsAsFailure bool
+		wantStatus      LoopStatus
+		wantFailures    int
+		wantIterations  int
+	}{
+		{"counts as failure", true, StatusAborted, 1, 1},
+		{"does not count as failure", false, StatusMaxIters, 0, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maxIters := 2
+			idle := 1
+			state := &IterationState{
+				Iteration:          0,
+				MaxIterations:      &maxIters,
+				IdleTimeout:        &idle,
+				IdleTimeoutFailure: tt.countsAsFailure,
+				FailureThreshold:   1,
+				Status:             StatusRunning,
+				ProcedureName:      "test",
+				StartedAt:          time.Now(),
+				MaxOutputBuffer:    config.DefaultMaxOutputBuffer,
+			}
+
+			cfg := config.Config{
+				Procedures: map[string]config.Procedure{
+					"test": {
+						Act: []config.FragmentAction{{Content: "act"}},
+					},
+				},
+			}
+
+			aiCmd := config.AICommand{Command: "sleep 10", Source: "test"}
+			logger := observability.NewLogger(config.LogLevelError, config.TimestampNone, time.Now())
+
+			status := RunLoop(state, cfg, aiCmd, "", false, logger)
+
+			if status != tt.wantStatus {
+				t.Errorf("Expected status %s, got %s", tt.wantStatus, status)
+			}
+			if state.ConsecutiveFailures != tt.wantFailures {
+				t.Errorf("Expected %d consecutive failures, got %d", tt.wantFailures, state.ConsecutiveFailures)
+			}
+			if state.Iteration != tt.wantIterations {
+				t.Errorf("Expected %d iterations, got %d", tt.wantIterations, state.Iteration)
+			}
+		})
+	}
+}
+
+func TestRunLoop_AdaptiveTimeout(t *testing.T) {
+	maxIters := 1
+	state := &IterationState{
+		Iteration:        0,
+		MaxIterations:    &maxIters,
+		FailureThreshold: 3,
+		Status:           StatusRunning,
+		ProcedureName:    "test",
+		StartedAt:        time.Now(),
+		MaxOutputBuffer:  config.DefaultMaxOutputBuffer,
+		AdaptiveTimeout:  &AdaptiveTimeout{K: 3, Floor: 1, Ceiling: 1, MinSamples: 5},
+	}
+
+	cfg := config.Config{
+		Procedures: map[string]config.Procedure{
+			"test": {
+				Act: []config.FragmentAction{{Content: "act"}},
+			},
+		},
+	}
+
+	aiCmd := config.AICommand{Command: "sleep 10", Source: "test"}
+	logger := observability.NewLogger(config.LogLevelError, config.TimestampNone, time.Now())
+
+	start := time.Now()
+	RunLoop(state, cfg, aiCmd, "", false, logger)
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected adaptive ceiling to cut iteration at ~1s, took %v", elapsed)
+	}
+	if state.ConsecutiveFailures != 1 {
+		t.Errorf("expected timeout to count as failure, got %d", state.ConsecutiveFailures)
+	}
+	if state.AdaptiveTimeout.samples() != 1 || state.AdaptiveTimeout.History.MinTime < time.Second {
+		t.Errorf("expected timed-out iteration recorded at no less than its timeout, got %+v", state.AdaptiveTimeout.History)
+	}
+}
+
+func TestRunLoop_RateLimitedRetriesWithoutFailure(t *testing.T) {
+	dir := t.TempDir()
+	counter := dir + "/count"
+	// First two invocations report a rate limit, the third succeeds
+	script := "n=$(cat " + counter + " 2>/dev/null || echo 0); n=$((n+1)); echo $n > " + counter +
+		"; if [ $n -le 2 ]; then echo 'Error: 429 rate limit'; exit 1; fi; echo '<promise>SUCCESS</promise>'"
+
+	maxIters := 1
+	state := &IterationState{
+		MaxIterations:    &maxIters,
+		FailureThreshold: 1,
+		Status:           StatusRunning,
+		ProcedureName:    "test",
+		StartedAt:        time.Now(),
+		MaxOutputBuffer:  config.DefaultMaxOutputBuffer,
+		RetryPolicy:      RetryPolicy{Backoff: 10 * time.Millisecond, MaxBackoff: 20 * time.Millisecond, MaxRetries: 3},
+	}
+
+	cfg := config.Config{
+		Procedures: map[string]config.Procedure{
+			"test": {Act: []config.FragmentAction{{Content: "act"}}},
+		},
+	}
+
+	aiCmd := config.AICommand{
+		Command: "sh -c \"" + script + "\"",
+		Source:  "test",
+		Options: config.AliasOptions{Classifiers: []config.OutputClassifier{
+			{Class: config.ClassRateLimited, Pattern: "rate limit"},
+		}},
+	}
+	logger := observability.NewLogger(config.LogLevelError, config.TimestampNone, time.Now())
+
+	status := RunLoop(state, cfg, aiCmd, "", false, logger)
+
+	if status != StatusSuccess {
+		t.Fatalf("Expected status %s, got %s", StatusSuccess, status)
+	}
+	if state.Iteration != 1 {
+		t.Errorf("Expected retries not to consume iterations, got %d", state.Iteration)
+	}
+	if len(state.Records) != 3 {
+		t.Fatalf("Expected 3 records, got %d: %+v", len(state.Records), state.Records)
+	}
+	for i, want := range []IterationOutcome{OutcomeRetried, OutcomeRetried, OutcomeJobDone} {
+		rec := state.Records[i]
+		if rec.Outcome != want || rec.Attempt != i+1 || rec.Iteration != 1 {
+			t.Errorf("Record %d: expected outcome %s attempt %d, got %+v", i, want, i+1, rec)
+		}
+	}
+	if state.Records[0].Classification != config.ClassRateLimited {
+		t.Errorf("Expected rate_limited classification, got %q", state.Records[0].Classification)
+	}
+}
+
+func TestRunLoop_RetriesExhaustedCountsFailure(t *testing.T) {
+	maxIters := 1
+	state := &IterationState{
+		MaxIterations:    &maxIters,
+		FailureThreshold: 3,
+		Status:           StatusRunning,
+		ProcedureName:    "test",
+		StartedAt:        time.Now(),
+		MaxOutputBuffer:  config.DefaultMaxOutputBuffer,
+		RetryPolicy:      RetryPolicy{Backoff: time.Millisecond, MaxBackoff: time.Millisecond, MaxRetries: 2},
+	}
+
+	cfg := config.Config{
+		Procedures: map[string]config.Procedure{
+			"test": {Act: []config.FragmentAction{{Content: "act"}}},
+		},
+	}
+
+	aiCmd := config.AICommand{
+		Command: "sh -c 'echo connection reset; exit 1'",
+		Source:  "test",
+		Options: config.AliasOptions{Classifiers: []config.OutputClassifier{
+			{Class: config.ClassTransient, Pattern: "connection reset"},
+		}},
+	}
+	logger := observability.NewLogger(config.LogLevelError, config.TimestampNone, time.Now())
+
+	RunLoop(state, cfg, aiCmd, "", false, logger)
+
+	if state.ConsecutiveFailures != 1 {
+		t.Errorf("Expected exhausted retries to count one failure, got %d", state.ConsecutiveFailures)
+	}
+	if len(state.Records) != 3 {
+		t.Fatalf("Expected 3 records (2 retries + failure), got %d", len(state.Records))
+	}
+	last := state.Records[2]
+	if last.Outcome != OutcomeFailure || last.Classification != config.ClassTransient {
+		t.Errorf("Expected final failure classified transient, got %+v", last)
+	}
+}
+
+func TestRunLoop_AuthErrorAbortsImmediately(t *testing.T) {
+	maxIters := 5
+	state := &IterationState{
+		MaxIterations:    &maxIters,
+		FailureThreshold: 3,
+		Status:           StatusRunning,
+		ProcedureName:    "test",
+		StartedAt:        time.Now(),
+		MaxOutputBuffer:  config.DefaultMaxOutputBuffer,
+		RetryPolicy:      RetryPolicy{Backoff: time.Millisecond, MaxBackoff: time.Millisecond, MaxRetries: 5},
+	}
+
+	cfg := config.Config{
+		Procedures: map[string]config.Procedure{
+			"test": {Act: []config.FragmentAction{{Content: "act"}}},
+		},
+	}
+
+	aiCmd := config.AICommand{
+		Command: "sh -c 'echo Invalid API key; exit 1'",
+		Source:  "test",
+		Options: config.AliasOptions{Classifiers: []config.OutputClassifier{
+			{Class: config.ClassAuthError, Pattern: "(?i)invalid api key"},
+		}},
+	}
+	logger := observability.NewLogger(config.LogLevelError, config.TimestampNone, time.Now())
+
+	status := RunLoop(state, cfg, aiCmd, "", false, logger)
+
+	if status != StatusAborted {
+		t.Errorf("Expected status %s, got %s", StatusAborted, status)
+	}
+	if len(state.Records) != 1 || state.Records[0].Classification != config.ClassAuthError {
+		t.Errorf("Expected single auth_error record, got %+v", state.Records)
+	}
+}
+
+func TestRunLoop_ResourceLimitOutcome(t *testing.T) {
+	if !ai.ResourceLimitsSupported {
+		t.Skip("resource limits are only enforced on Linux")
+	}
+
+	maxIters := 3
+	cpu := 1
+	state := &IterationState{
+		MaxIterations:    &maxIters,
+		ResourceLimits:   &config.ResourceLimits{CPUSeconds: &cpu},
+		FailureThreshold: 1,
+		Status:           StatusRunning,
+		ProcedureName:    "test",
+		StartedAt:        time.Now(),
+		MaxOutputBuffer:  config.DefaultMaxOutputBuffer,
+	}
+
+	cfg := config.Config{
+		Procedures: map[string]config.Procedure{
+			"test": {
+				Act: []config.FragmentAction{{Content: "act"}},
+			},
+		},
+	}
+
+	aiCmd := config.AICommand{Command: "sh -c 'while :; do :; done'", Source: "test"}
+	logger := observability.NewLogger(config.LogLevelError, config.TimestampNone, time.Now())
+
+	status := RunLoop(state, cfg, aiCmd, "", false, logger)
+
+	if status != StatusAborted {
+		t.Errorf("Expected status %s, got %s", StatusAborted, status)
+	}
+	if len(state.Records) != 1 || state.Records[0].Outcome != OutcomeResourceLimit {
+		t.Errorf("Expected one resource-limit record, got %+v", state.Records)
+	}
+}
+
+func TestRunLoop_SessionContext(t *testing.T) {
+	tests := []struct {
+		mode          config.ContextMode
+		wantExitCodes []int
+	}{
+		{config.ContextFresh, []int{0, 0, 0}},
+		{config.ContextContinue, []int{0, 1, 1}},
+		{config.ContextContinueUntilFailure, []int{0, 1, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			maxIters := 3
+			state := &IterationState{
+				MaxIterations:    &maxIters,
+				FailureThreshold: 5,
+				Status:           StatusRunning,
+				ProcedureName:    "test",
+				StartedAt:        time.Now(),
+				MaxOutputBuffer:  config.DefaultMaxOutputBuffer,
+				ContextMode:      tt.mode,
+			}
+
+			cfg := config.Config{
+				Procedures: map[string]config.Procedure{
+					"test": {
+						Act: []config.FragmentAction{{Content: "act"}},
+					},
+				},
+			}
+
+			// A fresh session succeeds; a resumed one fails
+			aiCmd := config.AICommand{
+				Command: "echo 'session: abc'",
+				Source:  "test",
+				Options: config.AliasOptions{Session: &config.SessionAdapter{
+					IDPattern:     `session: (\S+)`,
+					ResumeCommand: "sh -c 'echo session: $0; exit 1' {session_id}",
+				}},
+			}
+			logger := observability.NewLogger(config.LogLevelError, config.TimestampNone, time.Now())
+
+			RunLoop(state, cfg, aiCmd, "", false, logger)
+
+			if len(state.Records) != len(tt.wantExitCodes) {
+				t.Fatalf("Expected %d records, got %+v", len(tt.wantExitCodes), state.Records)
+			}
+			for i, want := range tt.wantExitCodes {
+				if state.Records[i].ExitCode != want {
+					t.Errorf("Iteration %d: expected exit code %d, got %d", i+1, want, state.Records[i].ExitCode)
+				}
+			}
+		})
+	}
+}
+
+func TestRunLoop_SessionContextWithoutAdapter(t *testing.T) {
+	maxIters := 2
+	state := &IterationState{
+		MaxIterations:    &maxIters,
+		FailureThreshold: 3,
+		Status:           StatusRunning,
+		ProcedureName:    "test",
+		StartedAt:        time.Now(),
+		MaxOutputBuffer:  config.DefaultMaxOutputBuffer,
+		ContextMode:      config.ContextContinue,
+	}
+
+	cfg := config.Config{
+		Procedures: map[string]config.Procedure{
+			"test": {
+				Act: []config.FragmentAction{{Content: "act"}},
+			},
+		},
+	}
+
+	aiCmd := config.AICommand{Command: "echo 'session: abc'", Source: "test"}
+	logger := observability.NewLogger(config.LogLevelError, config.TimestampNone, time.Now())
+
+	status := RunLoop(state, cfg, aiCmd, "", false, logger)
+
+	if status != StatusMaxIters {
+		t.Errorf("Expected status %s, got %s", StatusMaxIters, status)
+	}
+	if state.SessionID != "" {
+		t.Errorf("Expected no session captured without an adapter, got %q", state.SessionID)
+	}
+}
+
+func TestRunLoop_HTTPBackend(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"<promise>SUCCESS</promise>"}}]}`))
+	}))
+	defer server.Close()
+
+	maxIters := 3
+	state := &IterationState{
+		MaxIterations:    &maxIters,
+		FailureThreshold: 3,
+		Status:           StatusRunning,
+		ProcedureName:    "test",
+		StartedAt:        time.Now(),
+		MaxOutputBuffer:  config.DefaultMaxOutputBuffer,
+	}
+
+	cfg := config.Config{
+		Procedures: map[string]config.Procedure{
+			"test": {
+				Act: []config.FragmentAction{{Content: "act"}},
+			},
+		},
+	}
+
+	aiCmd := config.AICommand{
+		Source: "test",
+		Options: config.AliasOptions{
+			Backend: config.BackendHTTP,
+			HTTP:    &config.HTTPBackend{BaseURL: server.URL, Model: "m"},
+		},
+	}
+	logger := observability.NewLogger(config.LogLevelError, config.TimestampNone, time.Now())
+
+	status := RunLoop(state, cfg, aiCmd, "", false, logger)
+
+	if status != StatusSuccess {
+		t.Errorf("Expected status %s, got %s", StatusSuccess, status)
+	}
+}
+
+func TestRunLoop_RecordAndReplay(t *testing.T) {
+	cfg := config.Config{
+		Procedures: map[string]config.Procedure{
+			"test": {
+				Act: []config.FragmentAction{{Content: "act"}},
+			},
+		},
+	}
+	logger := observability.NewLogger(config.LogLevelError, config.TimestampNone, time.Now())
+	newState := func() *IterationState {
+		maxIters := 5
+		return &IterationState{
+			MaxIterations:    &maxIters,
+			FailureThreshold: 3,
+			Status:           StatusRunning,
+			ProcedureName:    "test",
+			StartedAt:        time.Now(),
+			MaxOutputBuffer:  config.DefaultMaxOutputBuffer,
+		}
+	}
+
+	// Record: fails once, then signals success
+	dir := t.TempDir()
+	marker := dir + "/ran-once"
+	recorder, err := ai.NewRecorder(dir+"/run", ai.RunInfo{Procedure: "test"})
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+	recorded := newState()
+	recorded.Recorder = recorder
+	aiCmd := config.AICommand{
+		Command: "sh -c 'if [ -e " + marker + " ]; then echo \"<promise>SUCCESS</promise>\"; else touch " + marker + "; exit 1; fi'",
+		Source:  "test",
+	}
+	recordedStatus := RunLoop(recorded, cfg, aiCmd, "", false, logger)
+	recorder.Close()
+
+	// Replay against a command that would always fail if it were run
+	replay, err := ai.LoadReplay(dir + "/run")
+	if err != nil {
+		t.Fatalf("LoadReplay failed: %v", err)
+	}
+	mismatches := 0
+	replay.OnPromptMismatch = func(int) { mismatches++ }
+	replayed := newState()
+	replayed.Executor = replay
+	replayedStatus := RunLoop(replayed, cfg, config.AICommand{Command: "false", Source: "test"}, "", false, logger)
+
+	if recordedStatus != StatusSuccess || replayedStatus != recordedStatus {
+		t.Errorf("Expected success recorded and replayed, got %s and %s", recordedStatus, replayedStatus)
+	}
+	if replayed.Iteration != 2 || len(replayed.Records) != 2 || replayed.Records[0].Outcome != OutcomeFailure {
+		t.Errorf("Expected replay to reproduce failure then success, got %+v", replayed.Records)
+	}
+	if mismatches != 0 {
+		t.Errorf("Expected prompts to match the recording, got %d mismatches", mismatches)
+	}
+}
+
+func TestRunLoop_ReplayedKillWithoutLimits(t *testing.T) {
+	cfg := config.Config{
+		Procedures: map[string]config.Procedure{
+			"test": {
+				Act: []config.FragmentAction{{Content: "act"}},
+			},
+		},
+	}
+	logger := observability.NewLogger(config.LogLevelError, config.TimestampNone, time.Now())
+
+	// A recording made with limits set, replayed under a config that sets none
+	for name, want := range map[string]IterationOutcome{
+		"timeout":        OutcomeTimeout,
+		"idle-timeout":   OutcomeIdleTimeout,
+		"resource-limit": OutcomeResourceLimit,
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			os.WriteFile(dir+"/run.json", []byte(`{"procedure":"test"}`), 0644)
+			os.WriteFile(dir+"/iterations.jsonl", []byte(`{"duration_ms":1000,"error":"`+name+`"}`+"\n"), 0644)
+			replay, err := ai.LoadReplay(dir)
+			if err != nil {
+				t.Fatalf("LoadReplay failed: %v", err)
+			}
+
+			maxIters := 1
+			state := &IterationState{
+				MaxIterations:    &maxIters,
+				FailureThreshold: 3,
+				Status:           StatusRunning,
+				ProcedureName:    "test",
+				StartedAt:        time.Now(),
+				MaxOutputBuffer:  config.DefaultMaxOutputBuffer,
+				Executor:         replay,
+			}
+			status := RunLoop(state, cfg, config.AICommand{Command: "false", Source: "test"}, "", false, logger)
+
+			if status != StatusMaxIters {
+				t.Errorf("Expected status %s, got %s", StatusMaxIters, status)
+			}
+			if len(state.Records) != 1 || state.Records[0].Outcome != want {
+				t.Errorf("Expected one %s record, got %+v", want, state.Records)
+			}
+		})
+	}
+}
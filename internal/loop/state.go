@@ -3,6 +3,9 @@ package loop
 import (
 	"math"
 	"time"
+
+	"github.com/jomadu/rooda/internal/ai"
+	"github.com/jomadu/rooda/internal/config"
 )
 
 // LoopStatus represents the current state of the iteration loop
@@ -18,26 +21,95 @@ const (
 
 // IterationState tracks the state of the iteration loop
 type IterationState struct {
-	Iteration           int            // Current iteration number (0-indexed)
-	MaxIterations       *int           // Termination threshold (nil = unlimited)
-	IterationTimeout    *int           // Per-iteration timeout in seconds (nil = no timeout)
-	MaxOutputBuffer     int            // Max AI CLI output buffer size in bytes (default: 10485760 = 10MB)
-	ConsecutiveFailures int            // Consecutive AI CLI failures
-	FailureThreshold    int            // Max consecutive failures before abort (default: 3)
-	StartedAt           time.Time      // When the loop started
-	Status              LoopStatus     // running, completed, aborted, interrupted
-	ProcedureName       string         // Name of the procedure being executed
-	Stats               IterationStats // Running statistics for iteration timing
+	Iteration           int                    // Current iteration number (0-indexed)
+	MaxIterations       *int                   // Termination threshold (nil = unlimited)
+	IterationTimeout    *int                   // Per-iteration timeout in seconds (nil = no timeout)
+	AdaptiveTimeout     *AdaptiveTimeout       // Overrides IterationTimeout when set (iteration_timeout: auto)
+	IdleTimeout         *int                   // Seconds without AI CLI output before kill (nil = no watchdog)
+	IdleTimeoutFailure  bool                   // Whether idle timeouts count toward FailureThreshold
+	ResourceLimits      *config.ResourceLimits // Limits for the AI CLI's process group (nil = none)
+	MaxOutputBuffer     int                    // Max AI CLI output buffer size in bytes (default: 10485760 = 10MB)
+	ConsecutiveFailures int                    // Consecutive AI CLI failures
+	FailureThreshold    int                    // Max consecutive failures before abort (default: 3)
+	StartedAt           time.Time              // When the loop started
+	Status              LoopStatus             // running, completed, aborted, interrupted
+	ProcedureName       string                 // Name of the procedure being executed
+	Stats               IterationStats         // Running statistics for iteration timing
+	RetryPolicy         RetryPolicy            // Backoff for iterations classified rate_limited/transient
+	Retries             int                    // Classified retries of the current iteration so far
+	Records             []IterationRecord      // One record per AI CLI invocation, including retries
+	ContextMode         config.ContextMode     // Session continuity between iterations ("" = fresh)
+	SessionID           string                 // AI CLI session to resume in the next invocation ("" = start fresh)
+	Recorder            *ai.Recorder           // Saves each AI invocation when recording (nil = not recording)
+	Executor            ai.Executor            // Replaces the AI, e.g. with a replayed or scripted run (nil = execute normally)
+}
+
+// RetryPolicy controls retries of iterations classified as rate-limited or transient.
+type RetryPolicy struct {
+	Backoff    time.Duration // Delay before a retry; rate-limited retries double it each time
+	MaxBackoff time.Duration // Cap for exponential rate-limit backoff
+	MaxRetries int           // Retries per iteration before it counts as an ordinary failure
+}
+
+// delay returns how long to wait before retry number retry (0-indexed).
+// Rate limits back off exponentially; transient errors retry at a fixed interval.
+func (p RetryPolicy) delay(class config.ErrorClass, retry int) time.Duration {
+	if class != config.ClassRateLimited {
+		return p.Backoff
+	}
+	d := p.Backoff
+	for i := 0; i < retry && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// IterationRecord summarizes one AI CLI invocation.
+type IterationRecord struct {
+	Iteration      int               // 1-indexed iteration number
+	Attempt        int               // 1-indexed attempt within the iteration (> 1 after classified retries)
+	Outcome        IterationOutcome  // How the loop treated this invocation
+	Classification config.ErrorClass // Matched alias classifier ("" if none)
+	ExitCode       int               // AI CLI exit code
+	Duration       time.Duration     // AI CLI execution time
+	SessionID      string            // AI CLI session after this invocation ("" when not continuing sessions)
+}
+
+// recordAttempt appends an IterationRecord for the current iteration and resets
+// the retry counter once the iteration is settled (any outcome other than a retry).
+// Under context: continue-until-failure a failed attempt also ends the session.
+func (s *IterationState) recordAttempt(outcome IterationOutcome, class config.ErrorClass, result ai.AIExecutionResult) {
+	s.Records = append(s.Records, IterationRecord{
+		Iteration:      s.Iteration + 1,
+		Attempt:        s.Retries + 1,
+		Outcome:        outcome,
+		Classification: class,
+		ExitCode:       result.ExitCode,
+		Duration:       result.Duration,
+		SessionID:      s.SessionID,
+	})
+	if outcome != OutcomeRetried {
+		s.Retries = 0
+	}
+	if s.ContextMode == config.ContextContinueUntilFailure {
+		switch outcome {
+		case OutcomeFailure, OutcomeTimeout, OutcomeIdleTimeout, OutcomeResourceLimit:
+			s.SessionID = ""
+		}
+	}
 }
 
 // IterationStats tracks iteration timing statistics using Welford's online algorithm
 // for constant memory usage regardless of iteration count
 type IterationStats struct {
-	Count     int           // Total iterations completed
-	TotalTime time.Duration // Sum of all iteration durations
-	MinTime   time.Duration // Fastest iteration (0 if no iterations)
-	MaxTime   time.Duration // Slowest iteration (0 if no iterations)
-	M2        float64       // Sum of squared differences from mean (for variance calculation)
+	Count     int           `json:"count"`      // Total iterations completed
+	TotalTime time.Duration `json:"total_time"` // Sum of all iteration durations
+	MinTime   time.Duration `json:"min_time"`   // Fastest iteration (0 if no iterations)
+	MaxTime   time.Duration `json:"max_time"`   // Slowest iteration (0 if no iterations)
+	M2        float64       `json:"m2"`         // Sum of squared differences from mean (for variance calculation)
 }
 
 // updateStats updates iteration statistics using Welford's online algorithm
@@ -57,16 +129,16 @@ func (s *IterationStats) updateStats(duration time.Duration) {
 	// Welford's online algorithm for variance
 	// See: https://en.wikipedia.org/wiki/Algorithms_for_calculating_variance#Welford's_online_algorithm
 	durationSeconds := duration.Seconds()
-	
+
 	// Calculate old mean BEFORE updating count
 	oldMean := 0.0
 	if s.Count > 1 {
 		oldMean = (s.TotalTime - duration).Seconds() / float64(s.Count-1)
 	}
-	
+
 	// Calculate new mean AFTER updating count
 	newMean := s.TotalTime.Seconds() / float64(s.Count)
-	
+
 	// M2 accumulates the squared distance from the mean
 	// M2 = M2 + (x - old_mean) * (x - new_mean)
 	s.M2 += (durationSeconds - oldMean) * (durationSeconds - newMean)
@@ -85,10 +157,64 @@ func (s *IterationStats) getStdDev() time.Duration {
 	if s.Count < 2 {
 		return 0
 	}
-	
+
 	// Variance = M2 / count (population variance)
 	variance := s.M2 / float64(s.Count)
 	stddevSeconds := math.Sqrt(variance)
-	
+
 	return time.Duration(stddevSeconds * float64(time.Second))
 }
+
+// AdaptiveTimeout derives per-iteration timeouts from recorded iteration durations
+// (iteration_timeout: auto). History persists across runs via StatsStore.
+type AdaptiveTimeout struct {
+	K          float64         // Standard deviations above the mean
+	Floor      int             // Minimum timeout in seconds
+	Ceiling    int             // Maximum timeout in seconds (used until MinSamples is reached)
+	MinSamples int             // Recorded durations required before adapting
+	History    *IterationStats // Durations of recorded iterations, killed ones included
+}
+
+// Timeout returns the timeout in seconds for the next iteration:
+// mean + K*stddev of History, clamped to [Floor, Ceiling].
+func (a *AdaptiveTimeout) Timeout() int {
+	if a.samples() < a.MinSamples {
+		return a.Ceiling
+	}
+
+	seconds := a.History.getMean().Seconds() + a.K*a.History.getStdDev().Seconds()
+	timeout := int(math.Ceil(seconds))
+	if timeout < a.Floor {
+		return a.Floor
+	}
+	if timeout > a.Ceiling {
+		return a.Ceiling
+	}
+	return timeout
+}
+
+// samples returns the number of recorded durations.
+func (a *AdaptiveTimeout) samples() int {
+	if a.History == nil {
+		return 0
+	}
+	return a.History.Count
+}
+
+// record adds an iteration duration to History.
+func (a *AdaptiveTimeout) record(duration time.Duration) {
+	if a.History == nil {
+		a.History = &IterationStats{}
+	}
+	a.History.updateStats(duration)
+}
+
+// recordKill adds a killed iteration to History at its elapsed duration, and
+// at no less than the timeout that killed it. Without these samples History
+// never reaches the current limit, so a limit that is too tight could not grow.
+func (a *AdaptiveTimeout) recordKill(elapsed time.Duration, timeout *int) {
+	if timeout != nil {
+		elapsed = max(elapsed, time.Duration(*timeout)*time.Second)
+	}
+	a.record(elapsed)
+}
@@ -4,6 +4,8 @@ import (
 	"math"
 	"testing"
 	"time"
+
+	"github.com/jomadu/rooda/internal/config"
 )
 
 func TestIterationStats_InitialState(t *testing.T) {
@@ -173,3 +175,77 @@ func TestIterationStats_ConstantMemory(t *testing.T) {
 		t.Error("expected non-zero stddev")
 	}
 }
+
+func TestAdaptiveTimeout_UsesCeilingUntilMinSamples(t *testing.T) {
+	adaptive := &AdaptiveTimeout{K: 3, Floor: 10, Ceiling: 600, MinSamples: 3}
+
+	if got := adaptive.Timeout(); got != 600 {
+		t.Errorf("expected ceiling with no history, got %d", got)
+	}
+
+	adaptive.record(20 * time.Second)
+	adaptive.record(30 * time.Second)
+	if got := adaptive.Timeout(); got != 600 {
+		t.Errorf("expected ceiling below min samples, got %d", got)
+	}
+}
+
+func TestAdaptiveTimeout_MeanPlusKStdDev(t *testing.T) {
+	adaptive := &AdaptiveTimeout{K: 2, Floor: 10, Ceiling: 600, MinSamples: 3}
+	// Durations 20s, 30s, 40s: mean=30s, population stddev≈8.165s
+	adaptive.record(20 * time.Second)
+	adaptive.record(30 * time.Second)
+	adaptive.record(40 * time.Second)
+
+	want := int(math.Ceil(30 + 2*math.Sqrt(200.0/3.0)))
+	if got := adaptive.Timeout(); got != want {
+		t.Errorf("expected timeout %d, got %d", want, got)
+	}
+}
+
+func TestAdaptiveTimeout_Clamped(t *testing.T) {
+	fast := &AdaptiveTimeout{K: 3, Floor: 60, Ceiling: 600, MinSamples: 2}
+	fast.record(1 * time.Second)
+	fast.record(2 * time.Second)
+	if got := fast.Timeout(); got != 60 {
+		t.Errorf("expected floor 60, got %d", got)
+	}
+
+	slow := &AdaptiveTimeout{K: 3, Floor: 60, Ceiling: 600, MinSamples: 2}
+	slow.record(500 * time.Second)
+	slow.record(900 * time.Second)
+	if got := slow.Timeout(); got != 600 {
+		t.Errorf("expected ceiling 600, got %d", got)
+	}
+}
+
+func TestAdaptiveTimeout_TimeoutsRaiseLimit(t *testing.T) {
+	adaptive := &AdaptiveTimeout{K: 2, Floor: 10, Ceiling: 600, MinSamples: 3}
+	adaptive.record(20 * time.Second)
+	adaptive.record(30 * time.Second)
+	adaptive.record(40 * time.Second)
+
+	// Each iteration needs more than the limit and is killed just after it
+	for i := 0; i < 5; i++ {
+		limit := adaptive.Timeout()
+		adaptive.recordKill(time.Duration(limit)*time.Second-time.Millisecond, &limit)
+		if got := adaptive.Timeout(); got <= limit {
+			t.Fatalf("timeout %d: expected the limit to grow past %ds, got %ds", i+1, limit, got)
+		}
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := RetryPolicy{Backoff: 5 * time.Second, MaxBackoff: 30 * time.Second, MaxRetries: 5}
+
+	rateLimited := []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second, 30 * time.Second, 30 * time.Second}
+	for retry, want := range rateLimited {
+		if got := policy.delay(config.ClassRateLimited, retry); got != want {
+			t.Errorf("rate_limited retry %d: expected %v, got %v", retry, want, got)
+		}
+	}
+
+	if got := policy.delay(config.ClassTransient, 3); got != 5*time.Second {
+		t.Errorf("transient: expected fixed 5s, got %v", got)
+	}
+}
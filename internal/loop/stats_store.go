@@ -0,0 +1,69 @@
+package loop
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DefaultStatsPath is the file, relative to the workspace root, where iteration
+// durations persist across runs.
+const DefaultStatsPath = ".rooda/stats.json"
+
+// StatsStore persists per-procedure iteration statistics for adaptive timeouts.
+type StatsStore struct {
+	path       string
+	Procedures map[string]IterationStats `json:"procedures"`
+}
+
+// NewStatsStore returns an empty store that saves to path.
+func NewStatsStore(path string) *StatsStore {
+	return &StatsStore{path: path, Procedures: make(map[string]IterationStats)}
+}
+
+// LoadStatsStore reads the stats file at path. A missing file yields an empty store.
+func LoadStatsStore(path string) (*StatsStore, error) {
+	store := NewStatsStore(path)
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("invalid stats file %s: %w", path, err)
+	}
+	if store.Procedures == nil {
+		store.Procedures = make(map[string]IterationStats)
+	}
+	return store, nil
+}
+
+// Get returns a copy of the recorded statistics for a procedure.
+func (s *StatsStore) Get(procedureName string) *IterationStats {
+	stats := s.Procedures[procedureName]
+	return &stats
+}
+
+// Set replaces the recorded statistics for a procedure.
+func (s *StatsStore) Set(procedureName string, stats IterationStats) {
+	s.Procedures[procedureName] = stats
+}
+
+// Save writes the store back to its file, creating the parent directory if needed.
+func (s *StatsStore) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, append(data, '\n'), 0644)
+}
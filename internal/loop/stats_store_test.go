@@ -0,0 +1,53 @@
+package loop
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStatsStore_MissingFileIsEmpty(t *testing.T) {
+	store, err := LoadStatsStore(filepath.Join(t.TempDir(), "stats.json"))
+	if err != nil {
+		t.Fatalf("expected no error for missing file, got %v", err)
+	}
+	if got := store.Get("build"); got.Count != 0 {
+		t.Errorf("expected empty stats, got count %d", got.Count)
+	}
+}
+
+func TestStatsStore_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".rooda", "stats.json")
+	store := NewStatsStore(path)
+
+	stats := IterationStats{}
+	stats.updateStats(10 * time.Second)
+	stats.updateStats(20 * time.Second)
+	store.Set("build", stats)
+
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := LoadStatsStore(path)
+	if err != nil {
+		t.Fatalf("LoadStatsStore failed: %v", err)
+	}
+	got := loaded.Get("build")
+	if got.Count != 2 || got.TotalTime != 30*time.Second || got.M2 != stats.M2 {
+		t.Errorf("expected %+v, got %+v", stats, *got)
+	}
+	if loaded.Get("plan").Count != 0 {
+		t.Error("expected other procedures to be empty")
+	}
+}
+
+func TestStatsStore_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.json")
+	os.WriteFile(path, []byte("not json"), 0644)
+
+	if _, err := LoadStatsStore(path); err == nil {
+		t.Error("expected error for invalid stats file")
+	}
+}
@@ -0,0 +1,383 @@
+// Package pack installs procedure packs, versioned bundles of procedures and
+// fragments, into a config directory, and records and verifies the installed
+// files in a lockfile.
+package pack
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jomadu/rooda/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestFile names a pack and its version, at the root of the pack.
+const ManifestFile = "rooda-pack.yml"
+
+// LockFile records the packs installed in a config directory.
+const LockFile = "rooda-pack.lock"
+
+// ProceduresDir holds a pack's config files, merged like procedures.d files.
+const ProceduresDir = "procedures"
+
+var packName = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// Manifest describes a pack.
+type Manifest struct {
+	Name        string `yaml:"name"`
+	Version     string `yaml:"version"`
+	Description string `yaml:"description"`
+}
+
+// Lock is the contents of rooda-pack.lock.
+type Lock struct {
+	Packs map[string]LockedPack `yaml:"packs"`
+}
+
+// LockedPack records what was installed for one pack.
+type LockedPack struct {
+	Version string            `yaml:"version"`
+	Source  string            `yaml:"source"`           // Absolute path installed from
+	Commit  string            `yaml:"commit,omitempty"` // HEAD of the source when it is a git checkout
+	Files   map[string]string `yaml:"files"`            // Slash path within the pack -> "sha256:<hex>"
+}
+
+// Problem is a difference between an installed pack and its lock entry.
+type Problem struct {
+	Pack  string
+	File  string // Slash path within the pack ("" for the pack as a whole)
+	Issue string
+}
+
+func (p Problem) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("%s: %s", p.Pack, p.Issue)
+	}
+	return fmt.Sprintf("%s: %s %s", p.Pack, p.File, p.Issue)
+}
+
+// Dir returns where packs are installed under root, the global config
+// directory or the workspace's .rooda directory.
+func Dir(root string) string {
+	return filepath.Join(root, config.PacksDir)
+}
+
+// LoadManifest reads and checks the manifest of the pack in dir.
+func LoadManifest(dir string) (*Manifest, error) {
+	path := filepath.Join(dir, ManifestFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if !packName.MatchString(m.Name) {
+		return nil, fmt.Errorf("%s: name %q must be lowercase letters, digits, '.', '_' or '-'", path, m.Name)
+	}
+	if m.Version == "" {
+		return nil, fmt.Errorf("%s: version is required", path)
+	}
+	return &m, nil
+}
+
+// ReadLock reads the lockfile under root. A missing lockfile is an empty lock.
+func ReadLock(root string) (*Lock, error) {
+	lock := &Lock{Packs: make(map[string]LockedPack)}
+	data, err := os.ReadFile(filepath.Join(root, LockFile))
+	if os.IsNotExist(err) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Join(root, LockFile), err)
+	}
+	if lock.Packs == nil {
+		lock.Packs = make(map[string]LockedPack)
+	}
+	return lock, nil
+}
+
+// Write saves the lock under root.
+func (l *Lock) Write(root string) error {
+	var b strings.Builder
+	b.WriteString("# Written by rooda pack install. Do not edit; run rooda pack verify to check installed packs.\n")
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(l); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(root, LockFile), []byte(b.String()), 0644)
+}
+
+// Names returns the locked pack names in order.
+func (l *Lock) Names() []string {
+	names := make([]string, 0, len(l.Packs))
+	for name := range l.Packs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Install copies the pack at source into root's packs directory, replacing
+// any installed version, and records it in root's lockfile.
+func Install(source string, root string) (*Manifest, LockedPack, error) {
+	source, err := filepath.Abs(source)
+	if err != nil {
+		return nil, LockedPack{}, err
+	}
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return nil, LockedPack{}, err
+	}
+	manifest, err := LoadManifest(source)
+	if err != nil {
+		return nil, LockedPack{}, err
+	}
+	if _, err := Procedures(source); err != nil {
+		return nil, LockedPack{}, err
+	}
+	lock, err := ReadLock(root)
+	if err != nil {
+		return nil, LockedPack{}, err
+	}
+
+	// Copy into a staging directory so a failed copy leaves the old version in place
+	packs := Dir(root)
+	if err := os.MkdirAll(packs, 0755); err != nil {
+		return nil, LockedPack{}, err
+	}
+	staging, err := os.MkdirTemp(packs, "."+manifest.Name+"-")
+	if err != nil {
+		return nil, LockedPack{}, err
+	}
+	defer os.RemoveAll(staging)
+
+	// A pack installed into its own workspace must not copy the packs and lockfile there
+	files, err := copyPack(source, staging, root)
+	if err != nil {
+		return nil, LockedPack{}, fmt.Errorf("failed to copy pack: %w", err)
+	}
+
+	// Move the old version aside, not away, so it can be restored if the new one can't be moved in
+	target := filepath.Join(packs, manifest.Name)
+	previous := staging + ".previous"
+	if err := os.Rename(target, previous); err != nil && !os.IsNotExist(err) {
+		return nil, LockedPack{}, err
+	}
+	if err := os.Rename(staging, target); err != nil {
+		if restoreErr := os.Rename(previous, target); restoreErr != nil && !os.IsNotExist(restoreErr) {
+			return nil, LockedPack{}, fmt.Errorf("%w (the previous version is left in %s: %v)", err, previous, restoreErr)
+		}
+		return nil, LockedPack{}, err
+	}
+	if err := os.RemoveAll(previous); err != nil {
+		return nil, LockedPack{}, err
+	}
+
+	locked := LockedPack{
+		Version: manifest.Version,
+		Source:  source,
+		Commit:  gitCommit(source),
+		Files:   files,
+	}
+	lock.Packs[manifest.Name] = locked
+	if err := lock.Write(root); err != nil {
+		return nil, LockedPack{}, err
+	}
+	return manifest, locked, nil
+}
+
+// Verify compares every pack in root's lockfile with the installed files,
+// reporting modified, missing and unexpected files and version drift.
+func Verify(root string) ([]Problem, error) {
+	lock, err := ReadLock(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []Problem
+	for _, name := range lock.Names() {
+		locked := lock.Packs[name]
+		dir := filepath.Join(Dir(root), name)
+		if _, err := os.Stat(dir); err != nil {
+			problems = append(problems, Problem{Pack: name, Issue: "is not installed"})
+			continue
+		}
+
+		if manifest, err := LoadManifest(dir); err != nil {
+			problems = append(problems, Problem{Pack: name, File: ManifestFile, Issue: "is unreadable: " + err.Error()})
+		} else if manifest.Version != locked.Version {
+			problems = append(problems, Problem{Pack: name, Issue: fmt.Sprintf("version is %s, lockfile has %s", manifest.Version, locked.Version)})
+		}
+
+		installed, err := hashTree(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range sortedKeys(locked.Files) {
+			hash, ok := installed[file]
+			switch {
+			case !ok:
+				problems = append(problems, Problem{Pack: name, File: file, Issue: "is missing"})
+			case hash != locked.Files[file]:
+				problems = append(problems, Problem{Pack: name, File: file, Issue: "was modified"})
+			}
+		}
+		for _, file := range sortedKeys(installed) {
+			if _, ok := locked.Files[file]; !ok {
+				problems = append(problems, Problem{Pack: name, File: file, Issue: "is not in the lockfile"})
+			}
+		}
+	}
+	return problems, nil
+}
+
+// Procedures lists the procedures defined by the pack in dir, by name, with
+// their summaries.
+func Procedures(dir string) (map[string]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, ProceduresDir, "*.yml"))
+	if err != nil {
+		return nil, err
+	}
+	procedures := make(map[string]string)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var cf struct {
+			Procedures map[string]struct {
+				Summary string `yaml:"summary"`
+			} `yaml:"procedures"`
+		}
+		if err := yaml.Unmarshal(data, &cf); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		for name, proc := range cf.Procedures {
+			procedures[name] = proc.Summary
+		}
+	}
+	return procedures, nil
+}
+
+// copyPack copies the regular files of the pack at src into dst, skipping
+// version control metadata and the directory skip, and returns their hashes.
+func copyPack(src string, dst string, skip string) (map[string]string, error) {
+	files := make(map[string]string)
+	err := walkPack(src, skip, func(path string, rel string) error {
+		target := filepath.Join(dst, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		hash, err := copyFile(path, target)
+		if err != nil {
+			return err
+		}
+		files[rel] = hash
+		return nil
+	})
+	return files, err
+}
+
+// hashTree returns the hash of every regular file under dir, by slash path.
+func hashTree(dir string) (map[string]string, error) {
+	files := make(map[string]string)
+	err := walkPack(dir, "", func(path string, rel string) error {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return err
+		}
+		files[rel] = "sha256:" + hex.EncodeToString(h.Sum(nil))
+		return nil
+	})
+	return files, err
+}
+
+// walkPack calls fn for each regular file under dir, with its slash path
+// relative to dir. .git directories and the directory skip, if set, are
+// skipped.
+func walkPack(dir string, skip string, fn func(path string, rel string) error) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" || path == skip {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		return fn(path, filepath.ToSlash(rel))
+	})
+}
+
+func copyFile(src string, dst string) (string, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(out, h), in); err != nil {
+		out.Close()
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// gitCommit returns the HEAD commit of dir when it is a git checkout, or "".
+func gitCommit(dir string) string {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		return ""
+	}
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,152 @@
+package pack
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePack creates a pack source directory with one procedure and fragment.
+func writePack(t *testing.T, version string) string {
+	t.Helper()
+	src := t.TempDir()
+	files := map[string]string{
+		ManifestFile:               "name: team\nversion: " + version + "\ndescription: Team prompts\n",
+		"procedures/review.yml":    "procedures:\n  review:\n    summary: Review changes\n    act:\n      - path: ../fragments/review.md\n",
+		"fragments/review.md":      "Review the diff.\n",
+		".git/HEAD":                "ref: refs/heads/main\n",
+		"procedures/secondary.yml": "procedures:\n  triage:\n    summary: Triage issues\n",
+	}
+	for rel, content := range files {
+		path := filepath.Join(src, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return src
+}
+
+func TestInstall(t *testing.T) {
+	src := writePack(t, "1.0.0")
+	root := t.TempDir()
+
+	manifest, locked, err := Install(src, root)
+	if err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+	if manifest.Name != "team" || locked.Version != "1.0.0" || locked.Source != src {
+		t.Errorf("Unexpected install result: %+v %+v", manifest, locked)
+	}
+	if len(locked.Files) != 4 {
+		t.Errorf("Expected 4 files (no .git), got %v", locked.Files)
+	}
+	if !strings.HasPrefix(locked.Files["fragments/review.md"], "sha256:") {
+		t.Errorf("Expected sha256 hash, got %q", locked.Files["fragments/review.md"])
+	}
+	if _, err := os.Stat(filepath.Join(Dir(root), "team", "fragments", "review.md")); err != nil {
+		t.Errorf("Expected fragment installed: %v", err)
+	}
+
+	lock, err := ReadLock(root)
+	if err != nil {
+		t.Fatalf("ReadLock failed: %v", err)
+	}
+	if got := lock.Packs["team"]; got.Version != "1.0.0" || len(got.Files) != 4 {
+		t.Errorf("Unexpected lock entry: %+v", got)
+	}
+
+	procedures, err := Procedures(filepath.Join(Dir(root), "team"))
+	if err != nil || procedures["review"] != "Review changes" || procedures["triage"] != "Triage issues" {
+		t.Errorf("Unexpected procedures %v (err %v)", procedures, err)
+	}
+
+	// Reinstalling a new version replaces the old files
+	os.Remove(filepath.Join(src, "procedures", "secondary.yml"))
+	os.WriteFile(filepath.Join(src, ManifestFile), []byte("name: team\nversion: 1.1.0\n"), 0644)
+	if _, _, err := Install(src, root); err != nil {
+		t.Fatalf("Reinstall failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(Dir(root), "team", "procedures", "secondary.yml")); !os.IsNotExist(err) {
+		t.Errorf("Expected removed file to be gone after reinstall, got %v", err)
+	}
+	if problems, err := Verify(root); err != nil || len(problems) != 0 {
+		t.Errorf("Expected clean verify after reinstall, got %v (err %v)", problems, err)
+	}
+}
+
+func TestInstallRejectsInvalidManifest(t *testing.T) {
+	src := t.TempDir()
+	os.WriteFile(filepath.Join(src, ManifestFile), []byte("name: Team Pack\nversion: 1.0.0\n"), 0644)
+	if _, _, err := Install(src, t.TempDir()); err == nil || !strings.Contains(err.Error(), "must be lowercase") {
+		t.Errorf("Expected name error, got %v", err)
+	}
+
+	os.WriteFile(filepath.Join(src, ManifestFile), []byte("name: team\n"), 0644)
+	if _, _, err := Install(src, t.TempDir()); err == nil || !strings.Contains(err.Error(), "version is required") {
+		t.Errorf("Expected version error, got %v", err)
+	}
+}
+
+func TestInstallIntoOwnWorkspace(t *testing.T) {
+	src := writePack(t, "1.0.0")
+	root := filepath.Join(src, ".rooda")
+
+	// As rooda pack install . --workspace from the pack's own repository, twice
+	for i := 0; i < 2; i++ {
+		_, locked, err := Install(src, root)
+		if err != nil {
+			t.Fatalf("Install %d failed: %v", i+1, err)
+		}
+		if len(locked.Files) != 4 {
+			t.Errorf("Install %d: expected the 4 pack files without the packs directory and lockfile, got %v", i+1, locked.Files)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(Dir(root), "team", ".rooda")); !os.IsNotExist(err) {
+		t.Errorf("Expected the packs directory not to be copied into the pack, got %v", err)
+	}
+	if entries, _ := os.ReadDir(Dir(root)); len(entries) != 1 {
+		t.Errorf("Expected only the installed pack in the packs directory, got %v", entries)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	root := t.TempDir()
+	if _, _, err := Install(writePack(t, "1.0.0"), root); err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+	installed := filepath.Join(Dir(root), "team")
+
+	os.WriteFile(filepath.Join(installed, "fragments", "review.md"), []byte("Approve everything.\n"), 0644)
+	os.Remove(filepath.Join(installed, "procedures", "secondary.yml"))
+	os.WriteFile(filepath.Join(installed, "fragments", "extra.md"), []byte("extra"), 0644)
+	os.WriteFile(filepath.Join(installed, ManifestFile), []byte("name: team\nversion: 2.0.0\n"), 0644)
+
+	problems, err := Verify(root)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	var got []string
+	for _, p := range problems {
+		got = append(got, p.String())
+	}
+	want := []string{
+		"team: version is 2.0.0, lockfile has 1.0.0",
+		"team: fragments/review.md was modified",
+		"team: procedures/secondary.yml is missing",
+		"team: rooda-pack.yml was modified",
+		"team: fragments/extra.md is not in the lockfile",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	os.RemoveAll(installed)
+	problems, _ = Verify(root)
+	if len(problems) != 1 || problems[0].String() != "team: is not installed" {
+		t.Errorf("Expected not installed problem, got %v", problems)
+	}
+}
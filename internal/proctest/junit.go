@@ -0,0 +1,73 @@
+package proctest
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Time     float64         `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// WriteJUnit writes results as a JUnit XML report, with one testcase per case
+// named after the case and classed by its procedure.
+func WriteJUnit(w io.Writer, suiteName string, results []Result) error {
+	suite := junitTestSuite{Name: suiteName, Tests: len(results)}
+	for _, r := range results {
+		tc := junitTestCase{
+			Name:      r.Case.Name,
+			Classname: r.Case.Procedure,
+			Time:      r.Duration.Seconds(),
+		}
+		if !r.Passed() {
+			suite.Failures++
+			tc.Failure = &junitFailure{
+				Message: r.Failures[0],
+				Body:    strings.Join(r.Failures, "\n"),
+			}
+		}
+		suite.Time += r.Duration.Seconds()
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	report := junitTestSuites{
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Suites:   []junitTestSuite{suite},
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
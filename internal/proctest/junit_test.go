@@ -0,0 +1,44 @@
+package proctest
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	results := []Result{
+		{Case: Case{Name: "passes", Procedure: "build"}, Duration: 1500 * time.Millisecond},
+		{Case: Case{Name: "fails", Procedure: "review"}, Failures: []string{"first problem", "second <problem>"}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, "procedures.test.yml", results); err != nil {
+		t.Fatalf("WriteJUnit failed: %v", err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, xml.Header) {
+		t.Errorf("expected XML header, got:\n%s", out)
+	}
+
+	var report junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("report is not valid XML: %v\n%s", err, out)
+	}
+	if report.Tests != 2 || report.Failures != 1 || len(report.Suites) != 1 {
+		t.Fatalf("unexpected totals: %+v", report)
+	}
+	suite := report.Suites[0]
+	if suite.Name != "procedures.test.yml" || len(suite.Cases) != 2 {
+		t.Fatalf("unexpected suite: %+v", suite)
+	}
+	if tc := suite.Cases[0]; tc.Name != "passes" || tc.Classname != "build" || tc.Time != 1.5 || tc.Failure != nil {
+		t.Errorf("unexpected passing case: %+v", tc)
+	}
+	tc := suite.Cases[1]
+	if tc.Failure == nil || tc.Failure.Message != "first problem" || tc.Failure.Body != "first problem\nsecond <problem>" {
+		t.Errorf("unexpected failing case: %+v", tc)
+	}
+}
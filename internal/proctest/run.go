@@ -0,0 +1,185 @@
+package proctest
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jomadu/rooda/internal/ai"
+	"github.com/jomadu/rooda/internal/config"
+	"github.com/jomadu/rooda/internal/loop"
+	"github.com/jomadu/rooda/internal/observability"
+	"github.com/jomadu/rooda/internal/prompt"
+)
+
+// phaseNames maps phase_order entries to the names used in prompt section markers.
+var phaseNames = map[string]string{
+	"observe": "OBSERVE",
+	"orient":  "ORIENT",
+	"decide":  "DECIDE",
+	"act":     "ACT",
+}
+
+var phaseMarker = regexp.MustCompile(`(?m)^PHASE \d+: ([A-Z]+)$`)
+
+// Result is the outcome of one case.
+type Result struct {
+	Case     Case
+	Failures []string // Failed assertions; empty when the case passed
+	Duration time.Duration
+}
+
+// Passed reports whether every assertion held.
+func (r Result) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// Run runs every case of the suite against the procedures in cfg.
+func Run(suite *Suite, cfg config.Config) []Result {
+	results := make([]Result, 0, len(suite.Cases))
+	for _, c := range suite.Cases {
+		results = append(results, RunCase(c, cfg))
+	}
+	return results
+}
+
+// RunCase checks the case's prompt assertions and, when responses are
+// scripted, runs the loop against them and checks the expected outcome.
+func RunCase(c Case, cfg config.Config) Result {
+	start := time.Now()
+	result := Result{Case: c}
+	fail := func(format string, args ...interface{}) {
+		result.Failures = append(result.Failures, fmt.Sprintf(format, args...))
+	}
+	defer func() { result.Duration = time.Since(start) }()
+
+	proc, ok := cfg.Procedures[c.Procedure]
+	if !ok {
+		fail("unknown procedure %q", c.Procedure)
+		return result
+	}
+	proc = withParams(proc, c.Params)
+
+	maxIterations := resolveMaxIterations(c, proc, cfg)
+	userContext := strings.Join(c.Contexts, "\n\n")
+
+	assembled, err := prompt.AssemblePrompt(proc, userContext, "", &prompt.IterationContext{
+		CurrentIteration: 0,
+		MaxIterations:    maxIterations,
+	})
+	if err != nil {
+		fail("prompt assembly failed: %v", err)
+		return result
+	}
+	for _, snippet := range c.Prompt.Contains {
+		if !strings.Contains(assembled, snippet) {
+			fail("prompt does not contain %q", snippet)
+		}
+	}
+	for _, snippet := range c.Prompt.Excludes {
+		if strings.Contains(assembled, snippet) {
+			fail("prompt contains excluded %q", snippet)
+		}
+	}
+	if c.Prompt.PhaseOrder != nil {
+		want := make([]string, len(c.Prompt.PhaseOrder))
+		for i, phase := range c.Prompt.PhaseOrder {
+			want[i] = phaseNames[phase]
+		}
+		var got []string
+		for _, m := range phaseMarker.FindAllStringSubmatch(assembled, -1) {
+			got = append(got, m[1])
+		}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			fail("phase order is [%s], want [%s]", strings.ToLower(strings.Join(got, ", ")), strings.ToLower(strings.Join(want, ", ")))
+		}
+	}
+
+	if len(c.Responses) == 0 {
+		return result
+	}
+
+	agent := &ai.ScriptedExecutor{}
+	for _, r := range c.Responses {
+		agent.Responses = append(agent.Responses, ai.AIExecutionResult{Output: r.Output, ExitCode: r.ExitCode})
+	}
+
+	// Run against a copy so the case's params don't leak into other cases
+	procedures := make(map[string]config.Procedure, len(cfg.Procedures))
+	for name, p := range cfg.Procedures {
+		procedures[name] = p
+	}
+	procedures[c.Procedure] = proc
+	cfg.Procedures = procedures
+
+	state := &loop.IterationState{
+		MaxIterations:      maxIterations,
+		IdleTimeoutFailure: cfg.Loop.IdleTimeoutFailure,
+		MaxOutputBuffer:    cfg.Loop.MaxOutputBuffer,
+		FailureThreshold:   cfg.Loop.FailureThreshold,
+		StartedAt:          time.Now(),
+		Status:             loop.StatusRunning,
+		ProcedureName:      c.Procedure,
+		Executor:           agent,
+	}
+	logger := observability.NewLogger(config.LogLevelError, config.TimestampNone, state.StartedAt)
+	logger.SetOutput(io.Discard)
+
+	status := loop.RunLoop(state, cfg, config.AICommand{Source: "scripted"}, userContext, false, logger)
+
+	if len(agent.Prompts) > len(agent.Responses) {
+		fail("agent was invoked %d times but only %d responses are scripted", len(agent.Prompts), len(agent.Responses))
+	}
+	if c.Expect.Status != "" && status != c.Expect.Status {
+		fail("loop status is %s, want %s", status, c.Expect.Status)
+	}
+	if c.Expect.Iterations != nil && state.Iteration != *c.Expect.Iterations {
+		fail("loop ran %d iterations, want %d", state.Iteration, *c.Expect.Iterations)
+	}
+
+	return result
+}
+
+// withParams returns a copy of proc with params added to every fragment's
+// template parameters. Parameters set on the fragment itself win.
+func withParams(proc config.Procedure, params map[string]interface{}) config.Procedure {
+	if len(params) == 0 {
+		return proc
+	}
+	apply := func(fragments []config.FragmentAction) []config.FragmentAction {
+		out := make([]config.FragmentAction, len(fragments))
+		for i, f := range fragments {
+			merged := make(map[string]interface{}, len(params)+len(f.Parameters))
+			for k, v := range params {
+				merged[k] = v
+			}
+			for k, v := range f.Parameters {
+				merged[k] = v
+			}
+			f.Parameters = merged
+			out[i] = f
+		}
+		return out
+	}
+	proc.Observe = apply(proc.Observe)
+	proc.Orient = apply(proc.Orient)
+	proc.Decide = apply(proc.Decide)
+	proc.Act = apply(proc.Act)
+	return proc
+}
+
+// resolveMaxIterations mirrors rooda run without iteration flags.
+func resolveMaxIterations(c Case, proc config.Procedure, cfg config.Config) *int {
+	switch {
+	case c.MaxIterations != nil:
+		return c.MaxIterations
+	case proc.DefaultMaxIterations != nil:
+		return proc.DefaultMaxIterations
+	case cfg.Loop.DefaultMaxIterations != nil:
+		return cfg.Loop.DefaultMaxIterations
+	}
+	defaultMax := config.DefaultMaxIterations
+	return &defaultMax
+}
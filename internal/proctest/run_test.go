@@ -0,0 +1,137 @@
+package proctest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jomadu/rooda/internal/config"
+	"github.com/jomadu/rooda/internal/loop"
+)
+
+func testConfig() config.Config {
+	return config.Config{
+		Loop: config.LoopConfig{
+			FailureThreshold: 2,
+			MaxOutputBuffer:  config.DefaultMaxOutputBuffer,
+		},
+		Procedures: map[string]config.Procedure{
+			"review": {
+				Observe: []config.FragmentAction{{Content: "Read the {{.target}} code."}},
+				Act:     []config.FragmentAction{{Content: "Review {{.target}} and report."}},
+			},
+		},
+	}
+}
+
+func intPtr(i int) *int { return &i }
+
+func TestRunCase_PromptAssertions(t *testing.T) {
+	c := Case{
+		Name:      "prompt",
+		Procedure: "review",
+		Contexts:  []string{"focus on auth"},
+		Params:    map[string]interface{}{"target": "api"},
+		Prompt: PromptAssertions{
+			Contains:   []string{"Review api and report.", "focus on auth", "Iteration: 1 of 5"},
+			Excludes:   []string{"{{.target}}"},
+			PhaseOrder: []string{"observe", "act"},
+		},
+	}
+	result := RunCase(c, testConfig())
+	if !result.Passed() {
+		t.Errorf("expected case to pass, got failures: %v", result.Failures)
+	}
+}
+
+func TestRunCase_PromptAssertionFailures(t *testing.T) {
+	c := Case{
+		Name:      "prompt",
+		Procedure: "review",
+		Params:    map[string]interface{}{"target": "api"},
+		Prompt: PromptAssertions{
+			Contains:   []string{"Review web"},
+			Excludes:   []string{"Read the api code."},
+			PhaseOrder: []string{"observe", "decide", "act"},
+		},
+	}
+	result := RunCase(c, testConfig())
+	want := []string{
+		`prompt does not contain "Review web"`,
+		`prompt contains excluded "Read the api code."`,
+		"phase order is [observe, act], want [observe, decide, act]",
+	}
+	if strings.Join(result.Failures, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected failures:\n%s", strings.Join(result.Failures, "\n"))
+	}
+}
+
+func TestRunCase_UnknownProcedure(t *testing.T) {
+	result := RunCase(Case{Name: "x", Procedure: "missing"}, testConfig())
+	if result.Passed() || !strings.Contains(result.Failures[0], `unknown procedure "missing"`) {
+		t.Errorf("unexpected failures: %v", result.Failures)
+	}
+}
+
+func TestRunCase_ScriptedLoop(t *testing.T) {
+	tests := []struct {
+		name         string
+		responses    []Response
+		expect       LoopExpectations
+		wantFailures []string
+	}{
+		{
+			name:      "success on second iteration",
+			responses: []Response{{Output: "working"}, {Output: "<promise>SUCCESS</promise>"}},
+			expect:    LoopExpectations{Status: loop.StatusSuccess, Iterations: intPtr(2)},
+		},
+		{
+			name:      "aborts after consecutive failures",
+			responses: []Response{{Output: "<promise>FAILURE</promise>"}, {Output: "boom", ExitCode: 1}},
+			expect:    LoopExpectations{Status: loop.StatusAborted, Iterations: intPtr(2)},
+		},
+		{
+			name:      "status mismatch",
+			responses: []Response{{Output: "<promise>SUCCESS</promise>"}},
+			expect:    LoopExpectations{Status: loop.StatusMaxIters, Iterations: intPtr(3)},
+			wantFailures: []string{
+				"loop status is success, want max-iters",
+				"loop ran 1 iterations, want 3",
+			},
+		},
+		{
+			name:         "too few responses",
+			responses:    []Response{{Output: "working"}},
+			expect:       LoopExpectations{Status: loop.StatusAborted},
+			wantFailures: []string{"agent was invoked 2 times but only 1 responses are scripted"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Case{
+				Name:          tt.name,
+				Procedure:     "review",
+				Params:        map[string]interface{}{"target": "api"},
+				MaxIterations: intPtr(3),
+				Responses:     tt.responses,
+				Expect:        tt.expect,
+			}
+			result := RunCase(c, testConfig())
+			if strings.Join(result.Failures, "\n") != strings.Join(tt.wantFailures, "\n") {
+				t.Errorf("unexpected failures:\n%s", strings.Join(result.Failures, "\n"))
+			}
+		})
+	}
+}
+
+func TestRunCase_ParamsDoNotLeak(t *testing.T) {
+	cfg := testConfig()
+	RunCase(Case{
+		Name:      "first",
+		Procedure: "review",
+		Params:    map[string]interface{}{"target": "api"},
+		Responses: []Response{{Output: "<promise>SUCCESS</promise>"}},
+	}, cfg)
+	if params := cfg.Procedures["review"].Act[0].Parameters; params != nil {
+		t.Errorf("expected procedure parameters to be untouched, got %v", params)
+	}
+}
@@ -0,0 +1,102 @@
+package proctest
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jomadu/rooda/internal/loop"
+	"gopkg.in/yaml.v3"
+)
+
+// Suite is a file of declarative procedure test cases.
+type Suite struct {
+	Path  string `yaml:"-"`     // File the suite was loaded from
+	Cases []Case `yaml:"cases"` // Run in file order
+}
+
+// Case tests one procedure: the prompt it assembles and, optionally, how the
+// loop reacts to a scripted sequence of agent responses.
+type Case struct {
+	Name          string                 `yaml:"name"`
+	Procedure     string                 `yaml:"procedure"`
+	Contexts      []string               `yaml:"contexts"`       // Same as repeated --context values
+	Params        map[string]interface{} `yaml:"params"`         // Template parameters added to every fragment
+	MaxIterations *int                   `yaml:"max_iterations"` // Overrides the procedure's default (optional)
+	Prompt        PromptAssertions       `yaml:"prompt"`
+	Responses     []Response             `yaml:"responses"` // Scripted agent responses, one per invocation
+	Expect        LoopExpectations       `yaml:"expect"`
+}
+
+// PromptAssertions are checked against the prompt assembled for the first iteration.
+type PromptAssertions struct {
+	Contains   []string `yaml:"contains"`    // Snippets that must appear
+	Excludes   []string `yaml:"excludes"`    // Snippets that must not appear
+	PhaseOrder []string `yaml:"phase_order"` // Exact list of phases present, in order (e.g. [observe, act])
+}
+
+// Response is one scripted agent invocation.
+type Response struct {
+	Output   string `yaml:"output"`
+	ExitCode int    `yaml:"exit_code"`
+}
+
+// LoopExpectations are checked after the scripted loop finishes.
+type LoopExpectations struct {
+	Status     loop.LoopStatus `yaml:"status"`     // Final loop status (optional)
+	Iterations *int            `yaml:"iterations"` // Completed iterations (optional)
+}
+
+// LoadSuite reads and checks a test suite file.
+func LoadSuite(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var suite Suite
+	if err := yaml.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	suite.Path = path
+
+	if len(suite.Cases) == 0 {
+		return nil, fmt.Errorf("%s: no cases defined", path)
+	}
+	for i, c := range suite.Cases {
+		if err := c.validate(); err != nil {
+			name := c.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i+1)
+			}
+			return nil, fmt.Errorf("%s: case %s: %w", path, name, err)
+		}
+	}
+
+	return &suite, nil
+}
+
+func (c Case) validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if c.Procedure == "" {
+		return fmt.Errorf("procedure is required")
+	}
+	if c.MaxIterations != nil && *c.MaxIterations < 1 {
+		return fmt.Errorf("max_iterations must be >= 1")
+	}
+	for _, phase := range c.Prompt.PhaseOrder {
+		if _, ok := phaseNames[phase]; !ok {
+			return fmt.Errorf("unknown phase %q in phase_order (expected observe, orient, decide or act)", phase)
+		}
+	}
+	if (c.Expect.Status != "" || c.Expect.Iterations != nil) && len(c.Responses) == 0 {
+		return fmt.Errorf("expect needs scripted responses")
+	}
+	switch c.Expect.Status {
+	case "", loop.StatusSuccess, loop.StatusMaxIters, loop.StatusAborted:
+	default:
+		return fmt.Errorf("invalid expect.status %q (expected success, max-iters or aborted)", c.Expect.Status)
+	}
+	return nil
+}
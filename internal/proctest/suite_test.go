@@ -0,0 +1,81 @@
+package proctest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jomadu/rooda/internal/loop"
+)
+
+func writeSuite(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "procedures.test.yml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write suite: %v", err)
+	}
+	return path
+}
+
+func TestLoadSuite(t *testing.T) {
+	path := writeSuite(t, `
+cases:
+  - name: builds prompt
+    procedure: review
+    contexts: [focus on auth]
+    params:
+      target: api
+    prompt:
+      contains: ["Review api"]
+      excludes: ["TODO"]
+      phase_order: [observe, act]
+    responses:
+      - output: working
+      - output: "<promise>SUCCESS</promise>"
+    expect:
+      status: success
+      iterations: 2
+`)
+	suite, err := LoadSuite(path)
+	if err != nil {
+		t.Fatalf("LoadSuite failed: %v", err)
+	}
+	if suite.Path != path || len(suite.Cases) != 1 {
+		t.Fatalf("unexpected suite: %+v", suite)
+	}
+	c := suite.Cases[0]
+	if c.Procedure != "review" || c.Params["target"] != "api" || len(c.Contexts) != 1 {
+		t.Errorf("unexpected case fields: %+v", c)
+	}
+	if len(c.Responses) != 2 || c.Responses[1].Output != "<promise>SUCCESS</promise>" {
+		t.Errorf("unexpected responses: %+v", c.Responses)
+	}
+	if c.Expect.Status != loop.StatusSuccess || c.Expect.Iterations == nil || *c.Expect.Iterations != 2 {
+		t.Errorf("unexpected expectations: %+v", c.Expect)
+	}
+}
+
+func TestLoadSuite_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"no cases", "cases: []\n", "no cases defined"},
+		{"missing name", "cases:\n  - procedure: build\n", "case #1: name is required"},
+		{"missing procedure", "cases:\n  - name: a\n", "case a: procedure is required"},
+		{"unknown phase", "cases:\n  - name: a\n    procedure: build\n    prompt:\n      phase_order: [plan]\n", `unknown phase "plan"`},
+		{"expect without responses", "cases:\n  - name: a\n    procedure: build\n    expect:\n      status: success\n", "expect needs scripted responses"},
+		{"invalid status", "cases:\n  - name: a\n    procedure: build\n    responses: [{output: x}]\n    expect:\n      status: done\n", `invalid expect.status "done"`},
+		{"invalid max_iterations", "cases:\n  - name: a\n    procedure: build\n    max_iterations: 0\n", "max_iterations must be >= 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadSuite(writeSuite(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
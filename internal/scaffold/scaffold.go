@@ -0,0 +1,262 @@
+// Package scaffold sets up a workspace for rooda: a commented config file, a
+// directory per OODA phase for the workspace's own fragments, an AGENTS.md
+// skeleton and .gitignore entries for local run data.
+package scaffold
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jomadu/rooda/internal/ai"
+	"github.com/jomadu/rooda/internal/config"
+	"github.com/jomadu/rooda/internal/loop"
+)
+
+// FragmentsDir holds the workspace's own fragments, one directory per phase.
+const FragmentsDir = config.WorkspaceDir + "/fragments"
+
+// Phases are the fragment directories created under FragmentsDir.
+var Phases = []string{"observe", "orient", "decide", "act"}
+
+// AgentsFile is the agent instructions file the built-in procedures read.
+const AgentsFile = "AGENTS.md"
+
+// ignored are the .gitignore entries for data rooda writes while running.
+var ignored = []string{ai.DefaultRunsDir + "/", loop.DefaultStatsPath}
+
+// Options controls what Init writes.
+type Options struct {
+	Alias    string   // Default ai_cmd_alias ("" leaves it commented out)
+	Detected []string // Aliases found installed, noted in the config
+	AgentsMD bool     // Create AGENTS.md when missing
+	Force    bool     // Overwrite an existing rooda-config.yml
+}
+
+// Result lists what Init did, as paths relative to the workspace.
+type Result struct {
+	Created []string // Files and directories created
+	Updated []string // Existing files changed
+	Skipped []string // Existing files left alone
+}
+
+// DetectAliases returns the names of the aliases whose command is installed,
+// that is, whose first word is an executable on PATH, sorted.
+func DetectAliases(aliases map[string]string) []string {
+	var found []string
+	for name, command := range aliases {
+		fields := strings.Fields(command)
+		if len(fields) == 0 {
+			continue
+		}
+		if _, err := exec.LookPath(fields[0]); err == nil {
+			found = append(found, name)
+		}
+	}
+	sort.Strings(found)
+	return found
+}
+
+// Init sets up the workspace at dir. Existing files are kept, except the
+// config file when opts.Force is set; missing .gitignore entries are
+// appended. Running it again only fills in what is missing.
+func Init(dir string, opts Options) (*Result, error) {
+	r := &Result{}
+
+	configPath := filepath.Join(dir, config.ConfigFileName)
+	switch exists, err := pathExists(configPath); {
+	case err != nil:
+		return nil, err
+	case exists && !opts.Force:
+		r.Skipped = append(r.Skipped, config.ConfigFileName)
+	default:
+		if err := os.WriteFile(configPath, []byte(configTemplate(opts)), 0644); err != nil {
+			return nil, err
+		}
+		if exists {
+			r.Updated = append(r.Updated, config.ConfigFileName)
+		} else {
+			r.Created = append(r.Created, config.ConfigFileName)
+		}
+	}
+
+	for _, phase := range Phases {
+		rel := FragmentsDir + "/" + phase
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		exists, err := pathExists(path)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			continue
+		}
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return nil, err
+		}
+		// Git does not track empty directories
+		if err := os.WriteFile(filepath.Join(path, ".gitkeep"), nil, 0644); err != nil {
+			return nil, err
+		}
+		r.Created = append(r.Created, rel+"/")
+	}
+
+	if opts.AgentsMD {
+		path := filepath.Join(dir, AgentsFile)
+		switch exists, err := pathExists(path); {
+		case err != nil:
+			return nil, err
+		case exists:
+			r.Skipped = append(r.Skipped, AgentsFile)
+		default:
+			if err := os.WriteFile(path, []byte(agentsTemplate), 0644); err != nil {
+				return nil, err
+			}
+			r.Created = append(r.Created, AgentsFile)
+		}
+	}
+
+	existed, err := pathExists(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return nil, err
+	}
+	changed, err := updateGitignore(filepath.Join(dir, ".gitignore"))
+	switch {
+	case err != nil:
+		return nil, err
+	case changed && existed:
+		r.Updated = append(r.Updated, ".gitignore")
+	case changed:
+		r.Created = append(r.Created, ".gitignore")
+	}
+	return r, nil
+}
+
+// updateGitignore appends the entries in ignored that the file lacks,
+// creating it if needed. It reports whether the file changed.
+func updateGitignore(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	present := make(map[string]bool)
+	for _, line := range strings.Split(string(data), "\n") {
+		present[strings.TrimSpace(line)] = true
+	}
+	var missing []string
+	for _, entry := range ignored {
+		if !present[entry] && !present["/"+entry] {
+			missing = append(missing, entry)
+		}
+	}
+	if len(missing) == 0 {
+		return false, nil
+	}
+
+	var b strings.Builder
+	b.Write(data)
+	if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+		b.WriteString("\n")
+	}
+	if len(data) > 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString("# rooda run data\n")
+	for _, entry := range missing {
+		b.WriteString(entry + "\n")
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func pathExists(path string) (bool, error) {
+	_, err := os.Stat(path)
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// configTemplate is the commented rooda-config.yml Init writes.
+func configTemplate(opts Options) string {
+	var builtIn []string
+	for name := range config.BuiltInAliases() {
+		builtIn = append(builtIn, name)
+	}
+	sort.Strings(builtIn)
+	detected := "none"
+	if len(opts.Detected) > 0 {
+		detected = strings.Join(opts.Detected, ", ")
+	}
+	alias := fmt.Sprintf("ai_cmd_alias: %s", opts.Alias)
+	if opts.Alias == "" {
+		alias = "# ai_cmd_alias: claude  # or set ai_cmd to the full command"
+	}
+
+	return fmt.Sprintf(`# rooda workspace configuration. It merges over the global config and the
+# built-in defaults; "rooda config show --provenance" prints the result.
+# Reference: docs/configuration.md in the rooda repository.
+version: %d
+
+loop:
+  # AI command alias every procedure uses unless it names its own.
+  # Built-in aliases: %s
+  # Installed when rooda init ran: %s
+  %s
+
+  # Iterations per run, unless a procedure or --max-iterations sets it.
+  # default_max_iterations: %d
+
+  # Seconds per iteration, or auto to learn a timeout from past runs.
+  # iteration_timeout: auto
+
+# Procedures of your own, or overrides of built-in ones ("rooda list").
+# Fragment paths are relative to this file; %s has a directory
+# per OODA phase for your fragments, and builtin: paths name rooda's own.
+# procedures:
+#   review:
+#     summary: "Review the latest changes"
+#     observe:
+#       - path: %s/observe/review.md
+#     orient:
+#       - path: builtin:fragments/orient/identify_drift.md
+#     decide:
+#       - path: %s/decide/review.md
+#     act:
+#       - path: %s/act/review.md
+`, config.ConfigVersion, strings.Join(builtIn, ", "), detected, alias, config.DefaultMaxIterations,
+		FragmentsDir, FragmentsDir, FragmentsDir, FragmentsDir)
+}
+
+// agentsTemplate has the sections docs/agents-md.md describes, for the
+// agents-sync procedure or a person to fill in.
+const agentsTemplate = `# Agent Instructions
+
+Run ` + "`rooda run agents-sync`" + ` to fill in these sections from the repository.
+See docs/agents-md.md in the rooda repository for the format.
+
+## Issue Tracking
+
+## Work Tracking System
+
+## Build/Test/Lint Commands
+
+## Specification Definition
+
+## Implementation Definition
+
+## Quality Criteria
+
+## Planning System
+
+## Story/Bug Input
+
+## Operational Learnings
+`
@@ -0,0 +1,90 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jomadu/rooda/internal/config"
+)
+
+func TestDetectAliases(t *testing.T) {
+	bin := t.TempDir()
+	os.WriteFile(filepath.Join(bin, "copilot"), []byte("#!/bin/sh\n"), 0755)
+	os.WriteFile(filepath.Join(bin, "kiro-cli"), []byte("not executable"), 0644)
+	t.Setenv("PATH", bin)
+
+	got := DetectAliases(map[string]string{
+		"copilot":  "copilot --yolo",
+		"kiro-cli": "kiro-cli chat",
+		"claude":   "claude -p",
+		"empty":    "",
+	})
+	if !reflect.DeepEqual(got, []string{"copilot"}) {
+		t.Errorf("Expected only copilot detected, got %v", got)
+	}
+}
+
+func TestInit(t *testing.T) {
+	t.Setenv("ROODA_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("node_modules"), 0644)
+
+	r, err := Init(dir, Options{Alias: "copilot", Detected: []string{"copilot"}, AgentsMD: true})
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	wantCreated := []string{
+		"rooda-config.yml",
+		".rooda/fragments/observe/",
+		".rooda/fragments/orient/",
+		".rooda/fragments/decide/",
+		".rooda/fragments/act/",
+		"AGENTS.md",
+	}
+	if !reflect.DeepEqual(r.Created, wantCreated) || !reflect.DeepEqual(r.Updated, []string{".gitignore"}) {
+		t.Errorf("Unexpected result %+v", r)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".rooda", "fragments", "act", ".gitkeep")); err != nil {
+		t.Errorf("Expected .gitkeep in phase directory: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, ".gitignore")); string(data) != "node_modules\n\n# rooda run data\n.rooda/runs/\n.rooda/stats.json\n" {
+		t.Errorf("Unexpected .gitignore:\n%s", data)
+	}
+
+	configPath := filepath.Join(dir, "rooda-config.yml")
+	cfg, err := config.LoadConfig(config.CLIFlags{ConfigPath: configPath})
+	if err != nil {
+		t.Fatalf("Generated config does not load: %v", err)
+	}
+	if cfg.Loop.AICmdAlias != "copilot" || len(cfg.Warnings) != 0 {
+		t.Errorf("Expected ai_cmd_alias copilot and no warnings, got %q %v", cfg.Loop.AICmdAlias, cfg.Warnings)
+	}
+
+	// A second run keeps what exists
+	os.WriteFile(configPath, []byte("loop:\n  ai_cmd_alias: claude\n"), 0644)
+	r, err = Init(dir, Options{Alias: "copilot", AgentsMD: true})
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if len(r.Created) != 0 || len(r.Updated) != 0 || !reflect.DeepEqual(r.Skipped, []string{"rooda-config.yml", "AGENTS.md"}) {
+		t.Errorf("Expected nothing changed on a second run, got %+v", r)
+	}
+
+	r, err = Init(dir, Options{Force: true})
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if !reflect.DeepEqual(r.Updated, []string{"rooda-config.yml"}) {
+		t.Errorf("Expected config overwritten with Force, got %+v", r)
+	}
+	data, _ := os.ReadFile(configPath)
+	if !strings.Contains(string(data), "  # ai_cmd_alias: claude") || !strings.Contains(string(data), "Installed when rooda init ran: none") {
+		t.Errorf("Expected alias commented out when none is chosen, got:\n%s", data)
+	}
+	if _, err := config.LoadConfig(config.CLIFlags{ConfigPath: configPath}); err != nil {
+		t.Errorf("Generated config without alias does not load: %v", err)
+	}
+}